@@ -0,0 +1,93 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dataStore
+
+import (
+    "database/sql/driver"
+    "encoding/json"
+    "fmt"
+)
+
+//Free-form attributes of a record, they are stored as a JSON object.
+type Attributes map[string]interface{}
+
+//Copy of the attributes, nested values are shared as they are never updated
+// in place.
+func (attrs Attributes)Copy() Attributes {
+    if len(attrs) == 0 {
+        return nil
+    }
+    newAttrs := make(Attributes, len(attrs))
+    for key, value := range attrs {
+        newAttrs[key] = value
+    }
+    return newAttrs
+}
+
+//Remove the attributes with null value, null in an update removes the
+// attribute.
+func (attrs Attributes)RemoveNulls() {
+    for key, value := range attrs {
+        if value == nil {
+            delete(attrs, key)
+        }
+    }
+}
+
+//Check the attribute has the value, value of a string attribute is compared
+// as it is and rest of them on their JSON, e.g. 'true' or '10'.
+func (attrs Attributes)HasValue(key string, value string) bool {
+    attrVal, ok := attrs[key]
+    if !ok {
+        return false
+    }
+    if strVal, ok := attrVal.(string); ok {
+        return strVal == value
+    }
+    jsonVal, err := json.Marshal(attrVal)
+    return err == nil && string(jsonVal) == value
+}
+
+//Store the attributes as JSON text, no attributes are stored as NULL.
+func (attrs Attributes)Value() (driver.Value, error) {
+    if len(attrs) == 0 {
+        return nil, nil
+    }
+    value, err := json.Marshal(attrs)
+    if err != nil {
+        return nil, err
+    }
+    return string(value), nil
+}
+
+//Read the attributes from the JSON text.
+func (attrs *Attributes)Scan(src interface{}) error {
+    var value []byte
+    switch src := src.(type) {
+    case nil:
+        *attrs = nil
+        return nil
+    case string:
+        value = []byte(src)
+    case []byte:
+        value = src
+    default:
+        return fmt.Errorf("Cannot read the attributes from %T", src)
+    }
+    if len(value) == 0 {
+        *attrs = nil
+        return nil
+    }
+    return json.Unmarshal(value, attrs)
+}
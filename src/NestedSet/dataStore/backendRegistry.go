@@ -0,0 +1,111 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataStore
+
+import (
+    "sort"
+    "strconv"
+    "sync"
+    "NestedSet/appErrors"
+    "NestedSet/logger"
+)
+
+//Options of a backend from the configuration, the keys are specific to the
+// backend.
+type BackendOptions map[string]string
+
+//Create a new datastore of a backend with its options. Factory must fail on
+// the options that are not supported by the backend.
+type BackendFactory func(options BackendOptions) (DataSetInterface, error)
+
+var backendLock sync.Mutex
+var backends = make(map[string]BackendFactory)
+
+//Register a backend factory under 'name'. Backends are registered in the init
+// of their package, so importing the package is enough to make it available.
+func RegisterBackend(name string, factory BackendFactory) error {
+    backendLock.Lock()
+    defer backendLock.Unlock()
+    if len(name) == 0 || factory == nil {
+        return appErrors.INVALID_INPUT
+    }
+    if _, ok := backends[name]; ok {
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    backends[name] = factory
+    return nil
+}
+
+//Get the names of all the registered backends in order.
+func GetBackendNames() []string {
+    backendLock.Lock()
+    defer backendLock.Unlock()
+    names := make([]string, 0, len(backends))
+    for name := range backends {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+//Create a new datastore of the backend registered under 'name'.
+func NewBackend(name string,
+                options BackendOptions) (DataSetInterface, error) {
+    backendLock.Lock()
+    factory, ok := backends[name]
+    backendLock.Unlock()
+    if !ok {
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    if options == nil {
+        options = BackendOptions{}
+    }
+    return factory(options)
+}
+
+//Check all the options are in the supported 'keys' of the backend.
+func (options BackendOptions)Check(keys ...string) error {
+    for option := range options {
+        supported := false
+        for _, key := range keys {
+            if option == key {
+                supported = true
+                break
+            }
+        }
+        if !supported {
+            logger.GetLoggerInstance().Error("Backend option %s is not " +
+                                             "supported", option)
+            return appErrors.INVALID_INPUT
+        }
+    }
+    return nil
+}
+
+//Get the positive integer value of the option, 'defVal' when its not set.
+func (options BackendOptions)GetInt(key string, defVal int) (int, error) {
+    value, ok := options[key]
+    if !ok {
+        return defVal, nil
+    }
+    intVal, err := strconv.Atoi(value)
+    if err != nil || intVal <= 0 {
+        logger.GetLoggerInstance().Error("Backend option %s must be a " +
+                                         "positive integer, not '%s'",
+                                         key, value)
+        return 0, appErrors.INVALID_INPUT
+    }
+    return intVal, nil
+}
@@ -19,11 +19,39 @@ import (
 
 type Data struct {
     Uid string      `json:"uid" db:"Uid"`
+    TreeId string   `json:"treeId" db:"TreeId"`
     Puid string     `json:"puid" db:"Puid"`
     Name string     `json:"name" db:"Name"`
     Desc string     `json:"desc" db:"Desc"`
+    //Type of the record, empty when the record has no type.
+    Type string     `json:"type,omitempty" db:"Type"`
     LftId int64      `json:"lftId" db:"LftId"`//Used for nestedset hierarchy
     RgtId int64      `json:"rgtId" db:"RgtId"`//Used for nestedset hierarchy
+    Attributes Attributes `json:"attributes,omitempty" db:"Attributes"`
+    //Versions of the record and its childrens, from the version of the tree.
+    Version int64      `json:"version" db:"Version"`
+    ChildVersion int64 `json:"-" db:"ChildVersion"`
+    //Computed fields of the record, they are not stored in the datastore.
+    Depth int64       `json:"depth" db:"Depth"`//Number of parents upto root
+    Descendants int64 `json:"descendants" db:"-"`
+    IsLeaf bool       `json:"isLeaf" db:"-"`
+    //Latest version of the record and its childrens, only set on GetRecord.
+    SubtreeVersion int64 `json:"-" db:"-"`
+    //Position of the new record under its parent, only used on create.
+    Position string  `json:"position,omitempty" db:"-"`
+    //Sibling record for 'before'/'after' position, only used on create.
+    SiblingId string `json:"siblingId,omitempty" db:"-"`
+}
+
+//Every record belongs to a tree, a tree is an independent hierarchy with its
+// own root record and nested set limits.
+type Tree struct {
+    TreeId string   `json:"treeId" db:"TreeId"`
+    Name string     `json:"name" db:"Name"`
+    Desc string     `json:"desc" db:"Desc"`
+    RootUid string  `json:"rootUid" db:"RootUid"`
+    //Incremented on every change of the records in the tree.
+    Version int64   `json:"version" db:"Version"`
 }
 
 const (
@@ -32,4 +60,59 @@ const (
     DEFAULT_RGTID = DEFAULT_SETID
     ROOT_UID = "00112233-4455-6677-8899-aabbccddeeff"
     DEFAULT_PUID = ROOT_UID
+    //Tree that is created by the application, ROOT_UID is its root record.
+    DEFAULT_TREE_ID = "default"
 )
+
+//Positions to insert a new record in its parent. Default is to insert the
+// record as the last child.
+const (
+    POSITION_FIRST_CHILD = "first-child"
+    POSITION_LAST_CHILD = "last-child"
+    POSITION_BEFORE = "before"
+    POSITION_AFTER = "after"
+)
+
+
+//Update the computed subtree fields of the record from its nested set limits.
+func (rec *Data)UpdateSubtreeInfo() {
+    rec.Descendants = (rec.RgtId - rec.LftId - 1) / 2
+    rec.IsLeaf = rec.Descendants == 0
+}
+
+//Update all the computed fields of the records in pre-order(sorted on LftId).
+// 'baseDepth' is the depth of the top level records in the list, depth of
+// every other record is found from its parents in the list.
+func UpdateTreeInfo(recs []Data, baseDepth int64) {
+    //Right limits of the parents of current record.
+    parentRgtIds := []int64{}
+    for i := range recs {
+        for len(parentRgtIds) != 0 &&
+            parentRgtIds[len(parentRgtIds) - 1] < recs[i].LftId {
+            parentRgtIds = parentRgtIds[:len(parentRgtIds) - 1]
+        }
+        recs[i].Depth = baseDepth + int64(len(parentRgtIds))
+        recs[i].UpdateSubtreeInfo()
+        parentRgtIds = append(parentRgtIds, recs[i].RgtId)
+    }
+}
+
+//Filter the records to keep only the records upto the depth 'maxDepth'. The
+// depth of the records must be updated before filtering.
+func FilterByDepth(recs []Data, maxDepth int64) []Data {
+    rows := []Data{}
+    for _, rec := range recs {
+        if rec.Depth <= maxDepth {
+            rows = append(rows, rec)
+        }
+    }
+    return rows
+}
+
+//Schema migration of a datastore, migrations are applied in the order of their
+// version. 'AppliedAt' is empty when the migration is pending.
+type Migration struct {
+    Version int         `json:"version" db:"Version"`
+    Desc string         `json:"desc" db:"Desc"`
+    AppliedAt string    `json:"appliedAt" db:"AppliedAt"`
+}
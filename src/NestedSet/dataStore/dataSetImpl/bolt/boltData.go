@@ -0,0 +1,576 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package bolt
+
+import (
+    "bytes"
+    "encoding/binary"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    bbolt "go.etcd.io/bbolt"
+    "NestedSet/appErrors"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl/memory"
+    "NestedSet/logger"
+)
+
+const (
+    // Trees are stored in the tree bucket with their id as key. Records of a
+    // tree are in its own bucket under the data bucket, with two buckets
+    // inside, records keyed by Uid and the ordered index on LftId.
+    BOLT_TREE_BUCKET = "trees"
+    BOLT_DATA_BUCKET = "data"
+    BOLT_RECORD_BUCKET = "records"
+    BOLT_LFTID_BUCKET = "lftIds"
+    // Trash entries of a tree keyed by the deleted record id, its created
+    // inside the tree bucket on the first soft delete.
+    BOLT_TRASH_BUCKET = "trash"
+    // Changes of the records in all the trees, keyed by the time and the
+    // order of the change. Its kept after the trees are deleted.
+    BOLT_HISTORY_BUCKET = "history"
+    // Snapshots of a tree keyed by the snapshot id, the records of the
+    // snapshots are kept in another bucket with the same key. Both are created
+    // inside the tree bucket on the first snapshot.
+    BOLT_SNAPSHOT_BUCKET = "snapshots"
+    BOLT_SNAPSHOT_RECORD_BUCKET = "snapshotRecords"
+)
+
+//Fields of a record that are stored, tree id is the bucket of the record and
+// rest of the fields are computed.
+type boltRecord struct {
+    Uid string      `json:"uid"`
+    Puid string     `json:"puid"`
+    Name string     `json:"name"`
+    Desc string     `json:"desc"`
+    Type string     `json:"type,omitempty"`
+    LftId int64     `json:"lftId"`
+    RgtId int64     `json:"rgtId"`
+    Attributes dataStore.Attributes `json:"attributes,omitempty"`
+    Version int64   `json:"version,omitempty"`
+    ChildVersion int64 `json:"childVersion,omitempty"`
+}
+
+//Buckets of a tree.
+type boltTreeData struct {
+    tree *dataStore.Tree
+    bucket *bbolt.Bucket
+    records *bbolt.Bucket
+    lftIds *bbolt.Bucket
+    //Number of records, stored in the tree bucket on every update.
+    count int64
+    //Records changed by the update as they were before it, nil for the
+    // records added by the update.
+    changed map[string]*dataStore.Data
+}
+
+//Index key of the left limit, big endian to keep the keys in the limit order.
+func lftIdKey(lftId int64) []byte {
+    key := make([]byte, 8)
+    binary.BigEndian.PutUint64(key, uint64(lftId))
+    return key
+}
+
+func newBoltRecord(rec *dataStore.Data) *boltRecord {
+    return &boltRecord{rec.Uid, rec.Puid, rec.Name, rec.Desc, rec.Type,
+                       rec.LftId, rec.RgtId, rec.Attributes.Copy(),
+                       rec.Version, rec.ChildVersion}
+}
+
+func (treeData *boltTreeData)getRecord(uid string) (*dataStore.Data, error) {
+    log := logger.GetLoggerInstance()
+    if len(uid) == 0 {
+        log.Error("Cannot retrieve a record with empty Uid")
+        return nil, appErrors.INVALID_INPUT
+    }
+    value := treeData.records.Get([]byte(uid))
+    if value == nil {
+        log.Error("0 records only present in the system")
+        return nil, appErrors.DATA_NOT_UNIQUE_ERROR
+    }
+    return treeData.decodeRecord(value)
+}
+
+func (treeData *boltTreeData)decodeRecord(value []byte) (*dataStore.Data,
+                                                         error) {
+    boltRec := new(boltRecord)
+    err := json.Unmarshal(value, boltRec)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to decode the record " +
+                                         "err : %s", err)
+        return nil, err
+    }
+    rec := new(dataStore.Data)
+    rec.Uid = boltRec.Uid
+    rec.TreeId = treeData.tree.TreeId
+    rec.Puid = boltRec.Puid
+    rec.Name = boltRec.Name
+    rec.Desc = boltRec.Desc
+    rec.Type = boltRec.Type
+    rec.LftId = boltRec.LftId
+    rec.RgtId = boltRec.RgtId
+    rec.Attributes = boltRec.Attributes
+    rec.Version = boltRec.Version
+    rec.ChildVersion = boltRec.ChildVersion
+    return rec, nil
+}
+
+func (treeData *boltTreeData)putRecord(rec *dataStore.Data) error {
+    value, err := json.Marshal(newBoltRecord(rec))
+    if err != nil {
+        return err
+    }
+    err = treeData.records.Put([]byte(rec.Uid), value)
+    if err != nil {
+        return err
+    }
+    return treeData.lftIds.Put(lftIdKey(rec.LftId), []byte(rec.Uid))
+}
+
+//Remove the index entry of the record, only when its not taken over by
+// another record.
+func (treeData *boltTreeData)deleteLftId(rec *dataStore.Data) error {
+    key := lftIdKey(rec.LftId)
+    if !bytes.Equal(treeData.lftIds.Get(key), []byte(rec.Uid)) {
+        return nil
+    }
+    return treeData.lftIds.Delete(key)
+}
+
+//Get the records with left limit in the range [fromLftId, toLftId) in
+// pre-order, using the LftId index.
+func (treeData *boltTreeData)getRange(fromLftId int64,
+                                      toLftId int64) ([]dataStore.Data,
+                                                      error) {
+    rows := []dataStore.Data{}
+    cursor := treeData.lftIds.Cursor()
+    toKey := lftIdKey(toLftId)
+    for key, uid := cursor.Seek(lftIdKey(fromLftId));
+        key != nil && bytes.Compare(key, toKey) < 0; key, uid = cursor.Next() {
+        rec, err := treeData.getRecord(string(uid))
+        if err != nil {
+            return nil, err
+        }
+        rows = append(rows, *rec)
+    }
+    return rows, nil
+}
+
+//Get the parents of the record upto root, ordered from the root. Walk is
+// limited to the size of the tree, in case of a loop in the parent links.
+func (treeData *boltTreeData)getParents(
+                            rec *dataStore.Data) ([]dataStore.Data, error) {
+    parents := []dataStore.Data{}
+    for puid := rec.Puid;
+        len(puid) != 0 && int64(len(parents)) < treeData.count; {
+        parent, err := treeData.getRecord(puid)
+        if err != nil {
+            return nil, err
+        }
+        parents = append([]dataStore.Data{*parent}, parents...)
+        puid = parent.Puid
+    }
+    return parents, nil
+}
+
+//Retrieve a record with record UID along with its computed fields.
+func (treeData *boltTreeData)getRecordInfo(uid string) (*dataStore.Data,
+                                                         error) {
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        return nil, err
+    }
+    parents, err := treeData.getParents(rec)
+    if err != nil {
+        return nil, err
+    }
+    rec.Depth = int64(len(parents))
+    rec.UpdateSubtreeInfo()
+    return rec, nil
+}
+
+//Get the direct childrens of the record in order. Every child is found by
+// skipping the subtree of its previous sibling in the LftId index.
+func (treeData *boltTreeData)getChildren(
+                                rec *dataStore.Data) ([]dataStore.Data, error) {
+    rows := []dataStore.Data{}
+    cursor := treeData.lftIds.Cursor()
+    toKey := lftIdKey(rec.RgtId)
+    nextLftId := rec.LftId + 1
+    for key, uid := cursor.Seek(lftIdKey(nextLftId));
+        key != nil && bytes.Compare(key, toKey) < 0;
+        key, uid = cursor.Seek(lftIdKey(nextLftId)) {
+        child, err := treeData.getRecord(string(uid))
+        if err != nil {
+            return nil, err
+        }
+        if child.Puid == rec.Uid {
+            rows = append(rows, *child)
+        }
+        //Skip the subtree of the child, corrupted limits never go back.
+        if child.RgtId + 1 > nextLftId {
+            nextLftId = child.RgtId + 1
+        } else {
+            nextLftId++
+        }
+    }
+    return rows, nil
+}
+
+//Get the records before 'lftId' whose subtree is not ended there, ordered
+// from the root.
+func (treeData *boltTreeData)getOpenParents(
+                                lftId int64) ([]dataStore.Data, error) {
+    parents := []dataStore.Data{}
+    cursor := treeData.lftIds.Cursor()
+    key, uid := cursor.Seek(lftIdKey(lftId + 1))
+    if key == nil {
+        key, uid = cursor.Last()
+    } else {
+        key, uid = cursor.Prev()
+    }
+    if key == nil {
+        return parents, nil
+    }
+    prev, err := treeData.getRecord(string(uid))
+    if err != nil {
+        return nil, err
+    }
+    prevParents, err := treeData.getParents(prev)
+    if err != nil {
+        return nil, err
+    }
+    for _, rec := range append(prevParents, *prev) {
+        if rec.RgtId > lftId {
+            parents = append(parents, rec)
+        }
+    }
+    return parents, nil
+}
+
+//Get a page of the records in pre-order from the LftId index, the records
+// before the cursor are read only when they are counted.
+func (treeData *boltTreeData)getPage(
+                        request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                         error) {
+    from := request.ReadFrom()
+    parents, err := treeData.getOpenParents(from)
+    if err != nil {
+        return nil, err
+    }
+    builder := dataStore.NewPageBuilder(request, parents)
+    cursor := treeData.lftIds.Cursor()
+    for key, uid := cursor.Seek(lftIdKey(from + 1)); key != nil;
+        key, uid = cursor.Next() {
+        rec, err := treeData.getRecord(string(uid))
+        if err != nil {
+            return nil, err
+        }
+        if !builder.AddRecord(rec) {
+            break
+        }
+    }
+    return builder.GetPage(treeData.count), nil
+}
+
+//Load all the records of the tree in memory.
+func (treeData *boltTreeData)loadTree() (*memory.TreeData, error) {
+    rows := []dataStore.Data{}
+    err := treeData.records.ForEach(func(key []byte, value []byte) error {
+        rec, err := treeData.decodeRecord(value)
+        if err != nil {
+            return err
+        }
+        rows = append(rows, *rec)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return memory.NewTreeData(treeData.tree, rows), nil
+}
+
+//Store the records of the tree that are changed in memory, 'oldRows' are the
+// records before the change.
+func (treeData *boltTreeData)saveTree(memTree *memory.TreeData,
+                                      oldRows []dataStore.Data) error {
+    var err error
+    newRows := memTree.GetAllRecords()
+    newRecs := make(map[string]*boltRecord)
+    for i := range newRows {
+        newRecs[newRows[i].Uid] = newBoltRecord(&newRows[i])
+    }
+    oldRecs := make(map[string]*boltRecord)
+    for i := range oldRows {
+        oldRec := &oldRows[i]
+        oldRecs[oldRec.Uid] = newBoltRecord(oldRec)
+        if _, ok := newRecs[oldRec.Uid]; ok {
+            continue
+        }
+        err = treeData.removeRecord(oldRec)
+        if err != nil {
+            return err
+        }
+    }
+    for i := range newRows {
+        newRec := &newRows[i]
+        oldRec, ok := oldRecs[newRec.Uid]
+        if ok && reflect.DeepEqual(newRecs[newRec.Uid], oldRec) {
+            continue
+        }
+        err = treeData.saveRecord(newRec)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+//Get the bucket 'name' of the tree, nil when its not present and 'create' is
+// not set.
+func (treeData *boltTreeData)getBucket(name string,
+                                       create bool) (*bbolt.Bucket, error) {
+    if create == false {
+        return treeData.bucket.Bucket([]byte(name)), nil
+    }
+    return treeData.bucket.CreateBucketIfNotExists([]byte(name))
+}
+
+func (treeData *boltTreeData)getTrash() ([]dataStore.TrashEntry, error) {
+    entries := []dataStore.TrashEntry{}
+    trash, _ := treeData.getBucket(BOLT_TRASH_BUCKET, false)
+    if trash == nil {
+        return entries, nil
+    }
+    err := trash.ForEach(func(key []byte, value []byte) error {
+        entry := dataStore.TrashEntry{}
+        err := json.Unmarshal(value, &entry)
+        if err != nil {
+            return err
+        }
+        entries = append(entries, entry)
+        return nil
+    })
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to decode the trash of " +
+                                         "tree %s err : %s",
+                                         treeData.tree.TreeId, err)
+        return nil, err
+    }
+    dataStore.SortTrashEntries(entries)
+    return entries, nil
+}
+
+func (treeData *boltTreeData)getTrashEntry(
+                            uid string) (*dataStore.TrashEntry, error) {
+    log := logger.GetLoggerInstance()
+    trash, _ := treeData.getBucket(BOLT_TRASH_BUCKET, false)
+    var value []byte
+    if trash != nil {
+        value = trash.Get([]byte(uid))
+    }
+    if value == nil {
+        log.Error("Record %s is not present in the trash", uid)
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    entry := new(dataStore.TrashEntry)
+    err := json.Unmarshal(value, entry)
+    if err != nil {
+        log.Error("Failed to decode trash entry %s err : %s", uid, err)
+        return nil, err
+    }
+    return entry, nil
+}
+
+func (treeData *boltTreeData)putTrashEntry(
+                                    entry *dataStore.TrashEntry) error {
+    trash, err := treeData.getBucket(BOLT_TRASH_BUCKET, true)
+    if err != nil {
+        return err
+    }
+    value, err := json.Marshal(entry)
+    if err != nil {
+        return err
+    }
+    return trash.Put([]byte(entry.Uid), value)
+}
+
+func (treeData *boltTreeData)deleteTrashEntry(uid string) error {
+    trash, err := treeData.getBucket(BOLT_TRASH_BUCKET, true)
+    if err != nil {
+        return err
+    }
+    return trash.Delete([]byte(uid))
+}
+
+func (treeData *boltTreeData)getSnapshots() ([]dataStore.Snapshot, error) {
+    snapshots := []dataStore.Snapshot{}
+    bucket, _ := treeData.getBucket(BOLT_SNAPSHOT_BUCKET, false)
+    if bucket == nil {
+        return snapshots, nil
+    }
+    err := bucket.ForEach(func(key []byte, value []byte) error {
+        snapshot := dataStore.Snapshot{}
+        err := json.Unmarshal(value, &snapshot)
+        if err != nil {
+            return err
+        }
+        snapshots = append(snapshots, snapshot)
+        return nil
+    })
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to decode the snapshots of " +
+                                         "tree %s err : %s",
+                                         treeData.tree.TreeId, err)
+        return nil, err
+    }
+    dataStore.SortSnapshots(snapshots)
+    return snapshots, nil
+}
+
+//Get the value of the snapshot from the bucket 'name' of the tree.
+func (treeData *boltTreeData)getSnapshotValue(name string,
+                                              snapshotId string,
+                                              value interface{}) error {
+    log := logger.GetLoggerInstance()
+    bucket, _ := treeData.getBucket(name, false)
+    var content []byte
+    if bucket != nil {
+        content = bucket.Get([]byte(snapshotId))
+    }
+    if content == nil {
+        log.Error("Snapshot %s is not present in the tree %s", snapshotId,
+                   treeData.tree.TreeId)
+        return appErrors.DATA_NOT_FOUND
+    }
+    err := json.Unmarshal(content, value)
+    if err != nil {
+        log.Error("Failed to decode snapshot %s err : %s", snapshotId, err)
+        return err
+    }
+    return nil
+}
+
+func (treeData *boltTreeData)getSnapshot(
+                        snapshotId string) (*dataStore.Snapshot, error) {
+    snapshot := new(dataStore.Snapshot)
+    err := treeData.getSnapshotValue(BOLT_SNAPSHOT_BUCKET, snapshotId,
+                                     snapshot)
+    if err != nil {
+        return nil, err
+    }
+    return snapshot, nil
+}
+
+//Get the records of the snapshot in pre-order.
+func (treeData *boltTreeData)getSnapshotRecords(
+                            snapshotId string) ([]dataStore.Data, error) {
+    recs := []dataStore.Data{}
+    err := treeData.getSnapshotValue(BOLT_SNAPSHOT_RECORD_BUCKET, snapshotId,
+                                     &recs)
+    if err != nil {
+        return nil, err
+    }
+    return recs, nil
+}
+
+func (treeData *boltTreeData)putSnapshot(snapshot *dataStore.Snapshot,
+                                         recs []dataStore.Data) error {
+    values := map[string]interface{}{BOLT_SNAPSHOT_BUCKET : snapshot,
+                                     BOLT_SNAPSHOT_RECORD_BUCKET : recs}
+    for name, value := range values {
+        bucket, err := treeData.getBucket(name, true)
+        if err != nil {
+            return err
+        }
+        content, err := json.Marshal(value)
+        if err != nil {
+            return err
+        }
+        err = bucket.Put([]byte(snapshot.SnapshotId), content)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (treeData *boltTreeData)deleteSnapshot(snapshotId string) error {
+    for _, name := range []string{BOLT_SNAPSHOT_BUCKET,
+                                  BOLT_SNAPSHOT_RECORD_BUCKET} {
+        bucket, err := treeData.getBucket(name, true)
+        if err != nil {
+            return err
+        }
+        err = bucket.Delete([]byte(snapshotId))
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+//Key of the history entry, sorts the entries in the order of time.
+func historyKey(entry *dataStore.HistoryEntry) []byte {
+    return []byte(fmt.Sprintf("%s/%06d/%s", entry.ChangedAt, entry.Seq,
+                              entry.Id))
+}
+
+//Add the changes from the records 'before' to the records 'after' the update
+// to the history.
+func addHistory(tx *bbolt.Tx, change *dataStore.Change,
+                before []dataStore.Data, after []dataStore.Data) error {
+    entries, err := change.GetHistory(before, after)
+    if err != nil {
+        return err
+    }
+    history := tx.Bucket([]byte(BOLT_HISTORY_BUCKET))
+    for i := range entries {
+        value, err := json.Marshal(&entries[i])
+        if err != nil {
+            return err
+        }
+        err = history.Put(historyKey(&entries[i]), value)
+        if err != nil {
+            logger.GetLoggerInstance().Error("Failed to add the change of " +
+                                             "%s to the history err : %s",
+                                             entries[i].Uid, err)
+            return err
+        }
+    }
+    return nil
+}
+
+//Get the history entries from 'since' that match the filter, all of them when
+// the filter is nil.
+func getHistory(tx *bbolt.Tx, since string,
+        filter func(*dataStore.HistoryEntry) bool) ([]dataStore.HistoryEntry,
+                                                    error) {
+    entries := []dataStore.HistoryEntry{}
+    cursor := tx.Bucket([]byte(BOLT_HISTORY_BUCKET)).Cursor()
+    for key, value := cursor.Seek([]byte(since)); key != nil;
+        key, value = cursor.Next() {
+        entry := dataStore.HistoryEntry{}
+        err := json.Unmarshal(value, &entry)
+        if err != nil {
+            logger.GetLoggerInstance().Error("Failed to decode the history " +
+                                             "entry %s err : %s", key, err)
+            return nil, err
+        }
+        if filter == nil || filter(&entry) {
+            entries = append(entries, entry)
+        }
+    }
+    return entries, nil
+}
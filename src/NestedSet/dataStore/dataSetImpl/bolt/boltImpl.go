@@ -0,0 +1,892 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package bolt
+
+import (
+    "encoding/json"
+    "fmt"
+    "path/filepath"
+    "sort"
+    "time"
+    bbolt "go.etcd.io/bbolt"
+    "NestedSet/appErrors"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl/memory"
+    "NestedSet/logger"
+    "NestedSet/sys"
+)
+
+const (
+    BACKEND_NAME = "bolt"
+    // Seconds to wait for the DB file lock, when its used by another process.
+    BOLT_OPEN_TIMEOUT = 5
+    //Backend option to set the open timeout in seconds.
+    OPTION_TIMEOUT = "timeout"
+)
+
+func init() {
+    err := dataStore.RegisterBackend(BACKEND_NAME, newDataStore)
+    if err != nil {
+        panic(err)
+    }
+}
+
+//Datastore on an embedded bolt key-value file, it needs no cgo. Updates are
+// serialized by bolt, readers see a consistent tree in their transaction.
+type BoltDataStore struct {
+    dblogger *logger.Logging
+    DBConn *bbolt.DB
+    openTimeout time.Duration
+}
+
+//Open the bolt DB file at 'dbPath', it is created when not present.
+func (boltds *BoltDataStore)CreateDBConnection(dbPath string) error {
+    dbFile, err := filepath.Abs(dbPath)
+    if err != nil {
+        boltds.dblogger.Error("Failed to open DB file, %s", err.Error())
+        return err
+    }
+    boltds.DBConn, err = bbolt.Open(dbFile, 0600,
+                                    &bbolt.Options{Timeout: boltds.openTimeout})
+    if err != nil {
+        boltds.dblogger.Error("Failed to open bolt DB %s err : %s", dbFile,
+                              err)
+        return err
+    }
+    boltds.dblogger.Trace("Created bolt DB connection to %s", dbFile)
+    return nil
+}
+
+//Create the top level buckets and the default tree if not exisits.
+func (boltds *BoltDataStore)CreateDataStoreTables() error {
+    if boltds.DBConn == nil {
+        return fmt.Errorf("Null DB connection, cannot create tables")
+    }
+    return boltds.DBConn.Update(func(tx *bbolt.Tx) error {
+        for _, bucket := range []string{BOLT_TREE_BUCKET, BOLT_DATA_BUCKET,
+                                        BOLT_HISTORY_BUCKET} {
+            _, err := tx.CreateBucketIfNotExists([]byte(bucket))
+            if err != nil {
+                boltds.dblogger.Error("Failed to create bucket %s err : %s",
+                                      bucket, err)
+                return err
+            }
+        }
+        err := boltds.putRecordCounts(tx)
+        if err != nil {
+            return err
+        }
+        if tx.Bucket([]byte(BOLT_TREE_BUCKET)).Get(
+                            []byte(dataStore.DEFAULT_TREE_ID)) != nil {
+            boltds.dblogger.Trace("Default tree already present.")
+            return nil
+        }
+        tree := new(dataStore.Tree)
+        tree.TreeId = dataStore.DEFAULT_TREE_ID
+        tree.Name = dataStore.DEFAULT_TREE_ID
+        tree.Desc = "The default tree in the system"
+        return boltds.insertTree(tx, tree)
+    })
+}
+
+//Store the record count of the trees created before it was kept in the tree.
+func (boltds *BoltDataStore)putRecordCounts(tx *bbolt.Tx) error {
+    trees, err := boltds.getAllTrees(tx)
+    if err != nil {
+        return err
+    }
+    for _, tree := range trees {
+        treeData, err := boltds.getTree(tx, tree.TreeId)
+        if err != nil {
+            return err
+        }
+        if _, ok := treeData.getRecordCount(); ok {
+            continue
+        }
+        err = treeData.putRecordCount()
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (boltds *BoltDataStore)getAllTrees(tx *bbolt.Tx) ([]dataStore.Tree,
+                                                       error) {
+    trees := []dataStore.Tree{}
+    err := tx.Bucket([]byte(BOLT_TREE_BUCKET)).ForEach(
+                                    func(key []byte, value []byte) error {
+        tree := dataStore.Tree{}
+        err := json.Unmarshal(value, &tree)
+        if err != nil {
+            return err
+        }
+        trees = append(trees, tree)
+        return nil
+    })
+    if err != nil {
+        boltds.dblogger.Error("Failed to retereive all the trees err : %s",
+                              err)
+        return nil, err
+    }
+    return trees, nil
+}
+
+//Create a new tree along with its root record. Tree id is generated when its
+// not provided by the user.
+func (boltds *BoltDataStore)insertTree(tx *bbolt.Tx,
+                                       tree *dataStore.Tree) error {
+    var err error
+    if len(tree.Name) == 0 {
+        boltds.dblogger.Error("Cannot create a tree with empty name")
+        return appErrors.INVALID_INPUT
+    }
+    trees, err := boltds.getAllTrees(tx)
+    if err != nil {
+        return err
+    }
+    for _, present := range trees {
+        if present.Name == tree.Name || present.TreeId == tree.TreeId {
+            boltds.dblogger.Info("Cannot create tree, %s already present " +
+                                 "in the system", tree.Name)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    if len(tree.TreeId) == 0 {
+        tree.TreeId, err = sys.NewUUIDString()
+        if err != nil {
+            boltds.dblogger.Error("Failed to create UUID, cannot create a " +
+                                  "tree")
+            return err
+        }
+    }
+    if tree.TreeId == dataStore.DEFAULT_TREE_ID {
+        tree.RootUid = dataStore.ROOT_UID
+    } else {
+        tree.RootUid, err = sys.NewUUIDString()
+        if err != nil {
+            boltds.dblogger.Error("Failed to create UUID, cannot create a " +
+                                  "tree root")
+            return err
+        }
+    }
+    err = boltds.putTree(tx, tree)
+    if err != nil {
+        return err
+    }
+    treeBucket, err := tx.Bucket([]byte(BOLT_DATA_BUCKET)).CreateBucket(
+                                                        []byte(tree.TreeId))
+    if err != nil {
+        return err
+    }
+    treeData := &boltTreeData{tree: tree, bucket: treeBucket}
+    treeData.records, err = treeBucket.CreateBucket(
+                                                []byte(BOLT_RECORD_BUCKET))
+    if err != nil {
+        return err
+    }
+    treeData.lftIds, err = treeBucket.CreateBucket([]byte(BOLT_LFTID_BUCKET))
+    if err != nil {
+        return err
+    }
+    err = treeData.insertRoot()
+    if err != nil {
+        return err
+    }
+    return treeData.putRecordCount()
+}
+
+//Store the tree in the tree bucket.
+func (boltds *BoltDataStore)putTree(tx *bbolt.Tx, tree *dataStore.Tree) error {
+    value, err := json.Marshal(tree)
+    if err != nil {
+        return err
+    }
+    err = tx.Bucket([]byte(BOLT_TREE_BUCKET)).Put([]byte(tree.TreeId), value)
+    if err != nil {
+        boltds.dblogger.Error("Failed to store tree %s err %s", tree.Name,
+                              err)
+    }
+    return err
+}
+
+//Get the tree along with its buckets.
+func (boltds *BoltDataStore)getTree(tx *bbolt.Tx,
+                                    treeId string) (*boltTreeData, error) {
+    if len(treeId) == 0 {
+        boltds.dblogger.Error("Cannot retrieve a tree with empty id")
+        return nil, appErrors.INVALID_INPUT
+    }
+    value := tx.Bucket([]byte(BOLT_TREE_BUCKET)).Get([]byte(treeId))
+    if value == nil {
+        boltds.dblogger.Error("Tree %s is not present in the system", treeId)
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    treeData := new(boltTreeData)
+    treeData.tree = new(dataStore.Tree)
+    err := json.Unmarshal(value, treeData.tree)
+    if err != nil {
+        boltds.dblogger.Error("Failed to decode the tree %s err : %s", treeId,
+                              err)
+        return nil, err
+    }
+    treeBucket := tx.Bucket([]byte(BOLT_DATA_BUCKET)).Bucket([]byte(treeId))
+    if treeBucket == nil {
+        boltds.dblogger.Error("Records of tree %s are not present", treeId)
+        return nil, appErrors.INVALID_STATE
+    }
+    treeData.bucket = treeBucket
+    treeData.records = treeBucket.Bucket([]byte(BOLT_RECORD_BUCKET))
+    treeData.lftIds = treeBucket.Bucket([]byte(BOLT_LFTID_BUCKET))
+    count, ok := treeData.getRecordCount()
+    if !ok {
+        //Tree is stored before the count is kept, count the records.
+        count = int64(treeData.records.Stats().KeyN)
+    }
+    treeData.count = count
+    return treeData, nil
+}
+
+//Run a tree read in a bolt read transaction.
+func (boltds *BoltDataStore)readTree(treeId string,
+                                readFn func(*boltTreeData) error) error {
+    return boltds.DBConn.View(func(tx *bbolt.Tx) error {
+        treeData, err := boltds.getTree(tx, treeId)
+        if err != nil {
+            return err
+        }
+        return readFn(treeData)
+    })
+}
+
+//Run a tree update that changes only the records it needs. Nothing is stored
+// when the update fails. The versions of the changed records are updated and
+// the changes are added to the history, unless 'change' is nil.
+func (boltds *BoltDataStore)updateTree(treeId string, change *dataStore.Change,
+                                  updateFn func(*boltTreeData) error) error {
+    return boltds.DBConn.Update(func(tx *bbolt.Tx) error {
+        treeData, err := boltds.getTree(tx, treeId)
+        if err != nil {
+            return err
+        }
+        treeData.startUpdate()
+        err = updateFn(treeData)
+        if err != nil {
+            return err
+        }
+        err = treeData.putRecordCount()
+        if err != nil {
+            return err
+        }
+        if change == nil {
+            return nil
+        }
+        before, after, err := treeData.getChanges()
+        if err != nil {
+            return err
+        }
+        err = addHistory(tx, change, before, after)
+        if err != nil {
+            return err
+        }
+        err = treeData.updateVersions(before, after)
+        if err != nil {
+            return err
+        }
+        return boltds.putTree(tx, treeData.tree)
+    })
+}
+
+//Run a tree update on the tree loaded in memory, and store only the records
+// that are changed.
+func (boltds *BoltDataStore)updateMemTree(treeId string,
+                                change *dataStore.Change,
+                                updateFn func(*memory.TreeData) error) error {
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        memTree, err := treeData.loadTree()
+        if err != nil {
+            return err
+        }
+        oldRows := memTree.GetAllRecords()
+        err = updateFn(memTree)
+        if err != nil {
+            return err
+        }
+        return treeData.saveTree(memTree, oldRows)
+    })
+}
+
+func (boltds *BoltDataStore)CreateTree(tree *dataStore.Tree) error {
+    return boltds.DBConn.Update(func(tx *bbolt.Tx) error {
+        return boltds.insertTree(tx, tree)
+    })
+}
+
+//Delete the tree and all its records. Default tree cannot be deleted.
+func (boltds *BoltDataStore)DeleteTree(treeId string) error {
+    if treeId == dataStore.DEFAULT_TREE_ID {
+        boltds.dblogger.Error("Cannot delete default tree, as its owned by " +
+                              "application")
+        return appErrors.INVALID_INPUT
+    }
+    return boltds.DBConn.Update(func(tx *bbolt.Tx) error {
+        _, err := boltds.getTree(tx, treeId)
+        if err != nil {
+            return err
+        }
+        err = tx.Bucket([]byte(BOLT_DATA_BUCKET)).DeleteBucket([]byte(treeId))
+        if err != nil {
+            boltds.dblogger.Error("Failed to delete records of tree %s " +
+                                  "err : %s", treeId, err)
+            return err
+        }
+        return tx.Bucket([]byte(BOLT_TREE_BUCKET)).Delete([]byte(treeId))
+    })
+}
+
+func (boltds *BoltDataStore)GetTree(treeId string) (*dataStore.Tree, error) {
+    var tree *dataStore.Tree
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        tree = treeData.tree
+        return nil
+    })
+    return tree, err
+}
+
+//Get all the trees ordered on their name.
+func (boltds *BoltDataStore)GetAllTrees() ([]dataStore.Tree, error) {
+    var trees []dataStore.Tree
+    err := boltds.DBConn.View(func(tx *bbolt.Tx) error {
+        var err error
+        trees, err = boltds.getAllTrees(tx)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    sort.Slice(trees, func(i, j int) bool {
+        return trees[i].Name < trees[j].Name
+    })
+    return trees, nil
+}
+
+func (boltds *BoltDataStore)VerifyTree(treeId string) (*dataStore.TreeReport,
+                                                       error) {
+    var report *dataStore.TreeReport
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        memTree, err := treeData.loadTree()
+        if err != nil {
+            return err
+        }
+        report = memTree.VerifyTree()
+        return nil
+    })
+    return report, err
+}
+
+func (boltds *BoltDataStore)RebuildTree(treeId string) error {
+    return boltds.updateMemTree(treeId, nil,
+                                func(memTree *memory.TreeData) error {
+        return memTree.RebuildTree()
+    })
+}
+
+func (boltds *BoltDataStore)CreateRecord(treeId string, rec *dataStore.Data,
+                                         actor string) error {
+    change := dataStore.NewChange(actor)
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        return treeData.insertRecord(rec)
+    })
+}
+
+func (boltds *BoltDataStore)DeleteRecord(treeId string, recid string,
+                                         version int64, actor string) error {
+    change := dataStore.NewChange(actor)
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        err := treeData.checkVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        return treeData.deleteRecord(recid)
+    })
+}
+
+func (boltds *BoltDataStore)DeleteLeafRecord(treeId string, recid string,
+                                             version int64,
+                                             actor string) error {
+    change := dataStore.NewChange(actor)
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        err := treeData.checkVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        return treeData.deleteLeafRecord(recid)
+    })
+}
+
+func (boltds *BoltDataStore)PromoteRecord(treeId string, recid string,
+                                          version int64, actor string) error {
+    change := dataStore.NewChange(actor)
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        err := treeData.checkVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        return treeData.promoteRecord(recid)
+    })
+}
+
+func (boltds *BoltDataStore)UpdateRecord(treeId string, rec *dataStore.Data,
+                                         version int64, actor string) error {
+    change := dataStore.NewChange(actor)
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        err := treeData.checkVersion(rec.Uid, version, false)
+        if err != nil {
+            return err
+        }
+        return treeData.updateRecord(rec)
+    })
+}
+
+func (boltds *BoltDataStore)MoveRecord(treeId string, recid string,
+                                       newPuid string, version int64,
+                                       actor string) error {
+    change := dataStore.NewChange(actor)
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        err := treeData.checkVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        return treeData.moveRecord(recid, newPuid)
+    })
+}
+
+func (boltds *BoltDataStore)CopySubtree(treeId string, srcId string,
+                                        destPuid string,
+                                        actor string) (*dataStore.Data,
+                                                       error) {
+    var uid string
+    change := dataStore.NewChange(actor)
+    err := boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        var err error
+        uid, err = treeData.copyRecord(srcId, destPuid)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    //Copied records get their versions after the update.
+    return boltds.GetRecord(treeId, uid)
+}
+
+func (boltds *BoltDataStore)GetRecord(treeId string,
+                                      recid string) (*dataStore.Data, error) {
+    var row *dataStore.Data
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        var err error
+        row, err = treeData.getRecordInfo(recid)
+        if err != nil {
+            return err
+        }
+        row.UpdateSubtreeVersion()
+        return nil
+    })
+    return row, err
+}
+
+func (boltds *BoltDataStore)GetAncestors(treeId string,
+                                         recid string) ([]dataStore.Data,
+                                                        error) {
+    var rows []dataStore.Data
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        rec, err := treeData.getRecord(recid)
+        if err != nil {
+            return err
+        }
+        rows, err = treeData.getParents(rec)
+        if err != nil {
+            return err
+        }
+        rows = append(rows, *rec)
+        dataStore.UpdateTreeInfo(rows, 0)
+        return nil
+    })
+    return rows, err
+}
+
+func (boltds *BoltDataStore)GetDescendants(treeId string, recid string,
+                                           maxDepth int) ([]dataStore.Data,
+                                                          error) {
+    var rows []dataStore.Data
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        rec, err := treeData.getRecordInfo(recid)
+        if err != nil {
+            return err
+        }
+        rows, err = treeData.getRange(rec.LftId + 1, rec.RgtId)
+        if err != nil {
+            return err
+        }
+        dataStore.UpdateTreeInfo(rows, rec.Depth + 1)
+        if maxDepth > 0 {
+            rows = dataStore.FilterByDepth(rows,
+                                           rec.Depth + int64(maxDepth))
+        }
+        return nil
+    })
+    return rows, err
+}
+
+func (boltds *BoltDataStore)GetChildren(treeId string,
+                                        recid string) ([]dataStore.Data,
+                                                       error) {
+    var rows []dataStore.Data
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        rec, err := treeData.getRecordInfo(recid)
+        if err != nil {
+            return err
+        }
+        rows, err = treeData.getChildren(rec)
+        if err != nil {
+            return err
+        }
+        dataStore.UpdateTreeInfo(rows, rec.Depth + 1)
+        return nil
+    })
+    return rows, err
+}
+
+func (boltds *BoltDataStore)GetRecordByName(treeId string,
+                                            name string) ([]dataStore.Data,
+                                                         error) {
+    var rows []dataStore.Data
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        memTree, err := treeData.loadTree()
+        if err != nil {
+            return err
+        }
+        rows, err = memTree.GetRecordByName(name)
+        return err
+    })
+    return rows, err
+}
+
+func (boltds *BoltDataStore)GetAllRecords(treeId string) ([]dataStore.Data,
+                                                          error) {
+    var rows []dataStore.Data
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        memTree, err := treeData.loadTree()
+        if err != nil {
+            return err
+        }
+        rows = memTree.GetAllRecords()
+        return nil
+    })
+    return rows, err
+}
+
+func (boltds *BoltDataStore)GetRecordsPage(treeId string,
+                        request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                         error) {
+    var page *dataStore.RecordPage
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        var err error
+        page, err = treeData.getPage(request)
+        return err
+    })
+    return page, err
+}
+
+func (boltds *BoltDataStore)GetRecordByNamePage(treeId string, name string,
+                        request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                         error) {
+    if len(name) == 0 {
+        boltds.dblogger.Error("Failed to get the records, name is null")
+        return nil, appErrors.INVALID_INPUT
+    }
+    nameRequest := *request
+    nameRequest.Filter.Name = &name
+    return boltds.GetRecordsPage(treeId, &nameRequest)
+}
+
+func (boltds *BoltDataStore)TrashRecord(treeId string, recid string,
+                                        version int64, actor string) error {
+    change := dataStore.NewChange(actor)
+    change.RemoveAction = dataStore.HISTORY_TRASH
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        err := treeData.checkVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        entry, err := treeData.trashRecord(recid, time.Now())
+        if err != nil {
+            return err
+        }
+        return treeData.putTrashEntry(entry)
+    })
+}
+
+func (boltds *BoltDataStore)GetTrash(treeId string) ([]dataStore.TrashEntry,
+                                                     error) {
+    var entries []dataStore.TrashEntry
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        var err error
+        entries, err = treeData.getTrash()
+        return err
+    })
+    return entries, err
+}
+
+func (boltds *BoltDataStore)RestoreRecord(treeId string, recid string,
+                                          puid string, actor string) error {
+    change := dataStore.NewChange(actor)
+    change.AddAction = dataStore.HISTORY_RESTORE
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        entry, err := treeData.getTrashEntry(recid)
+        if err != nil {
+            return err
+        }
+        if len(puid) == 0 {
+            puid = entry.Puid
+        }
+        err = treeData.restoreSubtree(entry, puid, nil)
+        if err != nil {
+            return err
+        }
+        return treeData.deleteTrashEntry(recid)
+    })
+}
+
+func (boltds *BoltDataStore)PurgeTrash(before time.Time) (int, error) {
+    count := 0
+    beforeStr := dataStore.TrashTimeString(before)
+    err := boltds.DBConn.Update(func(tx *bbolt.Tx) error {
+        trees, err := boltds.getAllTrees(tx)
+        if err != nil {
+            return err
+        }
+        for _, tree := range trees {
+            treeData, err := boltds.getTree(tx, tree.TreeId)
+            if err != nil {
+                return err
+            }
+            entries, err := treeData.getTrash()
+            if err != nil {
+                return err
+            }
+            for _, entry := range entries {
+                if entry.DeletedAt >= beforeStr {
+                    break
+                }
+                err = treeData.deleteTrashEntry(entry.Uid)
+                if err != nil {
+                    return err
+                }
+                count++
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        boltds.dblogger.Error("Failed to purge the trash err : %s", err)
+        return 0, err
+    }
+    return count, nil
+}
+
+func (boltds *BoltDataStore)GetRecordHistory(treeId string,
+                            recid string) ([]dataStore.HistoryEntry, error) {
+    var entries []dataStore.HistoryEntry
+    filter := func(entry *dataStore.HistoryEntry) bool {
+        return entry.TreeId == treeId && entry.Uid == recid
+    }
+    err := boltds.DBConn.View(func(tx *bbolt.Tx) error {
+        var err error
+        entries, err = getHistory(tx, "", filter)
+        return err
+    })
+    return entries, err
+}
+
+func (boltds *BoltDataStore)GetAudit(
+                        since time.Time) ([]dataStore.HistoryEntry, error) {
+    var entries []dataStore.HistoryEntry
+    err := boltds.DBConn.View(func(tx *bbolt.Tx) error {
+        var err error
+        entries, err = getHistory(tx, dataStore.HistoryTimeString(since),
+                                  nil)
+        return err
+    })
+    return entries, err
+}
+
+func (boltds *BoltDataStore)CreateSnapshot(treeId string,
+                                        snapshot *dataStore.Snapshot) error {
+    return boltds.DBConn.Update(func(tx *bbolt.Tx) error {
+        treeData, err := boltds.getTree(tx, treeId)
+        if err != nil {
+            return err
+        }
+        memTree, err := treeData.loadTree()
+        if err != nil {
+            return err
+        }
+        recs := memTree.GetAllRecords()
+        err = snapshot.Init(treeId, len(recs), time.Now())
+        if err != nil {
+            return err
+        }
+        snapshots, err := treeData.getSnapshots()
+        if err != nil {
+            return err
+        }
+        for _, entry := range snapshots {
+            if entry.Name == snapshot.Name {
+                boltds.dblogger.Info("Cannot create snapshot, %s already " +
+                                     "present in the tree %s", snapshot.Name,
+                                     treeId)
+                return appErrors.DATA_PRESENT_IN_SYSTEM
+            }
+        }
+        return treeData.putSnapshot(snapshot, recs)
+    })
+}
+
+func (boltds *BoltDataStore)DeleteSnapshot(treeId string,
+                                           snapshotId string) error {
+    return boltds.DBConn.Update(func(tx *bbolt.Tx) error {
+        treeData, err := boltds.getTree(tx, treeId)
+        if err != nil {
+            return err
+        }
+        _, err = treeData.getSnapshot(snapshotId)
+        if err != nil {
+            return err
+        }
+        return treeData.deleteSnapshot(snapshotId)
+    })
+}
+
+func (boltds *BoltDataStore)GetSnapshot(treeId string,
+                            snapshotId string) (*dataStore.Snapshot, error) {
+    var snapshot *dataStore.Snapshot
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        var err error
+        snapshot, err = treeData.getSnapshot(snapshotId)
+        return err
+    })
+    return snapshot, err
+}
+
+func (boltds *BoltDataStore)GetAllSnapshots(
+                            treeId string) ([]dataStore.Snapshot, error) {
+    var snapshots []dataStore.Snapshot
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        var err error
+        snapshots, err = treeData.getSnapshots()
+        return err
+    })
+    return snapshots, err
+}
+
+func (boltds *BoltDataStore)GetSnapshotRecords(treeId string,
+                                    snapshotId string,
+                                    recid string) ([]dataStore.Data, error) {
+    var rows []dataStore.Data
+    err := boltds.readTree(treeId, func(treeData *boltTreeData) error {
+        recs, err := treeData.getSnapshotRecords(snapshotId)
+        if err != nil {
+            return err
+        }
+        rows, err = dataStore.GetSnapshotSubtree(recs, recid)
+        return err
+    })
+    return rows, err
+}
+
+func (boltds *BoltDataStore)RestoreSnapshot(treeId string,
+                                            snapshotId string, recid string,
+                                            actor string) error {
+    change := dataStore.NewChange(actor)
+    change.AddAction = dataStore.HISTORY_RESTORE
+    change.RemoveAction = dataStore.HISTORY_TRASH
+    return boltds.updateTree(treeId, change,
+                             func(treeData *boltTreeData) error {
+        recs, err := treeData.getSnapshotRecords(snapshotId)
+        if err != nil {
+            return err
+        }
+        var entries []dataStore.TrashEntry
+        if len(recid) == 0 || recid == treeData.tree.RootUid {
+            //Entire tree is replaced, its restored on the tree in memory.
+            entries, err = treeData.restoreSnapshotTree(recs, time.Now())
+        } else {
+            entries, err = treeData.restoreSnapshotSubtree(recs, recid,
+                                                           time.Now())
+        }
+        if err != nil {
+            return err
+        }
+        //Trash entries of the records put back in the tree are stale.
+        restored, err := dataStore.GetSnapshotSubtree(recs, recid)
+        if err != nil {
+            return err
+        }
+        trash, err := treeData.getTrash()
+        if err != nil {
+            return err
+        }
+        for _, uid := range dataStore.GetRestoredTrash(trash, restored) {
+            err = treeData.deleteTrashEntry(uid)
+            if err != nil {
+                return err
+            }
+        }
+        for i := range entries {
+            err = treeData.putTrashEntry(&entries[i])
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+//Wait for the DB file lock can be set with the 'timeout' option. Only one
+// process can open the DB file at a time.
+func newDataStore(
+        options dataStore.BackendOptions) (dataStore.DataSetInterface, error) {
+    err := options.Check(OPTION_TIMEOUT)
+    if err != nil {
+        return nil, err
+    }
+    timeout, err := options.GetInt(OPTION_TIMEOUT, BOLT_OPEN_TIMEOUT)
+    if err != nil {
+        return nil, err
+    }
+    boltds := new(BoltDataStore)
+    boltds.dblogger = logger.GetLoggerInstance()
+    boltds.openTimeout = time.Duration(timeout) * time.Second
+    return boltds, nil
+}
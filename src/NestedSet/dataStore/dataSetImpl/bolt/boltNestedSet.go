@@ -0,0 +1,866 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package bolt
+
+import (
+    "encoding/binary"
+    "math"
+    "sort"
+    "time"
+    "NestedSet/appErrors"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+    "NestedSet/sys"
+)
+
+//Updates of a tree change only the records in the nested set limits they
+// need, using the LftId index. The records are kept as they were before the
+// update on their first change, so the versions and the history are found
+// from the changed records only.
+
+const (
+    // Number of records in the tree, kept in the tree bucket so its read
+    // without scanning the records.
+    BOLT_RECORD_COUNT_KEY = "recordCount"
+)
+
+func (treeData *boltTreeData)getRecordCount() (int64, bool) {
+    value := treeData.bucket.Get([]byte(BOLT_RECORD_COUNT_KEY))
+    if value == nil {
+        return 0, false
+    }
+    return int64(binary.BigEndian.Uint64(value)), true
+}
+
+func (treeData *boltTreeData)putRecordCount() error {
+    value := make([]byte, 8)
+    binary.BigEndian.PutUint64(value, uint64(treeData.count))
+    return treeData.bucket.Put([]byte(BOLT_RECORD_COUNT_KEY), value)
+}
+
+//Start keeping the records changed by an update.
+func (treeData *boltTreeData)startUpdate() {
+    treeData.changed = make(map[string]*dataStore.Data)
+}
+
+//Keep the record 'uid' as it was before the update, 'rec' is its stored value
+// and nil when its added by the update.
+func (treeData *boltTreeData)keepRecord(uid string, rec *dataStore.Data) {
+    if treeData.changed == nil {
+        return
+    }
+    if _, ok := treeData.changed[uid]; ok {
+        return
+    }
+    if rec != nil {
+        row := *rec
+        row.Attributes = rec.Attributes.Copy()
+        rec = &row
+    }
+    treeData.changed[uid] = rec
+}
+
+//Get the stored value of the record, nil when its not present.
+func (treeData *boltTreeData)getStoredRecord(
+                                uid string) (*dataStore.Data, error) {
+    value := treeData.records.Get([]byte(uid))
+    if value == nil {
+        return nil, nil
+    }
+    return treeData.decodeRecord(value)
+}
+
+//Store the record along with its index entry, the record is added when its
+// not present.
+func (treeData *boltTreeData)saveRecord(rec *dataStore.Data) error {
+    stored, err := treeData.getStoredRecord(rec.Uid)
+    if err != nil {
+        return err
+    }
+    treeData.keepRecord(rec.Uid, stored)
+    if stored == nil {
+        treeData.count++
+    } else if stored.LftId != rec.LftId {
+        err = treeData.deleteLftId(stored)
+        if err != nil {
+            return err
+        }
+    }
+    return treeData.putRecord(rec)
+}
+
+//Remove the record along with its index entry.
+func (treeData *boltTreeData)removeRecord(rec *dataStore.Data) error {
+    stored, err := treeData.getStoredRecord(rec.Uid)
+    if err != nil || stored == nil {
+        return err
+    }
+    treeData.keepRecord(rec.Uid, stored)
+    err = treeData.deleteLftId(stored)
+    if err != nil {
+        return err
+    }
+    treeData.count--
+    return treeData.records.Delete([]byte(rec.Uid))
+}
+
+//Sort the records on their left limit.
+func sortRecords(recs []dataStore.Data) {
+    sort.Slice(recs, func(i, j int) bool {
+        return recs[i].LftId < recs[j].LftId
+    })
+}
+
+//Get the changed records before and after the update in pre-order.
+func (treeData *boltTreeData)getChanges() ([]dataStore.Data,
+                                           []dataStore.Data, error) {
+    before := []dataStore.Data{}
+    after := []dataStore.Data{}
+    for uid, rec := range treeData.changed {
+        if rec != nil {
+            before = append(before, *rec)
+        }
+        stored, err := treeData.getStoredRecord(uid)
+        if err != nil {
+            return nil, nil, err
+        }
+        if stored != nil {
+            after = append(after, *stored)
+        }
+    }
+    sortRecords(before)
+    sortRecords(after)
+    return before, after, nil
+}
+
+//Update the versions of the records changed from the records 'before' to the
+// records 'after', the version of the tree is incremented for the change.
+func (treeData *boltTreeData)updateVersions(before []dataStore.Data,
+                                            after []dataStore.Data) error {
+    treeData.tree.Version++
+    versions, parents := dataStore.GetRecordVersions(before, after,
+                                                     treeData.tree.Version)
+    for _, recVersion := range versions {
+        rec, err := treeData.getRecord(recVersion.Uid)
+        if err != nil {
+            return err
+        }
+        rec.Version = recVersion.Version
+        rec.ChildVersion = recVersion.ChildVersion
+        err = treeData.putRecord(rec)
+        if err != nil {
+            return err
+        }
+    }
+    //Parents that are deleted along with the records are not updated. The
+    // parents of a record with the tree version are updated already.
+    for _, puid := range parents {
+        for len(puid) != 0 {
+            rec, err := treeData.getStoredRecord(puid)
+            if err != nil {
+                return err
+            }
+            if rec == nil || rec.ChildVersion == treeData.tree.Version {
+                break
+            }
+            rec.ChildVersion = treeData.tree.Version
+            err = treeData.putRecord(rec)
+            if err != nil {
+                return err
+            }
+            puid = rec.Puid
+        }
+    }
+    return nil
+}
+
+//Shift the nested set limits of all the records at or after the position
+// 'pos' by 'updateVal', the records after it are read from the LftId index
+// and the parents whose right limit is after it from the parent links.
+func (treeData *boltTreeData)shiftNSLimits(pos int64, updateVal int64) error {
+    rows, err := treeData.getRange(pos, math.MaxInt64)
+    if err != nil {
+        return err
+    }
+    parents, err := treeData.getOpenParents(pos - 1)
+    if err != nil {
+        return err
+    }
+    for i := range parents {
+        parents[i].RgtId += updateVal
+        err = treeData.saveRecord(&parents[i])
+        if err != nil {
+            return err
+        }
+    }
+    for i := range rows {
+        rows[i].LftId += updateVal
+        rows[i].RgtId += updateVal
+        err = treeData.saveRecord(&rows[i])
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+//Get the record and all its childrens in pre-order.
+func (treeData *boltTreeData)getSubtree(
+                            rec *dataStore.Data) ([]dataStore.Data, error) {
+    if rec.RgtId <= rec.LftId {
+        //Limits are corrupted, consider only the record.
+        return []dataStore.Data{*rec}, nil
+    }
+    return treeData.getRange(rec.LftId, rec.RgtId)
+}
+
+//Get the record with the name under the parent, nil when its not present.
+func (treeData *boltTreeData)getRecordWithNameAndPID(name string,
+                                   puid string) (*dataStore.Data, error) {
+    if len(name) == 0 {
+        logger.GetLoggerInstance().Error("Failed to get the record, " +
+                                         "name/puid is null")
+        return nil, appErrors.INVALID_INPUT
+    }
+    parent, err := treeData.getStoredRecord(puid)
+    if err != nil || parent == nil {
+        return nil, err
+    }
+    childs, err := treeData.getChildren(parent)
+    if err != nil {
+        return nil, err
+    }
+    for i := range childs {
+        if childs[i].Name == name {
+            return &childs[i], nil
+        }
+    }
+    return nil, nil
+}
+
+//Check the version of the record from the user before a change of the
+// record, or of its subtree when 'subtree' is set.
+func (treeData *boltTreeData)checkVersion(uid string, version int64,
+                                          subtree bool) error {
+    if version == dataStore.ANY_VERSION {
+        return nil
+    }
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        return err
+    }
+    rec.UpdateSubtreeVersion()
+    return rec.CheckVersion(version, treeData.tree.Version, subtree)
+}
+
+//Insert the root record of the tree.
+func (treeData *boltTreeData)insertRoot() error {
+    root := new(dataStore.Data)
+    root.Uid = treeData.tree.RootUid
+    root.TreeId = treeData.tree.TreeId
+    root.Name = "root"
+    root.Desc = "The default root node in the hierarchy"
+    root.LftId = 1
+    root.RgtId = root.LftId + 1
+    return treeData.saveRecord(root)
+}
+
+//Get the sibling record of 'before'/'after' insert position. The new record is
+// always created under the parent of its sibling.
+func (treeData *boltTreeData)getInsertSibling(
+                            rec *dataStore.Data) (*dataStore.Data, error) {
+    log := logger.GetLoggerInstance()
+    if len(rec.SiblingId) == 0 {
+        log.Error("Cannot insert %s record %s, sibling is not provided",
+                   rec.Position, rec.Name)
+        return nil, appErrors.INVALID_INPUT
+    }
+    sibling, err := treeData.getRecord(rec.SiblingId)
+    if err != nil {
+        log.Error("Failed to get the sibling record %s err : %s",
+                   rec.SiblingId, err)
+        return nil, err
+    }
+    if len(sibling.Puid) == 0 {
+        log.Error("Cannot insert a record next to the root node")
+        return nil, appErrors.INVALID_INPUT
+    }
+    if len(rec.Puid) != 0 && rec.Puid != sibling.Puid {
+        log.Error("Sibling %s is not a child of parent %s",
+                   sibling.Uid, rec.Puid)
+        return nil, appErrors.INVALID_INPUT
+    }
+    return sibling, nil
+}
+
+//Insert the record at its position, record is updated with its id and nested
+// set limits.
+func (treeData *boltTreeData)insertRecord(rec *dataStore.Data) error {
+    var err error
+    var sibling *dataStore.Data
+    log := logger.GetLoggerInstance()
+    switch rec.Position {
+    case "", dataStore.POSITION_FIRST_CHILD, dataStore.POSITION_LAST_CHILD:
+    case dataStore.POSITION_BEFORE, dataStore.POSITION_AFTER:
+        sibling, err = treeData.getInsertSibling(rec)
+        if err != nil {
+            return err
+        }
+        rec.Puid = sibling.Puid
+    default:
+        log.Error("Invalid position %s to insert the record %s",
+                   rec.Position, rec.Name)
+        return appErrors.INVALID_INPUT
+    }
+    if len(rec.Puid) == 0 {
+        //Wanted to insert the record at top level, use root as parent.
+        rec.Puid = treeData.tree.RootUid
+    }
+    parent, err := treeData.getRecord(rec.Puid)
+    if err != nil {
+        log.Error("Failed to get the parent record err : %s", err)
+        return err
+    }
+    err = dataStore.CheckRecordType(rec, parent.Type, nil)
+    if err != nil {
+        return err
+    }
+    present, err := treeData.getRecordWithNameAndPID(rec.Name, rec.Puid)
+    if err != nil {
+        return err
+    }
+    if present != nil {
+        log.Info("Cannot insert data, Record already present in the system")
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    rec.Uid, err = sys.NewUUIDString()
+    if err != nil {
+        log.Error("Failed to create UUID, cannot insert a an entry")
+        return err
+    }
+    var pos int64
+    switch rec.Position {
+    case dataStore.POSITION_FIRST_CHILD:
+        pos = parent.LftId + 1
+    case dataStore.POSITION_BEFORE:
+        pos = sibling.LftId
+    case dataStore.POSITION_AFTER:
+        pos = sibling.RgtId + 1
+    default:
+        //Insert as last child by default.
+        pos = parent.RgtId
+    }
+    rec.TreeId = treeData.tree.TreeId
+    rec.LftId = pos
+    rec.RgtId = rec.LftId + 1
+    return treeData.insertSubtree([]dataStore.Data{{Uid: rec.Uid,
+                                   TreeId: rec.TreeId, Puid: rec.Puid,
+                                   Name: rec.Name, Desc: rec.Desc,
+                                   Type: rec.Type,
+                                   Attributes: rec.Attributes.Copy(),
+                                   LftId: rec.LftId, RgtId: rec.RgtId}})
+}
+
+//Insert the records of a subtree in pre-order at the left limit of the first
+// record, the records after it are shifted to make room for the subtree.
+func (treeData *boltTreeData)insertSubtree(recs []dataStore.Data) error {
+    if len(recs) == 0 {
+        return nil
+    }
+    err := treeData.shiftNSLimits(recs[0].LftId, 2 * int64(len(recs)))
+    if err != nil {
+        return err
+    }
+    for i := range recs {
+        err = treeData.saveRecord(&recs[i])
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+//Remove the records of the subtree of 'rec', and close the gap left by them.
+func (treeData *boltTreeData)removeSubtree(rec *dataStore.Data,
+                                           recs []dataStore.Data) error {
+    for i := range recs {
+        err := treeData.removeRecord(&recs[i])
+        if err != nil {
+            return err
+        }
+    }
+    return treeData.shiftNSLimits(rec.RgtId + 1,
+                                  -(rec.RgtId - rec.LftId + 1))
+}
+
+//Delete the record along with all its childrens.
+func (treeData *boltTreeData)deleteRecord(uid string) error {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete", uid)
+        return err
+    }
+    if len(rec.Puid) == 0 {
+        //System doesnt allow to delete root node, as its owned by application.
+        log.Error("Cannot delete root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    recs, err := treeData.getSubtree(rec)
+    if err != nil {
+        return err
+    }
+    return treeData.removeSubtree(rec, recs)
+}
+
+//Delete the record only when it has no childrens.
+func (treeData *boltTreeData)deleteLeafRecord(uid string) error {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete", uid)
+        return err
+    }
+    if rec.RgtId - rec.LftId > 1 {
+        log.Error("Cannot delete record %s, it has childrens", uid)
+        return appErrors.INVALID_OP
+    }
+    return treeData.deleteRecord(uid)
+}
+
+//Delete the record and move its childrens to its parent, in the place of the
+// record. Names of the childrens must be unique under the parent as well.
+func (treeData *boltTreeData)promoteRecord(uid string) error {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete", uid)
+        return err
+    }
+    if len(rec.Puid) == 0 {
+        log.Error("Cannot delete root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    //Childrens must be allowed under the type of the parent as well.
+    parent, err := treeData.getRecord(rec.Puid)
+    if err != nil {
+        return err
+    }
+    childs, err := treeData.getRange(rec.LftId + 1, rec.RgtId)
+    if err != nil {
+        return err
+    }
+    for i := range childs {
+        child := &childs[i]
+        if child.Puid != uid {
+            continue
+        }
+        err = dataStore.CheckChildType(parent.Type, child.Type)
+        if err != nil {
+            return err
+        }
+        present, err := treeData.getRecordWithNameAndPID(child.Name, rec.Puid)
+        if err != nil {
+            return err
+        }
+        if present != nil && present.Uid != uid {
+            log.Info("Cannot delete %s, Record %s already present under %s",
+                      uid, child.Name, rec.Puid)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    err = treeData.removeRecord(rec)
+    if err != nil {
+        return err
+    }
+    for i := range childs {
+        child := &childs[i]
+        if child.Puid == uid {
+            child.Puid = rec.Puid
+        }
+        child.LftId--
+        child.RgtId--
+        err = treeData.saveRecord(child)
+        if err != nil {
+            return err
+        }
+    }
+    return treeData.shiftNSLimits(rec.RgtId + 1, -2)
+}
+
+//Remove the record along with all its childrens from the tree, and return
+// them as a trash entry.
+func (treeData *boltTreeData)trashRecord(uid string,
+                        deletedAt time.Time) (*dataStore.TrashEntry, error) {
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to get the record %s on " +
+                                         "delete, Cannot delete", uid)
+        return nil, err
+    }
+    if len(rec.Puid) == 0 {
+        logger.GetLoggerInstance().Error("Cannot delete root node, as its " +
+                                         "not owned by user")
+        return nil, appErrors.INVALID_INPUT
+    }
+    recs, err := treeData.getSubtree(rec)
+    if err != nil {
+        return nil, err
+    }
+    entry := dataStore.NewTrashEntry(recs, deletedAt)
+    err = treeData.removeSubtree(rec, recs)
+    if err != nil {
+        return nil, err
+    }
+    return entry, nil
+}
+
+//Restore the subtree of the trash entry under 'puid' after the first of the
+// 'siblings' present under it, or as the first child when none is present.
+// Its the last child when 'siblings' is nil.
+func (treeData *boltTreeData)restoreSubtree(entry *dataStore.TrashEntry,
+                                        puid string, siblings []string) error {
+    log := logger.GetLoggerInstance()
+    parent, err := treeData.getRecord(puid)
+    if err != nil {
+        log.Error("Failed to get the parent %s to restore %s err : %s",
+                   puid, entry.Uid, err)
+        return err
+    }
+    err = dataStore.CheckChildType(parent.Type, entry.Records[0].Type)
+    if err != nil {
+        return err
+    }
+    present, err := treeData.getRecordWithNameAndPID(entry.Name, puid)
+    if err != nil {
+        return err
+    }
+    if present != nil {
+        log.Info("Cannot restore %s, Record %s already present under %s",
+                  entry.Uid, entry.Name, puid)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    for _, rec := range entry.Records {
+        if treeData.records.Get([]byte(rec.Uid)) != nil {
+            log.Error("Cannot restore %s, record %s is already present",
+                       entry.Uid, rec.Uid)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    pos := parent.RgtId
+    if siblings != nil {
+        pos = parent.LftId + 1
+    }
+    for _, uid := range siblings {
+        sibling, err := treeData.getStoredRecord(uid)
+        if err != nil {
+            return err
+        }
+        if sibling != nil && sibling.Puid == puid {
+            pos = sibling.RgtId + 1
+            break
+        }
+    }
+    return treeData.insertSubtree(entry.GetRestoreRecords(
+                                        treeData.tree.TreeId, puid, pos))
+}
+
+//Move the records dropped by restoring the snapshot records 'recs' on the
+// records 'current' to the trash, returns the trash entries.
+func (treeData *boltTreeData)trashDropped(current []dataStore.Data,
+                                          recs []dataStore.Data,
+                                          deletedAt time.Time) (
+                                          []dataStore.TrashEntry, error) {
+    dropped, err := dataStore.GetSnapshotDropped(current, recs)
+    if err != nil {
+        return nil, err
+    }
+    entries := []dataStore.TrashEntry{}
+    for _, rec := range dropped {
+        entry, err := treeData.trashRecord(rec.Uid, deletedAt)
+        if err != nil {
+            return nil, err
+        }
+        entries = append(entries, *entry)
+    }
+    return entries, nil
+}
+
+//Restore the entire tree from the records of a snapshot, its restored on the
+// tree loaded in memory as all the records are replaced. The records not in
+// the snapshot are moved to the trash, returns their trash entries.
+func (treeData *boltTreeData)restoreSnapshotTree(recs []dataStore.Data,
+                        deletedAt time.Time) ([]dataStore.TrashEntry, error) {
+    memTree, err := treeData.loadTree()
+    if err != nil {
+        return nil, err
+    }
+    oldRows := memTree.GetAllRecords()
+    entries, err := memTree.RestoreSnapshot(recs, "", deletedAt)
+    if err != nil {
+        return nil, err
+    }
+    err = treeData.saveTree(memTree, oldRows)
+    if err != nil {
+        return nil, err
+    }
+    return entries, nil
+}
+
+//Restore the subtree of 'uid' from the records of a snapshot at its position
+// among the siblings in the snapshot, the record and its childrens in the
+// tree are replaced by the subtree. The records not in the snapshot are moved
+// to the trash, returns their trash entries.
+func (treeData *boltTreeData)restoreSnapshotSubtree(recs []dataStore.Data,
+                        uid string,
+                        deletedAt time.Time) ([]dataStore.TrashEntry, error) {
+    subtree, err := dataStore.GetSnapshotSubtree(recs, uid)
+    if err != nil {
+        return nil, err
+    }
+    entries := []dataStore.TrashEntry{}
+    rec, err := treeData.getStoredRecord(uid)
+    if err != nil {
+        return nil, err
+    }
+    if rec != nil {
+        current, err := treeData.getSubtree(rec)
+        if err != nil {
+            return nil, err
+        }
+        entries, err = treeData.trashDropped(current, subtree, deletedAt)
+        if err == nil {
+            err = treeData.deleteRecord(uid)
+        }
+        if err != nil {
+            return nil, err
+        }
+    }
+    entry := dataStore.NewTrashEntry(subtree, deletedAt)
+    err = treeData.restoreSubtree(entry, entry.Puid,
+                    dataStore.GetSnapshotPrevSiblings(recs, &subtree[0]))
+    if err != nil {
+        return nil, err
+    }
+    return entries, nil
+}
+
+//Copy the record and all its childrens as the last child of 'destPuid', the
+// copies get new ids. The copy is renamed when the name is already present
+// under the parent. Returns the id of the copied record.
+func (treeData *boltTreeData)copyRecord(uid string,
+                                        destPuid string) (string, error) {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on copy, Cannot copy", uid)
+        return "", err
+    }
+    if len(destPuid) == 0 {
+        //Copying the record to top level, use root as parent.
+        destPuid = treeData.tree.RootUid
+    }
+    parent, err := treeData.getRecord(destPuid)
+    if err != nil {
+        log.Error("Failed to get the parent %s on copy err : %s", destPuid,
+                   err)
+        return "", err
+    }
+    err = dataStore.CheckChildType(parent.Type, rec.Type)
+    if err != nil {
+        return "", err
+    }
+    name, err := dataStore.FindCopyName(rec.Name,
+                                        func(name string) (bool, error) {
+        present, err := treeData.getRecordWithNameAndPID(name, destPuid)
+        return present != nil, err
+    })
+    if err != nil {
+        return "", err
+    }
+    subtree, err := treeData.getSubtree(rec)
+    if err != nil {
+        return "", err
+    }
+    recs, err := dataStore.CopySubtreeRecords(subtree, treeData.tree.TreeId,
+                                              destPuid, name, parent.RgtId)
+    if err != nil {
+        return "", err
+    }
+    err = treeData.insertSubtree(recs)
+    if err != nil {
+        return "", err
+    }
+    return recs[0].Uid, nil
+}
+
+//Move the record along with all its childrens under a new parent. The record
+// is added as the last child of the new parent.
+func (treeData *boltTreeData)moveRecord(uid string, newPuid string) error {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on move, Cannot move", uid)
+        return err
+    }
+    if len(rec.Puid) == 0 {
+        log.Error("Cannot move root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    if len(newPuid) == 0 {
+        //Moving the record to top level, use root as parent.
+        newPuid = treeData.tree.RootUid
+    }
+    if newPuid == rec.Puid {
+        log.Trace("Record %s is already under %s, nothing to move",
+                   rec.Uid, newPuid)
+        return nil
+    }
+    parent, err := treeData.getRecord(newPuid)
+    if err != nil {
+        log.Error("Failed to get the new parent %s on move err : %s",
+                   newPuid, err)
+        return err
+    }
+    err = dataStore.CheckChildType(parent.Type, rec.Type)
+    if err != nil {
+        return err
+    }
+    if parent.LftId >= rec.LftId && parent.LftId <= rec.RgtId {
+        log.Error("Cannot move record %s into its own subtree %s",
+                   rec.Uid, newPuid)
+        return appErrors.INVALID_OP
+    }
+    //Name must be unique under the new parent as well.
+    present, err := treeData.getRecordWithNameAndPID(rec.Name, newPuid)
+    if err != nil {
+        return err
+    }
+    if present != nil {
+        log.Info("Cannot move data, Record already present under %s",
+                  newPuid)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    //The subtree is shifted to the right end of the new parent, and the
+    // records between old and new position fill the gap. Only the limits in
+    // the range of both are changed.
+    var subtreeShift, gapLftId, gapRgtId, gapShift int64
+    lftId := rec.LftId
+    rgtId := rec.RgtId
+    width := rgtId - lftId + 1
+    pos := parent.RgtId
+    if pos > rgtId {
+        subtreeShift = pos - rgtId - 1
+        gapLftId = rgtId + 1
+        gapRgtId = pos - 1
+        gapShift = -width
+    } else {
+        subtreeShift = pos - lftId
+        gapLftId = pos
+        gapRgtId = lftId - 1
+        gapShift = width
+    }
+    shiftLimit := func(limit int64) int64 {
+        if limit >= lftId && limit <= rgtId {
+            return limit + subtreeShift
+        }
+        if limit >= gapLftId && limit <= gapRgtId {
+            return limit + gapShift
+        }
+        return limit
+    }
+    fromLftId := lftId
+    toLftId := rgtId
+    if gapLftId < fromLftId {
+        fromLftId = gapLftId
+    }
+    if gapRgtId > toLftId {
+        toLftId = gapRgtId
+    }
+    rows, err := treeData.getRange(fromLftId, toLftId + 1)
+    if err != nil {
+        return err
+    }
+    parents, err := treeData.getOpenParents(fromLftId - 1)
+    if err != nil {
+        return err
+    }
+    for _, row := range append(parents, rows...) {
+        row.LftId = shiftLimit(row.LftId)
+        row.RgtId = shiftLimit(row.RgtId)
+        if row.Uid == uid {
+            row.Puid = newPuid
+        }
+        err = treeData.saveRecord(&row)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+//Update the name, description, type and attributes of the record. Name must be
+// unique under the parent of the record.
+func (treeData *boltTreeData)updateRecord(rec *dataStore.Data) error {
+    log := logger.GetLoggerInstance()
+    if len(rec.Name) == 0 {
+        log.Error("Cannot update the record %s with empty name", rec.Uid)
+        return appErrors.INVALID_INPUT
+    }
+    recData, err := treeData.getRecord(rec.Uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on update, Cannot update",
+                   rec.Uid)
+        return err
+    }
+    if len(recData.Puid) == 0 {
+        log.Error("Cannot update root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    //Only name, description, type and attributes can be updated, keep rest
+    // of the fields.
+    rec.Puid = recData.Puid
+    rec.LftId = recData.LftId
+    rec.RgtId = recData.RgtId
+    present, err := treeData.getRecordWithNameAndPID(rec.Name, rec.Puid)
+    if err != nil {
+        return err
+    }
+    if present != nil && present.Uid != rec.Uid {
+        log.Info("Cannot update data, Record %s already present under %s",
+                  rec.Name, rec.Puid)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    parent, err := treeData.getRecord(rec.Puid)
+    if err != nil {
+        return err
+    }
+    childs, err := treeData.getChildren(recData)
+    if err != nil {
+        return err
+    }
+    childTypes := []string{}
+    for _, child := range childs {
+        childTypes = append(childTypes, child.Type)
+    }
+    err = dataStore.CheckRecordType(rec, parent.Type, childTypes)
+    if err != nil {
+        return err
+    }
+    recData.Name = rec.Name
+    recData.Desc = rec.Desc
+    recData.Type = rec.Type
+    recData.Attributes = rec.Attributes.Copy()
+    return treeData.saveRecord(recData)
+}
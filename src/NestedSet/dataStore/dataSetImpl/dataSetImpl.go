@@ -15,11 +15,55 @@
 package dataSetImpl
 
 import (
+    "sync"
     "NestedSet/dataStore"
+    "NestedSet/logger"
     "NestedSet/dataStore/dataSetImpl/sqlite"
+    "NestedSet/dataStore/dataSetImpl/postgres"
+    "NestedSet/dataStore/dataSetImpl/mysql"
+    "NestedSet/dataStore/dataSetImpl/memory"
+    "NestedSet/dataStore/dataSetImpl/bolt"
 )
 
+//Built-in backends of the application, they are registered in dataStore on
+// import. Other backends register themselves the same way.
+const (
+    BACKEND_SQLITE = sqlite.BACKEND_NAME
+    BACKEND_POSTGRES = postgres.BACKEND_NAME
+    BACKEND_MYSQL = mysql.BACKEND_NAME
+    BACKEND_MEMORY = memory.BACKEND_NAME
+    BACKEND_BOLT = bolt.BACKEND_NAME
+)
+
+var dataSetOnce sync.Once
+var dataSetObj dataStore.DataSetInterface
+
+//Create the datastore of the application from a registered backend, it must
+// be set before the datastore is used.
+func SetBackend(name string, options dataStore.BackendOptions) error {
+    obj, err := dataStore.NewBackend(name, options)
+    if err != nil {
+        return err
+    }
+    dataSetObj = obj
+    return nil
+}
+
+//Only one datastore object is present in the application, its sqlite when no
+// backend is set. Its nil when the sqlite backend cannot be created.
 func GetDataSetObj() dataStore.DataSetInterface {
-    // Default backend is sqlite.
-    return sqlite.GetsqliteDataStoreObj()
-}
\ No newline at end of file
+    dataSetOnce.Do(func() {
+        if dataSetObj != nil {
+            return
+        }
+        obj, err := dataStore.NewBackend(BACKEND_SQLITE, nil)
+        if err != nil {
+            logger.GetLoggerInstance().Error("Failed to create the default " +
+                                             "%s backend err : %s",
+                                             BACKEND_SQLITE, err)
+            return
+        }
+        dataSetObj = obj
+    })
+    return dataSetObj
+}
@@ -0,0 +1,897 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package memory
+
+import (
+    "sort"
+    "time"
+    "NestedSet/appErrors"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+    "NestedSet/sys"
+)
+
+//Nested set of a tree held in memory. Records are kept in pre-order(sorted on
+// LftId), so the subtree of a record is a continuous range in the list.
+// TreeData is not thread safe, users must serialize the access to it.
+type TreeData struct {
+    dataStore.Tree
+    recs []*dataStore.Data
+    uids map[string]*dataStore.Data
+}
+
+//Create a tree from its records, records can be in any order.
+func NewTreeData(tree *dataStore.Tree, recs []dataStore.Data) *TreeData {
+    treeData := new(TreeData)
+    treeData.Tree = *tree
+    treeData.recs = make([]*dataStore.Data, 0, len(recs))
+    treeData.uids = make(map[string]*dataStore.Data, len(recs))
+    for i := range recs {
+        rec := recs[i]
+        treeData.recs = append(treeData.recs, &rec)
+        treeData.uids[rec.Uid] = &rec
+    }
+    treeData.sortRecords()
+    return treeData
+}
+
+func (treeData *TreeData)sortRecords() {
+    sort.SliceStable(treeData.recs, func(i, j int) bool {
+        return treeData.recs[i].LftId < treeData.recs[j].LftId
+    })
+}
+
+//Index of the first record in the list at or after the limit 'lftId'.
+func (treeData *TreeData)recordIndex(lftId int64) int {
+    return sort.Search(len(treeData.recs), func(i int) bool {
+        return treeData.recs[i].LftId >= lftId
+    })
+}
+
+//Copy a record of the tree along with its attributes.
+func copyRecord(rec *dataStore.Data) dataStore.Data {
+    row := *rec
+    row.Attributes = rec.Attributes.Copy()
+    return row
+}
+
+//Copy the records from the list, users never get the records of the tree.
+func copyRecords(recs []*dataStore.Data) []dataStore.Data {
+    rows := make([]dataStore.Data, 0, len(recs))
+    for _, rec := range recs {
+        rows = append(rows, copyRecord(rec))
+    }
+    return rows
+}
+
+//Insert the root record of the tree if its not present.
+func (treeData *TreeData)InsertRoot() {
+    if _, ok := treeData.uids[treeData.RootUid]; ok {
+        logger.GetLoggerInstance().Trace("Root record already present in " +
+                                         "the tree %s", treeData.TreeId)
+        return
+    }
+    root := new(dataStore.Data)
+    root.Uid = treeData.RootUid
+    root.TreeId = treeData.TreeId
+    root.Name = "root"
+    root.Desc = "The default root node in the hierarchy"
+    root.LftId = 1
+    root.RgtId = root.LftId + 1
+    treeData.recs = append([]*dataStore.Data{root}, treeData.recs...)
+    treeData.uids[root.Uid] = root
+}
+
+func (treeData *TreeData)getRecord(uid string) (*dataStore.Data, error) {
+    log := logger.GetLoggerInstance()
+    if len(uid) == 0 {
+        log.Error("Cannot retrieve a record with empty Uid")
+        return nil, appErrors.INVALID_INPUT
+    }
+    rec, ok := treeData.uids[uid]
+    if !ok {
+        log.Error("0 records only present in the system")
+        return nil, appErrors.DATA_NOT_UNIQUE_ERROR
+    }
+    return rec, nil
+}
+
+//Get the parents of the record upto root, ordered from the record. Walk is
+// limited to the size of the tree, in case of a loop in the parent links.
+func (treeData *TreeData)getParents(rec *dataStore.Data) []*dataStore.Data {
+    parents := []*dataStore.Data{}
+    for parent, ok := treeData.uids[rec.Puid];
+        ok && len(parents) < len(treeData.recs);
+        parent, ok = treeData.uids[parent.Puid] {
+        parents = append(parents, parent)
+    }
+    return parents
+}
+
+//Depth of a record is the number of its parents upto root.
+func (treeData *TreeData)getDepth(rec *dataStore.Data) int64 {
+    return int64(len(treeData.getParents(rec)))
+}
+
+//Range of the record and all its childrens in the list.
+func (treeData *TreeData)getSubtreeRange(rec *dataStore.Data) (int, int) {
+    start := treeData.recordIndex(rec.LftId)
+    end := treeData.recordIndex(rec.RgtId)
+    if end <= start {
+        //Limits are corrupted, consider only the record.
+        end = start + 1
+    }
+    return start, end
+}
+
+//Get the record and all its childrens in pre-order.
+func (treeData *TreeData)getSubtree(rec *dataStore.Data) []*dataStore.Data {
+    start, end := treeData.getSubtreeRange(rec)
+    return treeData.recs[start:end]
+}
+
+//Get the record with the name under the parent.
+func (treeData *TreeData)getRecordWithNameAndPID(name string,
+                                   puid string) (*dataStore.Data, error) {
+    if len(name) == 0 {
+        logger.GetLoggerInstance().Error("Failed to get the record, " +
+                                         "name/puid is null")
+        return nil, appErrors.INVALID_INPUT
+    }
+    for _, rec := range treeData.recs {
+        if rec.Name == name && rec.Puid == puid {
+            return rec, nil
+        }
+    }
+    return nil, nil
+}
+
+//Shift the nested set limits of all the records at or after the position
+// 'pos' by 'updateVal'.
+func (treeData *TreeData)shiftNSLimits(pos int64, updateVal int64) {
+    for _, rec := range treeData.recs {
+        if rec.LftId >= pos {
+            rec.LftId += updateVal
+        }
+        if rec.RgtId >= pos {
+            rec.RgtId += updateVal
+        }
+    }
+}
+
+//Retrieve a record with record UID along with its computed fields.
+func (treeData *TreeData)GetRecord(uid string) (*dataStore.Data, error) {
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        return nil, err
+    }
+    row := copyRecord(rec)
+    row.Depth = treeData.getDepth(rec)
+    row.UpdateSubtreeInfo()
+    row.UpdateSubtreeVersion()
+    return &row, nil
+}
+
+//Check the version of the record from the user before a change of the
+// record, or of its subtree when 'subtree' is set.
+func (treeData *TreeData)CheckVersion(uid string, version int64,
+                                      subtree bool) error {
+    if version == dataStore.ANY_VERSION {
+        return nil
+    }
+    row, err := treeData.GetRecord(uid)
+    if err != nil {
+        return err
+    }
+    return row.CheckVersion(version, treeData.Version, subtree)
+}
+
+//Update the versions of the records changed from the records 'before' to
+// the records 'after', the version of the tree is incremented for the change.
+func (treeData *TreeData)UpdateVersions(before []dataStore.Data,
+                                        after []dataStore.Data) {
+    treeData.Version++
+    versions, _ := dataStore.GetRecordVersions(before, after,
+                                               treeData.Version)
+    for _, recVersion := range versions {
+        rec := treeData.uids[recVersion.Uid]
+        rec.Version = recVersion.Version
+        rec.ChildVersion = recVersion.ChildVersion
+    }
+}
+
+//Get the path from root to the record, ordered from the root.
+func (treeData *TreeData)GetAncestors(uid string) ([]dataStore.Data, error) {
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        return nil, err
+    }
+    recs := []*dataStore.Data{rec}
+    for _, parent := range treeData.getParents(rec) {
+        recs = append([]*dataStore.Data{parent}, recs...)
+    }
+    rows := copyRecords(recs)
+    dataStore.UpdateTreeInfo(rows, 0)
+    return rows, nil
+}
+
+//Get all the childrens of the record in pre-order, upto 'maxDepth' levels
+// below the record.
+func (treeData *TreeData)GetDescendants(uid string,
+                                        maxDepth int) ([]dataStore.Data,
+                                                       error) {
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        return nil, err
+    }
+    depth := treeData.getDepth(rec)
+    rows := copyRecords(treeData.getSubtree(rec)[1:])
+    dataStore.UpdateTreeInfo(rows, depth + 1)
+    if maxDepth > 0 {
+        rows = dataStore.FilterByDepth(rows, depth + int64(maxDepth))
+    }
+    return rows, nil
+}
+
+//Get the direct childrens of the record in order.
+func (treeData *TreeData)GetChildren(uid string) ([]dataStore.Data, error) {
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        return nil, err
+    }
+    recs := []*dataStore.Data{}
+    for _, child := range treeData.getSubtree(rec)[1:] {
+        if child.Puid == rec.Uid {
+            recs = append(recs, child)
+        }
+    }
+    rows := copyRecords(recs)
+    dataStore.UpdateTreeInfo(rows, treeData.getDepth(rec) + 1)
+    return rows, nil
+}
+
+func (treeData *TreeData)GetRecordByName(name string) ([]dataStore.Data,
+                                                       error) {
+    if len(name) == 0 {
+        logger.GetLoggerInstance().Error("Failed to get the record, " +
+                                         "name/puid is null")
+        return nil, appErrors.INVALID_INPUT
+    }
+    rows := []dataStore.Data{}
+    for _, rec := range treeData.recs {
+        if rec.Name == name {
+            row := copyRecord(rec)
+            row.Depth = treeData.getDepth(rec)
+            row.UpdateSubtreeInfo()
+            rows = append(rows, row)
+        }
+    }
+    return rows, nil
+}
+
+func (treeData *TreeData)GetAllRecords() []dataStore.Data {
+    rows := copyRecords(treeData.recs)
+    dataStore.UpdateTreeInfo(rows, 0)
+    return rows
+}
+
+//Get the records before the position 'start' in the list whose subtree is not
+// ended at 'lftId', ordered from the root.
+func (treeData *TreeData)getOpenParents(start int,
+                                        lftId int64) []dataStore.Data {
+    parents := []dataStore.Data{}
+    if start == 0 {
+        return parents
+    }
+    prev := treeData.recs[start - 1]
+    for _, rec := range append([]*dataStore.Data{prev},
+                               treeData.getParents(prev)...) {
+        if rec.RgtId > lftId {
+            parents = append([]dataStore.Data{*rec}, parents...)
+        }
+    }
+    return parents
+}
+
+func (treeData *TreeData)GetRecordsPage(
+                        request *dataStore.PageRequest) *dataStore.RecordPage {
+    from := request.ReadFrom()
+    start := treeData.recordIndex(from + 1)
+    builder := dataStore.NewPageBuilder(request,
+                                        treeData.getOpenParents(start, from))
+    for _, rec := range treeData.recs[start:] {
+        row := *rec
+        if !builder.AddRecord(&row) {
+            break
+        }
+    }
+    page := builder.GetPage(int64(len(treeData.recs)))
+    for i := range page.Records {
+        page.Records[i].Attributes = page.Records[i].Attributes.Copy()
+    }
+    return page
+}
+
+func (treeData *TreeData)GetRecordByNamePage(name string,
+                        request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                         error) {
+    if len(name) == 0 {
+        logger.GetLoggerInstance().Error("Failed to get the records, " +
+                                         "name is null")
+        return nil, appErrors.INVALID_INPUT
+    }
+    nameRequest := *request
+    nameRequest.Filter.Name = &name
+    return treeData.GetRecordsPage(&nameRequest), nil
+}
+
+//Get the sibling record of 'before'/'after' insert position. The new record is
+// always created under the parent of its sibling.
+func (treeData *TreeData)getInsertSibling(
+                            rec *dataStore.Data) (*dataStore.Data, error) {
+    log := logger.GetLoggerInstance()
+    if len(rec.SiblingId) == 0 {
+        log.Error("Cannot insert %s record %s, sibling is not provided",
+                   rec.Position, rec.Name)
+        return nil, appErrors.INVALID_INPUT
+    }
+    sibling, err := treeData.getRecord(rec.SiblingId)
+    if err != nil {
+        log.Error("Failed to get the sibling record %s err : %s",
+                   rec.SiblingId, err)
+        return nil, err
+    }
+    if len(sibling.Puid) == 0 {
+        log.Error("Cannot insert a record next to the root node")
+        return nil, appErrors.INVALID_INPUT
+    }
+    if len(rec.Puid) != 0 && rec.Puid != sibling.Puid {
+        log.Error("Sibling %s is not a child of parent %s",
+                   sibling.Uid, rec.Puid)
+        return nil, appErrors.INVALID_INPUT
+    }
+    return sibling, nil
+}
+
+//Insert the record at its position, record is updated with its id and nested
+// set limits.
+func (treeData *TreeData)InsertRecord(rec *dataStore.Data) error {
+    var err error
+    var sibling *dataStore.Data
+    log := logger.GetLoggerInstance()
+    switch rec.Position {
+    case "", dataStore.POSITION_FIRST_CHILD, dataStore.POSITION_LAST_CHILD:
+    case dataStore.POSITION_BEFORE, dataStore.POSITION_AFTER:
+        sibling, err = treeData.getInsertSibling(rec)
+        if err != nil {
+            return err
+        }
+        rec.Puid = sibling.Puid
+    default:
+        log.Error("Invalid position %s to insert the record %s",
+                   rec.Position, rec.Name)
+        return appErrors.INVALID_INPUT
+    }
+    if len(rec.Puid) == 0 {
+        //Wanted to insert the record at top level, use root as parent.
+        rec.Puid = treeData.RootUid
+    }
+    parent, err := treeData.getRecord(rec.Puid)
+    if err != nil {
+        log.Error("Failed to get the parent record err : %s", err)
+        return err
+    }
+    err = dataStore.CheckRecordType(rec, parent.Type, nil)
+    if err != nil {
+        return err
+    }
+    present, err := treeData.getRecordWithNameAndPID(rec.Name, rec.Puid)
+    if err != nil {
+        return err
+    }
+    if present != nil {
+        log.Info("Cannot insert data, Record already present in the system")
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    rec.Uid, err = sys.NewUUIDString()
+    if err != nil {
+        log.Error("Failed to create UUID, cannot insert a an entry")
+        return err
+    }
+    var pos int64
+    switch rec.Position {
+    case dataStore.POSITION_FIRST_CHILD:
+        pos = parent.LftId + 1
+    case dataStore.POSITION_BEFORE:
+        pos = sibling.LftId
+    case dataStore.POSITION_AFTER:
+        pos = sibling.RgtId + 1
+    default:
+        //Insert as last child by default.
+        pos = parent.RgtId
+    }
+    // Make room for the new node at its position by shifting every record
+    // at or after it, including right end of all its parents.
+    treeData.shiftNSLimits(pos, 2)
+    rec.TreeId = treeData.TreeId
+    rec.LftId = pos
+    rec.RgtId = rec.LftId + 1
+    newRec := &dataStore.Data{Uid: rec.Uid, TreeId: rec.TreeId,
+                              Puid: rec.Puid, Name: rec.Name, Desc: rec.Desc,
+                              Type: rec.Type,
+                              Attributes: rec.Attributes.Copy(),
+                              LftId: rec.LftId, RgtId: rec.RgtId}
+    index := treeData.recordIndex(pos)
+    treeData.recs = append(treeData.recs, nil)
+    copy(treeData.recs[index + 1:], treeData.recs[index:])
+    treeData.recs[index] = newRec
+    treeData.uids[newRec.Uid] = newRec
+    return nil
+}
+
+//Delete the record along with all its childrens.
+func (treeData *TreeData)DeleteRecord(uid string) error {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete", uid)
+        return err
+    }
+    if len(rec.Puid) == 0 {
+        //System doesnt allow to delete root node, as its owned by application.
+        log.Error("Cannot delete root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    start, end := treeData.getSubtreeRange(rec)
+    for _, child := range treeData.recs[start:end] {
+        delete(treeData.uids, child.Uid)
+    }
+    treeData.recs = append(treeData.recs[:start], treeData.recs[end:]...)
+    //Close the gap left by the record and its childrens.
+    treeData.shiftNSLimits(rec.RgtId + 1, -(rec.RgtId - rec.LftId + 1))
+    return nil
+}
+
+//Delete the record only when it has no childrens.
+func (treeData *TreeData)DeleteLeafRecord(uid string) error {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete", uid)
+        return err
+    }
+    if rec.RgtId - rec.LftId > 1 {
+        log.Error("Cannot delete record %s, it has childrens", uid)
+        return appErrors.INVALID_OP
+    }
+    return treeData.DeleteRecord(uid)
+}
+
+//Delete the record and move its childrens to its parent, in the place of the
+// record. Names of the childrens must be unique under the parent as well.
+func (treeData *TreeData)PromoteRecord(uid string) error {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete", uid)
+        return err
+    }
+    if len(rec.Puid) == 0 {
+        log.Error("Cannot delete root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    //Childrens must be allowed under the type of the parent as well.
+    parent, err := treeData.getRecord(rec.Puid)
+    if err != nil {
+        return err
+    }
+    start, end := treeData.getSubtreeRange(rec)
+    for _, child := range treeData.recs[start + 1:end] {
+        if child.Puid != uid {
+            continue
+        }
+        err = dataStore.CheckChildType(parent.Type, child.Type)
+        if err != nil {
+            return err
+        }
+        present, err := treeData.getRecordWithNameAndPID(child.Name, rec.Puid)
+        if err != nil {
+            return err
+        }
+        if present != nil && present != rec {
+            log.Info("Cannot delete %s, Record %s already present under %s",
+                      uid, child.Name, rec.Puid)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    for _, child := range treeData.recs[start + 1:end] {
+        if child.Puid == uid {
+            child.Puid = rec.Puid
+        }
+        child.LftId--
+        child.RgtId--
+    }
+    delete(treeData.uids, uid)
+    treeData.recs = append(treeData.recs[:start], treeData.recs[start + 1:]...)
+    treeData.shiftNSLimits(rec.RgtId + 1, -2)
+    return nil
+}
+
+//Remove the record along with all its childrens from the tree, and return
+// them as a trash entry.
+func (treeData *TreeData)TrashRecord(uid string,
+                        deletedAt time.Time) (*dataStore.TrashEntry, error) {
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to get the record %s on " +
+                                         "delete, Cannot delete", uid)
+        return nil, err
+    }
+    entry := dataStore.NewTrashEntry(copyRecords(treeData.getSubtree(rec)),
+                                     deletedAt)
+    err = treeData.DeleteRecord(uid)
+    if err != nil {
+        return nil, err
+    }
+    return entry, nil
+}
+
+//Restore the subtree of the trash entry as the last child of 'puid', or of
+// its original parent when 'puid' is empty.
+func (treeData *TreeData)RestoreRecord(entry *dataStore.TrashEntry,
+                                       puid string) error {
+    if len(puid) == 0 {
+        puid = entry.Puid
+    }
+    return treeData.restoreSubtree(entry, puid, nil)
+}
+
+//Restore the subtree of the trash entry under 'puid' after the first of the
+// 'siblings' present under it, or as the first child when none is present.
+// Its the last child when 'siblings' is nil.
+func (treeData *TreeData)restoreSubtree(entry *dataStore.TrashEntry,
+                                        puid string, siblings []string) error {
+    log := logger.GetLoggerInstance()
+    parent, err := treeData.getRecord(puid)
+    if err != nil {
+        log.Error("Failed to get the parent %s to restore %s err : %s",
+                   puid, entry.Uid, err)
+        return err
+    }
+    err = dataStore.CheckChildType(parent.Type, entry.Records[0].Type)
+    if err != nil {
+        return err
+    }
+    present, err := treeData.getRecordWithNameAndPID(entry.Name, puid)
+    if err != nil {
+        return err
+    }
+    if present != nil {
+        log.Info("Cannot restore %s, Record %s already present under %s",
+                  entry.Uid, entry.Name, puid)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    for _, rec := range entry.Records {
+        if _, ok := treeData.uids[rec.Uid]; ok {
+            log.Error("Cannot restore %s, record %s is already present",
+                       entry.Uid, rec.Uid)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    pos := parent.RgtId
+    if siblings != nil {
+        pos = parent.LftId + 1
+        for _, uid := range siblings {
+            sibling, ok := treeData.uids[uid]
+            if ok && sibling.Puid == puid {
+                pos = sibling.RgtId + 1
+                break
+            }
+        }
+    }
+    treeData.insertSubtree(entry.GetRestoreRecords(treeData.TreeId, puid,
+                                                   pos))
+    return nil
+}
+
+//Move the records dropped by restoring the snapshot records 'recs' on the
+// records 'current' to the trash, returns the trash entries.
+func (treeData *TreeData)trashDropped(current []dataStore.Data,
+                                      recs []dataStore.Data,
+                                      deletedAt time.Time) (
+                                      []dataStore.TrashEntry, error) {
+    dropped, err := dataStore.GetSnapshotDropped(current, recs)
+    if err != nil {
+        return nil, err
+    }
+    entries := []dataStore.TrashEntry{}
+    for _, rec := range dropped {
+        entry, err := treeData.TrashRecord(rec.Uid, deletedAt)
+        if err != nil {
+            return nil, err
+        }
+        entries = append(entries, *entry)
+    }
+    return entries, nil
+}
+
+//Restore the subtree of 'uid' from the records of a snapshot at its position
+// among the siblings in the snapshot, the record and its childrens in the
+// tree are replaced by the subtree. Entire tree is restored when 'uid' is
+// empty or the root of the tree. The records not in the snapshot are moved to
+// the trash, returns their trash entries.
+func (treeData *TreeData)RestoreSnapshot(recs []dataStore.Data, uid string,
+                        deletedAt time.Time) ([]dataStore.TrashEntry, error) {
+    //Snapshot is restored on a copy, the tree is not changed when it fails.
+    newTree := NewTreeData(&treeData.Tree, copyRecords(treeData.recs))
+    if len(uid) == 0 || uid == treeData.RootUid {
+        entries, err := newTree.trashDropped(copyRecords(newTree.recs), recs,
+                                             deletedAt)
+        if err != nil {
+            return nil, err
+        }
+        *treeData = *NewTreeData(&treeData.Tree,
+                                 dataStore.GetSnapshotRestoreRecords(recs))
+        return entries, nil
+    }
+    subtree, err := dataStore.GetSnapshotSubtree(recs, uid)
+    if err != nil {
+        return nil, err
+    }
+    entries := []dataStore.TrashEntry{}
+    if rec, ok := newTree.uids[uid]; ok {
+        entries, err = newTree.trashDropped(
+                                copyRecords(newTree.getSubtree(rec)), subtree,
+                                deletedAt)
+        if err == nil {
+            err = newTree.DeleteRecord(uid)
+        }
+        if err != nil {
+            return nil, err
+        }
+    }
+    entry := dataStore.NewTrashEntry(subtree, deletedAt)
+    err = newTree.restoreSubtree(entry, entry.Puid,
+                    dataStore.GetSnapshotPrevSiblings(recs, &subtree[0]))
+    if err != nil {
+        return nil, err
+    }
+    *treeData = *newTree
+    return entries, nil
+}
+
+//Insert the records of a subtree in pre-order at the left limit of the first
+// record, the records after it are shifted to make room for the subtree.
+func (treeData *TreeData)insertSubtree(recs []dataStore.Data) {
+    if len(recs) == 0 {
+        return
+    }
+    pos := recs[0].LftId
+    treeData.shiftNSLimits(pos, 2 * int64(len(recs)))
+    newRecs := []*dataStore.Data{}
+    for _, rec := range recs {
+        newRec := rec
+        newRec.Attributes = rec.Attributes.Copy()
+        newRecs = append(newRecs, &newRec)
+        treeData.uids[newRec.Uid] = &newRec
+    }
+    index := treeData.recordIndex(pos)
+    treeData.recs = append(treeData.recs[:index],
+                           append(newRecs, treeData.recs[index:]...)...)
+}
+
+//Copy the record and all its childrens as the last child of 'destPuid', the
+// copies get new ids. The copy is renamed when the name is already present
+// under the parent. Returns the id of the copied record.
+func (treeData *TreeData)CopyRecord(uid string,
+                                    destPuid string) (string, error) {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on copy, Cannot copy", uid)
+        return "", err
+    }
+    if len(destPuid) == 0 {
+        //Copying the record to top level, use root as parent.
+        destPuid = treeData.RootUid
+    }
+    parent, err := treeData.getRecord(destPuid)
+    if err != nil {
+        log.Error("Failed to get the parent %s on copy err : %s", destPuid,
+                   err)
+        return "", err
+    }
+    err = dataStore.CheckChildType(parent.Type, rec.Type)
+    if err != nil {
+        return "", err
+    }
+    name, err := dataStore.FindCopyName(rec.Name,
+                                        func(name string) (bool, error) {
+        present, err := treeData.getRecordWithNameAndPID(name, destPuid)
+        return present != nil, err
+    })
+    if err != nil {
+        return "", err
+    }
+    recs, err := dataStore.CopySubtreeRecords(
+                                copyRecords(treeData.getSubtree(rec)),
+                                treeData.TreeId, destPuid, name, parent.RgtId)
+    if err != nil {
+        return "", err
+    }
+    treeData.insertSubtree(recs)
+    return recs[0].Uid, nil
+}
+
+//Move the record along with all its childrens under a new parent. The record
+// is added as the last child of the new parent.
+func (treeData *TreeData)MoveRecord(uid string, newPuid string) error {
+    log := logger.GetLoggerInstance()
+    rec, err := treeData.getRecord(uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on move, Cannot move", uid)
+        return err
+    }
+    if len(rec.Puid) == 0 {
+        log.Error("Cannot move root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    if len(newPuid) == 0 {
+        //Moving the record to top level, use root as parent.
+        newPuid = treeData.RootUid
+    }
+    if newPuid == rec.Puid {
+        log.Trace("Record %s is already under %s, nothing to move",
+                   rec.Uid, newPuid)
+        return nil
+    }
+    parent, err := treeData.getRecord(newPuid)
+    if err != nil {
+        log.Error("Failed to get the new parent %s on move err : %s",
+                   newPuid, err)
+        return err
+    }
+    err = dataStore.CheckChildType(parent.Type, rec.Type)
+    if err != nil {
+        return err
+    }
+    if parent.LftId >= rec.LftId && parent.LftId <= rec.RgtId {
+        log.Error("Cannot move record %s into its own subtree %s",
+                   rec.Uid, newPuid)
+        return appErrors.INVALID_OP
+    }
+    //Name must be unique under the new parent as well.
+    present, err := treeData.getRecordWithNameAndPID(rec.Name, newPuid)
+    if err != nil {
+        return err
+    }
+    if present != nil {
+        log.Info("Cannot move data, Record already present under %s",
+                  newPuid)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    rec.Puid = newPuid
+    //The subtree is shifted to the right end of the new parent, and the
+    // records between old and new position fill the gap.
+    var subtreeShift, gapLftId, gapRgtId, gapShift int64
+    lftId := rec.LftId
+    rgtId := rec.RgtId
+    width := rgtId - lftId + 1
+    pos := parent.RgtId
+    if pos > rgtId {
+        subtreeShift = pos - rgtId - 1
+        gapLftId = rgtId + 1
+        gapRgtId = pos - 1
+        gapShift = -width
+    } else {
+        subtreeShift = pos - lftId
+        gapLftId = pos
+        gapRgtId = lftId - 1
+        gapShift = width
+    }
+    shiftLimit := func(limit int64) int64 {
+        if limit >= lftId && limit <= rgtId {
+            return limit + subtreeShift
+        }
+        if limit >= gapLftId && limit <= gapRgtId {
+            return limit + gapShift
+        }
+        return limit
+    }
+    for _, rec := range treeData.recs {
+        rec.LftId = shiftLimit(rec.LftId)
+        rec.RgtId = shiftLimit(rec.RgtId)
+    }
+    treeData.sortRecords()
+    return nil
+}
+
+//Update the name, description, type and attributes of the record. Name must be
+// unique under the parent of the record.
+func (treeData *TreeData)UpdateRecord(rec *dataStore.Data) error {
+    log := logger.GetLoggerInstance()
+    if len(rec.Name) == 0 {
+        log.Error("Cannot update the record %s with empty name", rec.Uid)
+        return appErrors.INVALID_INPUT
+    }
+    recData, err := treeData.getRecord(rec.Uid)
+    if err != nil {
+        log.Error("Failed to get the record %s on update, Cannot update",
+                   rec.Uid)
+        return err
+    }
+    if len(recData.Puid) == 0 {
+        log.Error("Cannot update root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    //Only name, description, type and attributes can be updated, keep rest
+    // of the fields.
+    rec.Puid = recData.Puid
+    rec.LftId = recData.LftId
+    rec.RgtId = recData.RgtId
+    present, err := treeData.getRecordWithNameAndPID(rec.Name, rec.Puid)
+    if err != nil {
+        return err
+    }
+    if present != nil && present.Uid != rec.Uid {
+        log.Info("Cannot update data, Record %s already present under %s",
+                  rec.Name, rec.Puid)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    parent, err := treeData.getRecord(rec.Puid)
+    if err != nil {
+        return err
+    }
+    childTypes := []string{}
+    start, end := treeData.getSubtreeRange(recData)
+    for _, child := range treeData.recs[start + 1:end] {
+        if child.Puid == rec.Uid {
+            childTypes = append(childTypes, child.Type)
+        }
+    }
+    err = dataStore.CheckRecordType(rec, parent.Type, childTypes)
+    if err != nil {
+        return err
+    }
+    recData.Name = rec.Name
+    recData.Desc = rec.Desc
+    recData.Type = rec.Type
+    recData.Attributes = rec.Attributes.Copy()
+    return nil
+}
+
+//Check the nested set limits of the tree against the parent links.
+func (treeData *TreeData)VerifyTree() *dataStore.TreeReport {
+    return dataStore.VerifyTree(treeData.TreeId, treeData.RootUid,
+                                copyRecords(treeData.recs))
+}
+
+//Recompute the nested set limits of the tree from the parent links.
+func (treeData *TreeData)RebuildTree() error {
+    changedRecs, err := dataStore.RebuildTree(treeData.RootUid,
+                                              copyRecords(treeData.recs))
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to rebuild the tree %s " +
+                                         "err : %s", treeData.TreeId, err)
+        return err
+    }
+    for _, changedRec := range changedRecs {
+        rec := treeData.uids[changedRec.Uid]
+        rec.Puid = changedRec.Puid
+        rec.LftId = changedRec.LftId
+        rec.RgtId = changedRec.RgtId
+    }
+    treeData.sortRecords()
+    return nil
+}
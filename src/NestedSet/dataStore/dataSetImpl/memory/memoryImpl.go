@@ -0,0 +1,678 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package memory
+
+import (
+    "sort"
+    "sync"
+    "time"
+    "NestedSet/appErrors"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+    "NestedSet/sys"
+)
+
+const (
+    BACKEND_NAME = "memory"
+)
+
+func init() {
+    err := dataStore.RegisterBackend(BACKEND_NAME, newDataStore)
+    if err != nil {
+        panic(err)
+    }
+}
+
+//Datastore that keeps all the trees in memory, nothing is persisted. The
+// updates are serialized on the store lock, readers share it.
+type MemoryDataStore struct {
+    dblogger *logger.Logging
+    lock sync.RWMutex
+    trees map[string]*TreeData
+    //Trash entries of the trees, by tree and record id.
+    trash map[string]map[string]dataStore.TrashEntry
+    //Changes of the records in all the trees, in the order of time.
+    history []dataStore.HistoryEntry
+    //Snapshots of the trees, by tree and snapshot id.
+    snapshots map[string]map[string]*memSnapshot
+}
+
+//Snapshot of a tree along with its records in pre-order.
+type memSnapshot struct {
+    dataStore.Snapshot
+    recs []dataStore.Data
+}
+
+//Nothing to connect, the data source is ignored.
+func (memds *MemoryDataStore)CreateDBConnection(dataSource string) error {
+    memds.dblogger.Trace("Using in-memory datastore")
+    return nil
+}
+
+//Create the default tree and its root node if not exisits.
+func (memds *MemoryDataStore)CreateDataStoreTables() error {
+    memds.lock.Lock()
+    defer memds.lock.Unlock()
+    if _, ok := memds.trees[dataStore.DEFAULT_TREE_ID]; ok {
+        memds.dblogger.Trace("Default tree already present.")
+        return nil
+    }
+    tree := new(dataStore.Tree)
+    tree.TreeId = dataStore.DEFAULT_TREE_ID
+    tree.Name = dataStore.DEFAULT_TREE_ID
+    tree.Desc = "The default tree in the system"
+    return memds.insertTree(tree)
+}
+
+//Create a new tree along with its root record. Tree id is generated when its
+// not provided by the user.
+func (memds *MemoryDataStore)insertTree(tree *dataStore.Tree) error {
+    var err error
+    if len(tree.Name) == 0 {
+        memds.dblogger.Error("Cannot create a tree with empty name")
+        return appErrors.INVALID_INPUT
+    }
+    for _, treeData := range memds.trees {
+        if treeData.Name == tree.Name {
+            memds.dblogger.Info("Cannot create tree, %s already present " +
+                                "in the system", tree.Name)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    if len(tree.TreeId) == 0 {
+        tree.TreeId, err = sys.NewUUIDString()
+        if err != nil {
+            memds.dblogger.Error("Failed to create UUID, cannot create a tree")
+            return err
+        }
+    } else if _, ok := memds.trees[tree.TreeId]; ok {
+        memds.dblogger.Info("Cannot create tree, %s already present in the " +
+                            "system", tree.TreeId)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    if tree.TreeId == dataStore.DEFAULT_TREE_ID {
+        tree.RootUid = dataStore.ROOT_UID
+    } else {
+        tree.RootUid, err = sys.NewUUIDString()
+        if err != nil {
+            memds.dblogger.Error("Failed to create UUID, cannot create a " +
+                                 "tree root")
+            return err
+        }
+    }
+    treeData := NewTreeData(tree, nil)
+    treeData.InsertRoot()
+    memds.trees[tree.TreeId] = treeData
+    return nil
+}
+
+//Get the tree, caller must hold the store lock.
+func (memds *MemoryDataStore)getTree(treeId string) (*TreeData, error) {
+    if len(treeId) == 0 {
+        memds.dblogger.Error("Cannot retrieve a tree with empty id")
+        return nil, appErrors.INVALID_INPUT
+    }
+    treeData, ok := memds.trees[treeId]
+    if !ok {
+        memds.dblogger.Error("Tree %s is not present in the system", treeId)
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    return treeData, nil
+}
+
+//Run a tree update with the exclusive lock of the store. The versions of the
+// changed records are updated and the changes are added to the history,
+// unless 'change' is nil.
+func (memds *MemoryDataStore)updateTree(treeId string,
+                                   change *dataStore.Change,
+                                   updateFn func(*TreeData) error) error {
+    memds.lock.Lock()
+    defer memds.lock.Unlock()
+    treeData, err := memds.getTree(treeId)
+    if err != nil {
+        return err
+    }
+    if change == nil {
+        return updateFn(treeData)
+    }
+    before := treeData.GetAllRecords()
+    err = updateFn(treeData)
+    if err != nil {
+        return err
+    }
+    after := treeData.GetAllRecords()
+    entries, err := change.GetHistory(before, after)
+    if err != nil {
+        return err
+    }
+    treeData.UpdateVersions(before, after)
+    memds.history = append(memds.history, entries...)
+    return nil
+}
+
+//Run a tree read with the shared lock of the store.
+func (memds *MemoryDataStore)readTree(treeId string,
+                                 readFn func(*TreeData) error) error {
+    memds.lock.RLock()
+    defer memds.lock.RUnlock()
+    treeData, err := memds.getTree(treeId)
+    if err != nil {
+        return err
+    }
+    return readFn(treeData)
+}
+
+func (memds *MemoryDataStore)CreateTree(tree *dataStore.Tree) error {
+    memds.lock.Lock()
+    defer memds.lock.Unlock()
+    return memds.insertTree(tree)
+}
+
+//Delete the tree and all its records. Default tree cannot be deleted.
+func (memds *MemoryDataStore)DeleteTree(treeId string) error {
+    memds.lock.Lock()
+    defer memds.lock.Unlock()
+    if treeId == dataStore.DEFAULT_TREE_ID {
+        memds.dblogger.Error("Cannot delete default tree, as its owned by " +
+                             "application")
+        return appErrors.INVALID_INPUT
+    }
+    _, err := memds.getTree(treeId)
+    if err != nil {
+        return err
+    }
+    delete(memds.trees, treeId)
+    delete(memds.trash, treeId)
+    delete(memds.snapshots, treeId)
+    return nil
+}
+
+func (memds *MemoryDataStore)GetTree(treeId string) (*dataStore.Tree, error) {
+    var tree dataStore.Tree
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        tree = treeData.Tree
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &tree, nil
+}
+
+func (memds *MemoryDataStore)GetAllTrees() ([]dataStore.Tree, error) {
+    memds.lock.RLock()
+    defer memds.lock.RUnlock()
+    trees := []dataStore.Tree{}
+    for _, treeData := range memds.trees {
+        trees = append(trees, treeData.Tree)
+    }
+    sort.Slice(trees, func(i, j int) bool {
+        return trees[i].Name < trees[j].Name
+    })
+    return trees, nil
+}
+
+func (memds *MemoryDataStore)VerifyTree(treeId string) (*dataStore.TreeReport,
+                                                        error) {
+    var report *dataStore.TreeReport
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        report = treeData.VerifyTree()
+        return nil
+    })
+    return report, err
+}
+
+func (memds *MemoryDataStore)RebuildTree(treeId string) error {
+    return memds.updateTree(treeId, nil, func(treeData *TreeData) error {
+        return treeData.RebuildTree()
+    })
+}
+
+func (memds *MemoryDataStore)CreateRecord(treeId string, rec *dataStore.Data,
+                                          actor string) error {
+    change := dataStore.NewChange(actor)
+    return memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        return treeData.InsertRecord(rec)
+    })
+}
+
+func (memds *MemoryDataStore)DeleteRecord(treeId string, recid string,
+                                          version int64, actor string) error {
+    change := dataStore.NewChange(actor)
+    return memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        err := treeData.CheckVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        return treeData.DeleteRecord(recid)
+    })
+}
+
+func (memds *MemoryDataStore)DeleteLeafRecord(treeId string, recid string,
+                                              version int64,
+                                              actor string) error {
+    change := dataStore.NewChange(actor)
+    return memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        err := treeData.CheckVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        return treeData.DeleteLeafRecord(recid)
+    })
+}
+
+func (memds *MemoryDataStore)PromoteRecord(treeId string, recid string,
+                                           version int64, actor string) error {
+    change := dataStore.NewChange(actor)
+    return memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        err := treeData.CheckVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        return treeData.PromoteRecord(recid)
+    })
+}
+
+func (memds *MemoryDataStore)UpdateRecord(treeId string, rec *dataStore.Data,
+                                          version int64, actor string) error {
+    change := dataStore.NewChange(actor)
+    return memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        err := treeData.CheckVersion(rec.Uid, version, false)
+        if err != nil {
+            return err
+        }
+        return treeData.UpdateRecord(rec)
+    })
+}
+
+func (memds *MemoryDataStore)MoveRecord(treeId string, recid string,
+                                        newPuid string, version int64,
+                                        actor string) error {
+    change := dataStore.NewChange(actor)
+    return memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        err := treeData.CheckVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        return treeData.MoveRecord(recid, newPuid)
+    })
+}
+
+func (memds *MemoryDataStore)CopySubtree(treeId string, srcId string,
+                                         destPuid string,
+                                         actor string) (*dataStore.Data,
+                                                        error) {
+    var uid string
+    change := dataStore.NewChange(actor)
+    err := memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        var err error
+        uid, err = treeData.CopyRecord(srcId, destPuid)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    //Copied records get their versions after the update.
+    return memds.GetRecord(treeId, uid)
+}
+
+func (memds *MemoryDataStore)GetRecord(treeId string,
+                                       recid string) (*dataStore.Data, error) {
+    var row *dataStore.Data
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        var err error
+        row, err = treeData.GetRecord(recid)
+        return err
+    })
+    return row, err
+}
+
+func (memds *MemoryDataStore)GetAncestors(treeId string,
+                                          recid string) ([]dataStore.Data,
+                                                         error) {
+    var rows []dataStore.Data
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        var err error
+        rows, err = treeData.GetAncestors(recid)
+        return err
+    })
+    return rows, err
+}
+
+func (memds *MemoryDataStore)GetDescendants(treeId string, recid string,
+                                            maxDepth int) ([]dataStore.Data,
+                                                           error) {
+    var rows []dataStore.Data
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        var err error
+        rows, err = treeData.GetDescendants(recid, maxDepth)
+        return err
+    })
+    return rows, err
+}
+
+func (memds *MemoryDataStore)GetChildren(treeId string,
+                                         recid string) ([]dataStore.Data,
+                                                        error) {
+    var rows []dataStore.Data
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        var err error
+        rows, err = treeData.GetChildren(recid)
+        return err
+    })
+    return rows, err
+}
+
+func (memds *MemoryDataStore)GetRecordByName(treeId string,
+                                             name string) ([]dataStore.Data,
+                                                          error) {
+    var rows []dataStore.Data
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        var err error
+        rows, err = treeData.GetRecordByName(name)
+        return err
+    })
+    return rows, err
+}
+
+func (memds *MemoryDataStore)GetAllRecords(treeId string) ([]dataStore.Data,
+                                                           error) {
+    var rows []dataStore.Data
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        rows = treeData.GetAllRecords()
+        return nil
+    })
+    return rows, err
+}
+
+func (memds *MemoryDataStore)GetRecordsPage(treeId string,
+                        request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                         error) {
+    var page *dataStore.RecordPage
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        page = treeData.GetRecordsPage(request)
+        return nil
+    })
+    return page, err
+}
+
+func (memds *MemoryDataStore)GetRecordByNamePage(treeId string, name string,
+                        request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                         error) {
+    var page *dataStore.RecordPage
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        var err error
+        page, err = treeData.GetRecordByNamePage(name, request)
+        return err
+    })
+    return page, err
+}
+
+func (memds *MemoryDataStore)TrashRecord(treeId string, recid string,
+                                         version int64, actor string) error {
+    change := dataStore.NewChange(actor)
+    change.RemoveAction = dataStore.HISTORY_TRASH
+    return memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        err := treeData.CheckVersion(recid, version, true)
+        if err != nil {
+            return err
+        }
+        entry, err := treeData.TrashRecord(recid, time.Now())
+        if err != nil {
+            return err
+        }
+        if _, ok := memds.trash[treeId]; !ok {
+            memds.trash[treeId] = make(map[string]dataStore.TrashEntry)
+        }
+        memds.trash[treeId][entry.Uid] = *entry
+        return nil
+    })
+}
+
+func (memds *MemoryDataStore)GetTrash(treeId string) ([]dataStore.TrashEntry,
+                                                      error) {
+    entries := []dataStore.TrashEntry{}
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        for _, entry := range memds.trash[treeId] {
+            entries = append(entries, entry)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    dataStore.SortTrashEntries(entries)
+    return entries, nil
+}
+
+func (memds *MemoryDataStore)RestoreRecord(treeId string, recid string,
+                                           puid string, actor string) error {
+    change := dataStore.NewChange(actor)
+    change.AddAction = dataStore.HISTORY_RESTORE
+    return memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        entry, ok := memds.trash[treeId][recid]
+        if !ok {
+            memds.dblogger.Error("Record %s is not present in the trash",
+                                 recid)
+            return appErrors.DATA_NOT_FOUND
+        }
+        err := treeData.RestoreRecord(&entry, puid)
+        if err != nil {
+            return err
+        }
+        delete(memds.trash[treeId], recid)
+        return nil
+    })
+}
+
+func (memds *MemoryDataStore)PurgeTrash(before time.Time) (int, error) {
+    memds.lock.Lock()
+    defer memds.lock.Unlock()
+    count := 0
+    beforeStr := dataStore.TrashTimeString(before)
+    for _, entries := range memds.trash {
+        for uid, entry := range entries {
+            if entry.DeletedAt < beforeStr {
+                delete(entries, uid)
+                count++
+            }
+        }
+    }
+    return count, nil
+}
+
+func (memds *MemoryDataStore)GetRecordHistory(treeId string,
+                            recid string) ([]dataStore.HistoryEntry, error) {
+    memds.lock.RLock()
+    defer memds.lock.RUnlock()
+    entries := []dataStore.HistoryEntry{}
+    for _, entry := range memds.history {
+        if entry.TreeId == treeId && entry.Uid == recid {
+            entries = append(entries, entry)
+        }
+    }
+    return entries, nil
+}
+
+func (memds *MemoryDataStore)GetAudit(
+                        since time.Time) ([]dataStore.HistoryEntry, error) {
+    memds.lock.RLock()
+    defer memds.lock.RUnlock()
+    sinceStr := dataStore.HistoryTimeString(since)
+    entries := []dataStore.HistoryEntry{}
+    for _, entry := range memds.history {
+        if entry.ChangedAt >= sinceStr {
+            entries = append(entries, entry)
+        }
+    }
+    return entries, nil
+}
+
+//Get the snapshot of the tree, the store lock must be held by the caller.
+func (memds *MemoryDataStore)getSnapshot(treeId string,
+                                    snapshotId string) (*memSnapshot, error) {
+    snapshot, ok := memds.snapshots[treeId][snapshotId]
+    if !ok {
+        memds.dblogger.Error("Snapshot %s is not present in the tree %s",
+                             snapshotId, treeId)
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    return snapshot, nil
+}
+
+func (memds *MemoryDataStore)CreateSnapshot(treeId string,
+                                        snapshot *dataStore.Snapshot) error {
+    memds.lock.Lock()
+    defer memds.lock.Unlock()
+    treeData, err := memds.getTree(treeId)
+    if err != nil {
+        return err
+    }
+    recs := treeData.GetAllRecords()
+    err = snapshot.Init(treeId, len(recs), time.Now())
+    if err != nil {
+        return err
+    }
+    for _, entry := range memds.snapshots[treeId] {
+        if entry.Name == snapshot.Name {
+            memds.dblogger.Info("Cannot create snapshot, %s already present " +
+                                "in the tree %s", snapshot.Name, treeId)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    if _, ok := memds.snapshots[treeId]; !ok {
+        memds.snapshots[treeId] = make(map[string]*memSnapshot)
+    }
+    memds.snapshots[treeId][snapshot.SnapshotId] = &memSnapshot{*snapshot,
+                                                                recs}
+    return nil
+}
+
+func (memds *MemoryDataStore)DeleteSnapshot(treeId string,
+                                            snapshotId string) error {
+    memds.lock.Lock()
+    defer memds.lock.Unlock()
+    _, err := memds.getTree(treeId)
+    if err != nil {
+        return err
+    }
+    _, err = memds.getSnapshot(treeId, snapshotId)
+    if err != nil {
+        return err
+    }
+    delete(memds.snapshots[treeId], snapshotId)
+    return nil
+}
+
+func (memds *MemoryDataStore)GetSnapshot(treeId string,
+                            snapshotId string) (*dataStore.Snapshot, error) {
+    var snapshot dataStore.Snapshot
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        entry, err := memds.getSnapshot(treeId, snapshotId)
+        if err != nil {
+            return err
+        }
+        snapshot = entry.Snapshot
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &snapshot, nil
+}
+
+func (memds *MemoryDataStore)GetAllSnapshots(
+                            treeId string) ([]dataStore.Snapshot, error) {
+    snapshots := []dataStore.Snapshot{}
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        for _, entry := range memds.snapshots[treeId] {
+            snapshots = append(snapshots, entry.Snapshot)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    dataStore.SortSnapshots(snapshots)
+    return snapshots, nil
+}
+
+func (memds *MemoryDataStore)GetSnapshotRecords(treeId string,
+                                    snapshotId string,
+                                    recid string) ([]dataStore.Data, error) {
+    var rows []dataStore.Data
+    err := memds.readTree(treeId, func(treeData *TreeData) error {
+        entry, err := memds.getSnapshot(treeId, snapshotId)
+        if err != nil {
+            return err
+        }
+        rows, err = dataStore.GetSnapshotSubtree(entry.recs, recid)
+        return err
+    })
+    return rows, err
+}
+
+func (memds *MemoryDataStore)RestoreSnapshot(treeId string,
+                                             snapshotId string, recid string,
+                                             actor string) error {
+    change := dataStore.NewChange(actor)
+    change.AddAction = dataStore.HISTORY_RESTORE
+    change.RemoveAction = dataStore.HISTORY_TRASH
+    return memds.updateTree(treeId, change, func(treeData *TreeData) error {
+        snapshot, err := memds.getSnapshot(treeId, snapshotId)
+        if err != nil {
+            return err
+        }
+        entries, err := treeData.RestoreSnapshot(snapshot.recs, recid,
+                                                 time.Now())
+        if err != nil {
+            return err
+        }
+        //Trash entries of the records put back in the tree are stale.
+        restored, err := dataStore.GetSnapshotSubtree(snapshot.recs, recid)
+        if err != nil {
+            return err
+        }
+        for _, rec := range restored {
+            delete(memds.trash[treeId], rec.Uid)
+        }
+        if _, ok := memds.trash[treeId]; !ok {
+            memds.trash[treeId] = make(map[string]dataStore.TrashEntry)
+        }
+        for _, entry := range entries {
+            memds.trash[treeId][entry.Uid] = entry
+        }
+        return nil
+    })
+}
+
+//Create an empty in-memory datastore, every store has its own trees.
+func NewMemoryDataStore() *MemoryDataStore {
+    memds := new(MemoryDataStore)
+    memds.dblogger = logger.GetLoggerInstance()
+    memds.trees = make(map[string]*TreeData)
+    memds.trash = make(map[string]map[string]dataStore.TrashEntry)
+    memds.snapshots = make(map[string]map[string]*memSnapshot)
+    return memds
+}
+
+//In-memory datastore has no options.
+func newDataStore(
+        options dataStore.BackendOptions) (dataStore.DataSetInterface, error) {
+    err := options.Check()
+    if err != nil {
+        return nil, err
+    }
+    return NewMemoryDataStore(), nil
+}
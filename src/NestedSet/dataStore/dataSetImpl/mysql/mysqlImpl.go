@@ -0,0 +1,128 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mysql
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    "github.com/jmoiron/sqlx"
+    driver "github.com/go-sql-driver/mysql"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl/rdbms"
+)
+
+const (
+    BACKEND_NAME = "mysql"
+    // Connections in the pool, writers of a tree are serialized on the tree
+    // lock, so the other trees and the readers can use rest of the pool.
+    MYSQL_MAX_OPEN_CONNS = 16
+    // Queries quote the identifiers with double quotes, and 'Desc' column is
+    // a reserved word in MySQL. Enable ANSI quotes on every connection along
+    // with the configured sql mode.
+    MYSQL_SQL_MODE = "CONCAT(@@sql_mode, ',ANSI_QUOTES')"
+)
+
+func init() {
+    err := dataStore.RegisterBackend(BACKEND_NAME, newDataStore)
+    if err != nil {
+        panic(err)
+    }
+}
+
+type mysqlDialect struct {
+    maxOpenConns int
+}
+
+func (dialect *mysqlDialect)Name() string {
+    return "mysql"
+}
+
+//Connect to the MySQL/MariaDB server with the data source name 'dsn' in the
+// form 'user:pass@tcp(host:3306)/db'.
+func (dialect *mysqlDialect)Connect(dsn string) (*sqlx.DB, error) {
+    cfg, err := driver.ParseDSN(dsn)
+    if err != nil {
+        return nil, err
+    }
+    if cfg.Params == nil {
+        cfg.Params = make(map[string]string)
+    }
+    cfg.Params["sql_mode"] = MYSQL_SQL_MODE
+    dbHandle, err := sqlx.Connect("mysql", cfg.FormatDSN())
+    if err != nil {
+        return nil, err
+    }
+    dbHandle.SetMaxOpenConns(dialect.maxOpenConns)
+    return dbHandle, nil
+}
+
+//Ids and names are indexed, InnoDB cannot index TEXT columns.
+func (dialect *mysqlDialect)StringType() string {
+    return "VARCHAR(255)"
+}
+
+func (dialect *mysqlDialect)TextType() string {
+    return "TEXT"
+}
+
+func (dialect *mysqlDialect)IntegerType() string {
+    return "BIGINT"
+}
+
+func (dialect *mysqlDialect)IndexExistsQuery() string {
+    return `SELECT INDEX_NAME FROM information_schema.STATISTICS WHERE
+            TABLE_SCHEMA=DATABASE() AND TABLE_NAME=(?) AND INDEX_NAME=(?)`
+}
+
+//InnoDB row locks, 'LOCK IN SHARE MODE' is supported by both MySQL and
+// MariaDB.
+func (dialect *mysqlDialect)LockClause(exclusive bool) string {
+    if exclusive == true {
+        return " FOR UPDATE"
+    }
+    return " LOCK IN SHARE MODE"
+}
+
+//The attributes are stored as JSON text, the attribute is compared as JSON.
+// MySQL does not compare the JSON values in an IN list.
+func (dialect *mysqlDialect)AttributeCond(key string,
+                                     value string) (string, []interface{}) {
+    path, _ := json.Marshal(key)
+    conds := []string{}
+    args := []interface{}{}
+    for _, jsonValue := range rdbms.GetAttributeJSONValues(value) {
+        conds = append(conds, fmt.Sprintf(`JSON_EXTRACT("%s", ?) =
+                                           CAST(? AS JSON)`,
+                                          rdbms.DATA_ATTRIBUTES))
+        args = append(args, "$." + string(path), jsonValue)
+    }
+    return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+//Size of the connection pool can be set with the 'maxOpenConns' option.
+func newDataStore(
+        options dataStore.BackendOptions) (dataStore.DataSetInterface, error) {
+    err := options.Check(rdbms.OPTION_MAX_OPEN_CONNS)
+    if err != nil {
+        return nil, err
+    }
+    dialect := new(mysqlDialect)
+    dialect.maxOpenConns, err = options.GetInt(rdbms.OPTION_MAX_OPEN_CONNS,
+                                               MYSQL_MAX_OPEN_CONNS)
+    if err != nil {
+        return nil, err
+    }
+    return rdbms.NewRdbmsDataStore(dialect), nil
+}
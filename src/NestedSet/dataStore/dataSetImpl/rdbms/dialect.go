@@ -0,0 +1,133 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rdbms
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "github.com/jmoiron/sqlx"
+)
+
+const (
+    //Backend option to set the size of the connection pool.
+    OPTION_MAX_OPEN_CONNS = "maxOpenConns"
+)
+
+//Database specific parts of the SQL datastore. The queries are written in
+// standard SQL with '?' placeholders and double quoted identifiers, the
+// dialect provides the rest.
+type Dialect interface {
+    //Name of the database, used in the logs.
+    Name() string
+    //Open the connection pool to the database with its driver.
+    Connect(dataSource string) (*sqlx.DB, error)
+    //Column types of the tables. String type is used for the ids and names
+    // that are indexed, Text type for free text.
+    StringType() string
+    TextType() string
+    IntegerType() string
+    //Query to find an index of a table, the table and index names are the
+    // arguments of the query.
+    IndexExistsQuery() string
+    //Clause appended to the tree select to lock the tree till the end of the
+    // transaction. Exclusive lock is taken for the updates and shared lock
+    // for the reads, empty when the database serializes the transactions.
+    LockClause(exclusive bool) string
+    //Condition that the attribute 'key' of the record has the 'value', as
+    // checked by Attributes.HasValue, along with the arguments of the
+    // condition.
+    AttributeCond(key string, value string) (string, []interface{})
+}
+
+//Get the JSON values an attribute is equal to when it has the 'value', the
+// value as a JSON string and the value itself when its the JSON of another
+// type.
+func GetAttributeJSONValues(value string) []string {
+    strValue, _ := json.Marshal(value)
+    values := []string{string(strValue)}
+    var jsonValue interface{}
+    err := json.Unmarshal([]byte(value), &jsonValue)
+    if err != nil {
+        return values
+    }
+    if _, ok := jsonValue.(string); ok {
+        return values
+    }
+    //The attributes are compared on their JSON, only the encoding of the
+    // value can match.
+    encValue, err := json.Marshal(jsonValue)
+    if err == nil && string(encValue) == value {
+        values = append(values, value)
+    }
+    return values
+}
+
+//Connection handle used by all the queries, it can be a DB or a transaction.
+// Queries are rebound to the placeholder style of the database driver.
+type dbConn struct {
+    sqlx.Ext
+    dialect Dialect
+}
+
+func (sqlds *RdbmsDataStore)newDBConn(ext sqlx.Ext) *dbConn {
+    return &dbConn{ext, sqlds.dialect}
+}
+
+func (conn *dbConn)Exec(query string, args ...interface{}) (sql.Result, error) {
+    return conn.Ext.Exec(conn.Rebind(query), args...)
+}
+
+func (conn *dbConn)Query(query string, args ...interface{}) (*sql.Rows, error) {
+    return conn.Ext.Query(conn.Rebind(query), args...)
+}
+
+func (conn *dbConn)Queryx(query string,
+                          args ...interface{}) (*sqlx.Rows, error) {
+    return conn.Ext.Queryx(conn.Rebind(query), args...)
+}
+
+func (conn *dbConn)QueryRowx(query string, args ...interface{}) *sqlx.Row {
+    return conn.Ext.QueryRowx(conn.Rebind(query), args...)
+}
+
+//Index on the table columns, index name is derived from the table and columns.
+type sqlIndex struct {
+    name string
+    table string
+    columns []string
+}
+
+func newSqlIndex(table string, columns ...string) sqlIndex {
+    return sqlIndex{table + strings.Join(columns, "") + "Idx", table, columns}
+}
+
+//Create the index if its not present in the table.
+func (index *sqlIndex)createIndex(conn *dbConn) error {
+    var err error
+    rows := []string{}
+    err = sqlx.Select(conn, &rows, conn.dialect.IndexExistsQuery(),
+                      index.table, index.name)
+    if err != nil {
+        return err
+    }
+    if len(rows) != 0 {
+        return nil
+    }
+    _, err = conn.Exec(fmt.Sprintf(`CREATE INDEX "%s" ON "%s" ("%s")`,
+                                   index.name, index.table,
+                                   strings.Join(index.columns, `", "`)))
+    return err
+}
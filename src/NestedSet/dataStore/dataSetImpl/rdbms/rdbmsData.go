@@ -0,0 +1,928 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rdbms
+
+import (
+    "fmt"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+    "NestedSet/appErrors"
+    "NestedSet/sys"
+)
+
+const (
+    SQL_DATA_TABLE_NAME = "dataSet"
+    DATA_TREEID = "TreeId"
+    DATA_UID = "Uid"
+    PARENT_UID = "Puid"
+    DATA_NAME = "Name"
+    DATA_DESC = "Desc"
+    DATA_LFTID = "LftId"
+    DATA_RGTID = "RgtId"
+    DATA_ATTRIBUTES = "Attributes"
+    DATA_TYPE = "Type"
+    DATA_VERSION = "Version"
+    DATA_CHILD_VERSION = "ChildVersion"
+)
+
+//Initial schema of the data table, column types are provided by the database
+// dialect. Rest of the columns are added by the schema migrations.
+func dataSchema(dialect Dialect) string {
+    return fmt.Sprintf(
+                 `CREATE TABLE IF NOT EXISTS "%s" ("%s" %s PRIMARY KEY,
+                 "%s" %s,
+                 "%s" %s NOT NULL,
+                 "%s" %s,
+                 "%s" %s DEFAULT %d,
+                 "%s" %s DEFAULT %d)`,
+                 SQL_DATA_TABLE_NAME,
+                 DATA_UID, dialect.StringType(),
+                 PARENT_UID, dialect.StringType(),
+                 DATA_NAME, dialect.StringType(),
+                 DATA_DESC, dialect.TextType(),
+                 DATA_LFTID, dialect.IntegerType(), dataStore.DEFAULT_LFTID,
+                 DATA_RGTID, dialect.IntegerType(), dataStore.DEFAULT_RGTID)
+}
+
+// Indexes on nested set limits, every tree update/range query uses them.
+// Nested set limits are maintained per tree, so index them with tree id.
+var dataNSIndexes = []sqlIndex{
+    newSqlIndex(SQL_DATA_TABLE_NAME, DATA_TREEID, DATA_LFTID),
+    newSqlIndex(SQL_DATA_TABLE_NAME, DATA_TREEID, DATA_RGTID),
+}
+
+// Indexes for the lookup of the childrens and the records with name.
+var dataInfoIndexes = []sqlIndex{
+    newSqlIndex(SQL_DATA_TABLE_NAME, DATA_TREEID, PARENT_UID),
+    newSqlIndex(SQL_DATA_TABLE_NAME, DATA_TREEID, DATA_NAME),
+}
+
+var (
+    //Create a entry without any nested set parameters
+    dataCreate = fmt.Sprintf(`INSERT INTO "%s"
+                                ("%s", "%s", "%s", "%s", "%s", "%s", "%s")
+                                VALUES (?, ?, ?, ?, ?, ?, ?)`,
+                                SQL_DATA_TABLE_NAME,
+                                DATA_UID,
+                                DATA_TREEID,
+                                PARENT_UID,
+                                DATA_NAME,
+                                DATA_DESC,
+                                DATA_TYPE,
+                                DATA_ATTRIBUTES)
+    //Create a entry along with its nested set parameters.
+    dataCreateWithLimits = fmt.Sprintf(`INSERT INTO "%s"
+                                ("%s", "%s", "%s", "%s", "%s", "%s", "%s",
+                                 "%s", "%s")
+                                VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+                                SQL_DATA_TABLE_NAME,
+                                DATA_UID, DATA_TREEID, PARENT_UID, DATA_NAME,
+                                DATA_DESC, DATA_TYPE, DATA_ATTRIBUTES,
+                                DATA_LFTID, DATA_RGTID)
+    dataDeleteOnId = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?)`,
+                                 SQL_DATA_TABLE_NAME, DATA_UID)
+    // Update the nested set parameters for the tree hierarchy.
+    dataUpdateTree = fmt.Sprintf(`UPDATE "%s" SET "%s"=(?),"%s"=(?)
+                                  WHERE "%s"=(?)`,
+                                SQL_DATA_TABLE_NAME,
+                                DATA_LFTID,
+                                DATA_RGTID,
+                                DATA_UID)
+    // Update the user owned fields of a record.
+    dataUpdateInfo = fmt.Sprintf(`UPDATE "%s" SET "%s"=(?),"%s"=(?),"%s"=(?),
+                                  "%s"=(?) WHERE "%s"=(?)`,
+                                SQL_DATA_TABLE_NAME,
+                                DATA_NAME,
+                                DATA_DESC,
+                                DATA_TYPE,
+                                DATA_ATTRIBUTES,
+                                DATA_UID)
+    // Update the parent of a record, used when moving a subtree.
+    dataUpdateParent = fmt.Sprintf(`UPDATE "%s" SET "%s"=(?) WHERE "%s"=(?)`,
+                                SQL_DATA_TABLE_NAME,
+                                PARENT_UID,
+                                DATA_UID)
+    // Depth of a record is the number of its parents upto root.
+    dataGetDepth = fmt.Sprintf(`SELECT COUNT(*) FROM "%s" WHERE "%s"=(?)
+                                AND "%s"<(?) AND "%s">(?)`,
+                                SQL_DATA_TABLE_NAME, DATA_TREEID,
+                                DATA_LFTID, DATA_RGTID)
+    dataGetAllRec = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                                ORDER BY "%s"`,
+                                SQL_DATA_TABLE_NAME, DATA_TREEID, DATA_LFTID)
+    dataGetwthUid = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                                AND "%s"=(?)`,
+                                SQL_DATA_TABLE_NAME, DATA_TREEID, DATA_UID)
+    //Get the root entry of the tree, its the only entry without parent.
+    dataGetTreeRoot = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                                AND "%s"=''`,
+                                SQL_DATA_TABLE_NAME, DATA_TREEID, PARENT_UID)
+    //Get an entry with name
+    dataGetwthName = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                               AND "%s"=(?) ORDER BY "%s"`,
+                               SQL_DATA_TABLE_NAME, DATA_TREEID,
+                               DATA_NAME, DATA_LFTID)
+    //Get an entry with name under specific parent.
+    dataGetwthNamePID = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?) AND
+                               "%s"=(?) AND "%s"=(?)`,
+                               SQL_DATA_TABLE_NAME,
+                               DATA_TREEID,
+                               DATA_NAME,
+                               PARENT_UID)
+    //Get all the parents of the record upto root including the record itself.
+    dataGetAllAncestors = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?) AND
+                                       "%s"<=(?) AND "%s">=(?) ORDER BY "%s"`,
+                                       SQL_DATA_TABLE_NAME, DATA_TREEID,
+                                       DATA_LFTID, DATA_RGTID, DATA_LFTID)
+    //Get all the childrens of the record in pre-order.
+    dataGetAllChildrens = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?) AND
+                                       "%s">(?) AND "%s"<(?) ORDER BY "%s"`,
+                                       SQL_DATA_TABLE_NAME, DATA_TREEID,
+                                       DATA_LFTID, DATA_LFTID, DATA_LFTID)
+    //Get the direct childrens of the record in order.
+    dataGetwthPID = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?) AND "%s"=(?)
+                                ORDER BY "%s"`,
+                                SQL_DATA_TABLE_NAME, DATA_TREEID, PARENT_UID,
+                                DATA_LFTID)
+    // Update the versions of a record after a change.
+    dataUpdateVersion = fmt.Sprintf(`UPDATE "%s" SET "%s"=(?),"%s"=(?)
+                                  WHERE "%s"=(?)`,
+                                SQL_DATA_TABLE_NAME,
+                                DATA_VERSION,
+                                DATA_CHILD_VERSION,
+                                DATA_UID)
+    //Update the child version of a record and all its parents.
+    dataUpdateParentsVersion = fmt.Sprintf(`UPDATE "%s" SET "%s"=(?)
+                                  WHERE "%s"=(?) AND "%s"<=(?) AND "%s">=(?)`,
+                                SQL_DATA_TABLE_NAME,
+                                DATA_CHILD_VERSION,
+                                DATA_TREEID, DATA_LFTID, DATA_RGTID)
+    //Get the records before a LftId whose subtree is not ended there.
+    dataGetOpenParents = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?) AND
+                                      "%s"<=(?) AND "%s">(?) ORDER BY "%s"`,
+                                      SQL_DATA_TABLE_NAME, DATA_TREEID,
+                                      DATA_LFTID, DATA_RGTID, DATA_LFTID)
+    //Conditions of the filter of a page, joined with AND in the page queries.
+    dataTreeCond = fmt.Sprintf(`"%s"=(?)`, DATA_TREEID)
+    dataNameCond = fmt.Sprintf(`"%s"=(?)`, DATA_NAME)
+    dataTypeCond = fmt.Sprintf(`"%s"=(?)`, DATA_TYPE)
+    dataLeafCond = fmt.Sprintf(`"%s"="%s"+1`, DATA_RGTID, DATA_LFTID)
+    dataParentCond = fmt.Sprintf(`"%s">"%s"+1`, DATA_RGTID, DATA_LFTID)
+    //Get the records after a LftId in pre-order, for a page of the records.
+    dataGetPage = fmt.Sprintf(`SELECT * FROM "%s" WHERE %%s AND "%s">(?)
+                               ORDER BY "%s"`,
+                               SQL_DATA_TABLE_NAME, DATA_LFTID, DATA_LFTID)
+    dataCountPage = fmt.Sprintf(`SELECT COUNT(*) FROM "%s" WHERE %%s`,
+                                SQL_DATA_TABLE_NAME)
+    //Delete all the entries of a tree.
+    dataDeleteTree = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?)`,
+                                SQL_DATA_TABLE_NAME, DATA_TREEID)
+
+)
+
+// Anonymous pointer to Data struct. It is decided to use pointer instead of
+// Data value as it reduce the overhead of copying in later stages.
+// Creation of sqlData must need to allocate Data as well to avoid runtime
+// panic. However end to end sqlData can keep the same Data entry struct
+// for whole operations without copying the values each time.
+// It is assumed copying pointer faster than copying the entire structure.
+type sqlData struct {
+    *dataStore.Data
+}
+
+//Root is the only record in a tree without a parent.
+func(dataObj *sqlData)IsdataRoot() bool {
+    if len(dataObj.Puid) == 0 {
+        return true
+    }
+    return false
+}
+
+//Create a record object with 'uid' in the same tree as the record.
+func(dataObj *sqlData)newTreeData(uid string) *sqlData {
+    recObj := new(sqlData)
+    recObj.Data = new(dataStore.Data)
+    recObj.TreeId = dataObj.TreeId
+    recObj.Uid = uid
+    return recObj
+}
+
+//Get the type of the record 'uid' in the same tree as the record.
+func(dataObj *sqlData)getTypeOf(conn *dbConn, uid string) (string, error) {
+    rec, err := dataObj.newTreeData(uid).GetdataById(conn)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to get the type of %s " +
+                                         "err : %s", uid, err)
+        return "", err
+    }
+    return rec.Type, nil
+}
+
+//Get the types of the direct childrens of the record.
+func(dataObj *sqlData)getChildTypes(conn *dbConn) ([]string, error) {
+    rows, err := dataObj.GetDirectChildrens(conn)
+    if err != nil {
+        return nil, err
+    }
+    childTypes := []string{}
+    for _, row := range rows {
+        childTypes = append(childTypes, row.Type)
+    }
+    return childTypes, nil
+}
+
+func (dataObj *sqlData)GetAllChildrens(conn *dbConn)([]dataStore.Data,
+                                               error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Data{}
+    err = sqlx.Select(conn, &rows, dataGetAllChildrens, dataObj.TreeId,
+                      dataObj.LftId, dataObj.RgtId)
+    if err != nil {
+        log.Error("Failed to retereive all the childrens for %d - %d",
+                    dataObj.LftId, dataObj.RgtId)
+        return nil, err
+    }
+    dataStore.UpdateTreeInfo(rows, dataObj.Depth + 1)
+    return rows, nil
+ }
+
+//Get the path from root to the record, ordered from the root.
+func (dataObj *sqlData)GetAllAncestors(conn *dbConn)([]dataStore.Data,
+                                               error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Data{}
+    err = sqlx.Select(conn, &rows, dataGetAllAncestors, dataObj.TreeId,
+                      dataObj.LftId, dataObj.LftId)
+    if err != nil {
+        log.Error("Failed to retereive all the ancestors for %s err : %s",
+                    dataObj.Uid, err)
+        return nil, err
+    }
+    dataStore.UpdateTreeInfo(rows, 0)
+    return rows, nil
+}
+
+func (dataObj *sqlData)GetDirectChildrens(conn *dbConn)([]dataStore.Data,
+                                               error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Data{}
+    err = sqlx.Select(conn, &rows, dataGetwthPID, dataObj.TreeId,
+                      dataObj.Uid)
+    if err != nil {
+        log.Error("Failed to retereive the direct childrens of %s err : %s",
+                    dataObj.Uid, err)
+        return nil, err
+    }
+    dataStore.UpdateTreeInfo(rows, dataObj.Depth + 1)
+    return rows, nil
+}
+
+func (dataObj *sqlData)GetAllRecords(conn *dbConn)([]dataStore.Data,
+                                               error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Data{}
+    err = sqlx.Select(conn, &rows, dataGetAllRec, dataObj.TreeId)
+    if err != nil {
+        log.Error("Failed to retereive all data  from table err : %s",
+                    err)
+        return nil, err
+    }
+    dataStore.UpdateTreeInfo(rows, 0)
+    return rows, nil
+ }
+
+//Delete a record from a table using its ID
+func (dataObj *sqlData)deleteDataOnId(conn *dbConn)(error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    if len(dataObj.Uid) == 0 {
+        log.Error("Cannot retrieve a record with empty Uid" +
+                  "Delete is failed")
+        return appErrors.INVALID_INPUT
+    }
+    if dataObj.IsdataRoot() == true {
+        //System doesnt allow to delete root node, as its owned by application.
+        log.Error("Cannot delete root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    _, err = conn.Exec(dataDeleteOnId, dataObj.Uid)
+    if err != nil {
+        log.Error("Failed to delete record with uid %s", dataObj.Uid)
+        return err
+    }
+    return nil
+}
+//Retrieve a record with record UID
+func (dataObj *sqlData)GetdataById(conn *dbConn)(*dataStore.Data, error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Data{}
+    if len(dataObj.Uid) == 0 {
+        log.Error("Cannot retrieve a record with empty Uid")
+        return nil,appErrors.INVALID_INPUT
+    }
+    err = sqlx.Select(conn, &rows, dataGetwthUid, dataObj.TreeId,
+                      dataObj.Uid)
+    if err != nil {
+        log.Error("Failed to get record with uid %s", dataObj.Uid)
+        return nil, err
+    }
+    if len(rows) != 1 {
+        log.Error("%d records only present in the system", len(rows))
+        return nil,appErrors.DATA_NOT_UNIQUE_ERROR
+    }
+    return &rows[0], nil
+}
+
+//Retrieve the root record of the tree.
+func (dataObj *sqlData)GetTreeRoot(conn *dbConn)(*dataStore.Data, error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Data{}
+    err = sqlx.Select(conn, &rows, dataGetTreeRoot, dataObj.TreeId)
+    if err != nil {
+        log.Error("Failed to get root record of tree %s", dataObj.TreeId)
+        return nil, err
+    }
+    if len(rows) != 1 {
+        log.Error("%d root records present in the tree %s", len(rows),
+                   dataObj.TreeId)
+        return nil,appErrors.INVALID_STATE
+    }
+    return &rows[0], nil
+}
+
+//Get the parent id for a record at the top level of the tree.
+func (dataObj *sqlData)getDefaultPuid(conn *dbConn)(string, error) {
+    rootData, err := dataObj.GetTreeRoot(conn)
+    if err != nil {
+        return "", err
+    }
+    return rootData.Uid, nil
+}
+
+//Update the computed fields of a record read from the table.
+func (dataObj *sqlData)updateRecordInfo(conn *dbConn,
+                                        rec *dataStore.Data) error {
+    err := sqlx.Get(conn, &rec.Depth, dataGetDepth, dataObj.TreeId,
+                    rec.LftId, rec.RgtId)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to get the depth of %s " +
+                                         "err : %s", rec.Uid, err)
+        return err
+    }
+    rec.UpdateSubtreeInfo()
+    return nil
+}
+
+//Retrieve a record with record UID along with its computed fields.
+func (dataObj *sqlData)GetdataInfoById(conn *dbConn)(*dataStore.Data, error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Data{}
+    if len(dataObj.Uid) == 0 {
+        log.Error("Cannot retrieve a record with empty Uid")
+        return nil,appErrors.INVALID_INPUT
+    }
+    err = sqlx.Select(conn, &rows, dataGetwthUid, dataObj.TreeId,
+                      dataObj.Uid)
+    if err != nil {
+        log.Error("Failed to get record with uid %s", dataObj.Uid)
+        return nil, err
+    }
+    if len(rows) != 1 {
+        log.Error("%d records only present in the system", len(rows))
+        return nil,appErrors.DATA_NOT_UNIQUE_ERROR
+    }
+    err = dataObj.updateRecordInfo(conn, &rows[0])
+    if err != nil {
+        return nil, err
+    }
+    return &rows[0], nil
+}
+
+//Retreive a record with specific name and parent ID.
+func (dataObj *sqlData)getDataWithName(conn *dbConn) ([]dataStore.Data,
+                                                            error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Data{}
+    if len(dataObj.Name) == 0 {
+        log.Error("Failed to get the record, name/puid is null")
+        return nil, appErrors.INVALID_INPUT
+    }
+    err = sqlx.Select(conn, &rows, dataGetwthName, dataObj.TreeId,
+                      dataObj.Name)
+    if err != nil {
+        log.Error("Failed to retereive the record with name %s",
+                    dataObj.Name)
+        return nil, err
+    }
+    for i := range rows {
+        err = dataObj.updateRecordInfo(conn, &rows[i])
+        if err != nil {
+            return nil, err
+        }
+    }
+    return rows, nil
+}
+
+//Retreive a record with specific name and parent ID.
+func (dataObj *sqlData)getDataWithNameAndPID(conn *dbConn) ([]dataStore.Data,
+                                                            error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Data{}
+    if len(dataObj.Name) == 0 {
+        log.Error("Failed to get the record, name/puid is null")
+        return nil, appErrors.INVALID_INPUT
+    }
+    err = sqlx.Select(conn, &rows, dataGetwthNamePID, dataObj.TreeId,
+                      dataObj.Name, dataObj.Puid)
+    if err != nil {
+        log.Error("Failed to retereive the record with name %s and pid %s",
+                    dataObj.Name, dataObj.Puid)
+        return nil, err
+    }
+    return rows, nil
+}
+
+// Update the NS limits with new values.
+func(dataObj *sqlData)updateIds(conn *dbConn) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    _, err = conn.Exec(dataUpdateTree, dataObj.LftId,
+                        dataObj.RgtId, dataObj.Uid)
+    if err != nil {
+        log.Error("Failed to update the limits err : %s", err)
+        return err
+    }
+    return nil
+}
+// Update the parent id of the record.
+func(dataObj *sqlData)updateParentId(conn *dbConn) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    _, err = conn.Exec(dataUpdateParent, dataObj.Puid, dataObj.Uid)
+    if err != nil {
+        log.Error("Failed to update the parent of %s err : %s",
+                   dataObj.Uid, err)
+        return err
+    }
+    return nil
+}
+
+//Create ROOT node for the tree. Root node is created along with the tree, the
+// Uid and TreeId of the record must be set by the caller.
+func(dataObj *sqlData)InsertRoot(conn *dbConn) error {
+    var err error
+    var rootdata *dataStore.Data
+    log := logger.GetLoggerInstance()
+    dataObj.Puid = ""
+    dataObj.Name = "root"
+    dataObj.Desc = "The default root node in the hierarchy"
+    dataObj.LftId = 1
+    dataObj.RgtId = dataObj.LftId + 1
+    //Check if data present before trying to insert them.
+    rootdata, err = dataObj.GetdataById(conn)
+    if rootdata != nil {
+        log.Trace("Root record already present in the system.")
+        return nil
+    }
+    err = dataObj.insertData(conn)
+    if err != nil && err != appErrors.DATA_PRESENT_IN_SYSTEM {
+        log.Error("Failed to insert the root node, err : %s", err)
+        return err
+    }
+    //Update the nestedset information for the root node.
+    err = dataObj.updateIds(conn)
+    if err != nil {
+        log.Error("Failed to update nestedSet ID for root node err: %s", err)
+        return err
+    }
+    return nil
+}
+
+func(dataObj *sqlData)insertData(conn *dbConn) error {
+    log := logger.GetLoggerInstance()
+    rows, err := dataObj.getDataWithNameAndPID(conn)
+    if err != nil {
+        log.Error("Failed to get the record from DB, err: %s", err)
+        return err
+    }
+    if len(rows) != 0 {
+        log.Info("Cannot insert data, Record already present in the system")
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    //TODO :: Update with proper UUID generator.
+    var uid string
+    uid, err = sys.NewUUIDString()
+    if err != nil {
+        log.Error("Failed to create UUID, cannot insert a an entry in DB")
+        return err
+    }
+    // Root node is created with its predefined Uid.
+    if dataObj.IsdataRoot() == false {
+        dataObj.Uid = uid
+    }
+    _, err = conn.Exec(dataCreate, dataObj.Uid, dataObj.TreeId, dataObj.Puid,
+                        dataObj.Name, dataObj.Desc, dataObj.Type,
+                        dataObj.Attributes)
+    if err != nil {
+        log.Error("Failed to insert data %s err %s", dataObj.Name,
+                    err);
+        return err
+    }
+    return nil
+}
+
+//Get the sibling record of 'before'/'after' insert position. The new record is
+// always created under the parent of its sibling.
+func(dataObj *sqlData)getInsertSibling(conn *dbConn) (*sqlData, error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    if len(dataObj.SiblingId) == 0 {
+        log.Error("Cannot insert %s record %s, sibling is not provided",
+                   dataObj.Position, dataObj.Name)
+        return nil, appErrors.INVALID_INPUT
+    }
+    siblingObj := dataObj.newTreeData(dataObj.SiblingId)
+    siblingObj.Data, err = siblingObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the sibling record %s err : %s",
+                   dataObj.SiblingId, err)
+        return nil, err
+    }
+    if siblingObj.IsdataRoot() == true {
+        log.Error("Cannot insert a record next to the root node")
+        return nil, appErrors.INVALID_INPUT
+    }
+    if len(dataObj.Puid) != 0 && dataObj.Puid != siblingObj.Puid {
+        log.Error("Sibling %s is not a child of parent %s",
+                   siblingObj.Uid, dataObj.Puid)
+        return nil, appErrors.INVALID_INPUT
+    }
+    return siblingObj, nil
+}
+
+func(dataObj *sqlData)InsertData(conn *dbConn) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    switch dataObj.Position {
+    case "", dataStore.POSITION_FIRST_CHILD, dataStore.POSITION_LAST_CHILD:
+    case dataStore.POSITION_BEFORE, dataStore.POSITION_AFTER:
+        var siblingObj *sqlData
+        siblingObj, err = dataObj.getInsertSibling(conn)
+        if err != nil {
+            return err
+        }
+        dataObj.Puid = siblingObj.Puid
+    default:
+        log.Error("Invalid position %s to insert the record %s",
+                   dataObj.Position, dataObj.Name)
+        return appErrors.INVALID_INPUT
+    }
+    if len(dataObj.Puid) == 0 {
+        //Wanted to insert the record at top level, use root as parent.
+        dataObj.Puid, err = dataObj.getDefaultPuid(conn)
+        if err != nil {
+            return err
+        }
+    }
+    parentType, err := dataObj.getTypeOf(conn, dataObj.Puid)
+    if err != nil {
+        return err
+    }
+    err = dataStore.CheckRecordType(dataObj.Data, parentType, nil)
+    if err != nil {
+        return err
+    }
+    err = dataObj.insertData(conn)
+    //While adding a new node, we must update the NS values.
+    if err == nil {
+        nsObj := NewSqlNestedSet(dataObj, conn)
+        err = nsObj.updateNSListLimitsOnAdd()
+    }
+    return err
+}
+
+func(dataObj *sqlData)DeleteData(conn *dbConn) error {
+    //Get all the record fields before delete.
+    var err error
+    log := logger.GetLoggerInstance()
+    dataObj.Data, err = dataObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete",
+                   dataObj.Uid)
+        return err
+    }    
+    err = dataObj.deleteDataOnId(conn)
+    if err == nil {
+        nsObj := NewSqlNestedSet(dataObj, conn)
+        err = nsObj.updateNSListLimitsOnDel()
+    }
+    return err
+}
+
+//Insert the records with their nested set limits, the gap for the records
+// must be present in the tree.
+func insertDataWithLimits(conn *dbConn, recs []dataStore.Data) error {
+    for _, rec := range recs {
+        _, err := conn.Exec(dataCreateWithLimits, rec.Uid, rec.TreeId,
+                            rec.Puid, rec.Name, rec.Desc, rec.Type,
+                            rec.Attributes, rec.LftId, rec.RgtId)
+        if err != nil {
+            logger.GetLoggerInstance().Error("Failed to insert record %s " +
+                                             "err : %s", rec.Uid, err)
+            return err
+        }
+    }
+    return nil
+}
+
+//Copy the record and all its childrens as the last child of 'destPuid', the
+// copies get new ids. The copy is renamed when the name is already present
+// under the parent. Returns the id of the copied record.
+func(dataObj *sqlData)CopyData(conn *dbConn, destPuid string) (string, error) {
+    log := logger.GetLoggerInstance()
+    rec, err := dataObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the record %s on copy, Cannot copy",
+                   dataObj.Uid)
+        return "", err
+    }
+    dataObj.Data = rec
+    rows, err := dataObj.GetAllChildrens(conn)
+    if err != nil {
+        return "", err
+    }
+    if len(destPuid) == 0 {
+        //Copying the record to top level, use root as parent.
+        destPuid, err = dataObj.getDefaultPuid(conn)
+        if err != nil {
+            return "", err
+        }
+    }
+    parentObj := dataObj.newTreeData(destPuid)
+    parentObj.Data, err = parentObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the parent %s on copy err : %s",
+                   destPuid, err)
+        return "", err
+    }
+    err = dataStore.CheckChildType(parentObj.Type, dataObj.Type)
+    if err != nil {
+        return "", err
+    }
+    name, err := dataStore.FindCopyName(dataObj.Name,
+                                        func(name string) (bool, error) {
+        nameObj := dataObj.newTreeData("")
+        nameObj.Name = name
+        nameObj.Puid = destPuid
+        present, err := nameObj.getDataWithNameAndPID(conn)
+        return len(present) != 0, err
+    })
+    if err != nil {
+        return "", err
+    }
+    pos := parentObj.RgtId
+    recs, err := dataStore.CopySubtreeRecords(
+                            append([]dataStore.Data{*dataObj.Data}, rows...),
+                            dataObj.TreeId, destPuid, name, pos)
+    if err != nil {
+        return "", err
+    }
+    nsObj := NewSqlNestedSet(dataObj, conn)
+    err = nsObj.shiftNSLimits(pos, 2 * int64(len(recs)))
+    if err != nil {
+        return "", err
+    }
+    err = insertDataWithLimits(conn, recs)
+    if err != nil {
+        return "", err
+    }
+    return recs[0].Uid, nil
+}
+
+//Delete a record only when it has no childrens.
+func(dataObj *sqlData)DeleteLeafData(conn *dbConn) error {
+    log := logger.GetLoggerInstance()
+    rec, err := dataObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete",
+                   dataObj.Uid)
+        return err
+    }
+    dataObj.Data = rec
+    if dataObj.RgtId - dataObj.LftId > 1 {
+        log.Error("Cannot delete record %s, it has childrens", dataObj.Uid)
+        return appErrors.INVALID_OP
+    }
+    err = dataObj.deleteDataOnId(conn)
+    if err == nil {
+        nsObj := NewSqlNestedSet(dataObj, conn)
+        err = nsObj.updateNSListLimitsOnDel()
+    }
+    return err
+}
+
+//Delete a record and move its childrens to its parent, in the place of the
+// record. Names of the childrens must be unique under the parent as well.
+func(dataObj *sqlData)PromoteData(conn *dbConn) error {
+    log := logger.GetLoggerInstance()
+    rec, err := dataObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete",
+                   dataObj.Uid)
+        return err
+    }
+    dataObj.Data = rec
+    childrens, err := dataObj.GetDirectChildrens(conn)
+    if err != nil {
+        return err
+    }
+    //Childrens must be allowed under the type of the parent as well.
+    parentType := ""
+    if dataObj.IsdataRoot() == false {
+        parentType, err = dataObj.getTypeOf(conn, dataObj.Puid)
+        if err != nil {
+            return err
+        }
+    }
+    for _, child := range childrens {
+        err = dataStore.CheckChildType(parentType, child.Type)
+        if err != nil {
+            return err
+        }
+        siblingObj := dataObj.newTreeData(child.Uid)
+        siblingObj.Name = child.Name
+        siblingObj.Puid = dataObj.Puid
+        rows, err := siblingObj.getDataWithNameAndPID(conn)
+        if err != nil {
+            return err
+        }
+        if len(rows) != 0 && rows[0].Uid != dataObj.Uid {
+            log.Info("Cannot delete %s, Record %s already present under %s",
+                      dataObj.Uid, child.Name, dataObj.Puid)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    err = dataObj.deleteDataOnId(conn)
+    if err == nil {
+        nsObj := NewSqlNestedSet(dataObj, conn)
+        err = nsObj.updateNSListLimitsOnPromote()
+    }
+    return err
+}
+
+//Move the record along with all its childrens under a new parent. The record
+// is added as the last child of the new parent.
+func(dataObj *sqlData)MoveData(conn *dbConn, newPuid string) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    dataObj.Data, err = dataObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the record %s on move, Cannot move",
+                   dataObj.Uid)
+        return err
+    }
+    if dataObj.IsdataRoot() == true {
+        log.Error("Cannot move root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    if len(newPuid) == 0 {
+        //Moving the record to top level, use root as parent.
+        newPuid, err = dataObj.getDefaultPuid(conn)
+        if err != nil {
+            return err
+        }
+    }
+    if newPuid == dataObj.Puid {
+        log.Trace("Record %s is already under %s, nothing to move",
+                   dataObj.Uid, newPuid)
+        return nil
+    }
+    parentObj := dataObj.newTreeData(newPuid)
+    parentObj.Data, err = parentObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the new parent %s on move err : %s",
+                   newPuid, err)
+        return err
+    }
+    err = dataStore.CheckChildType(parentObj.Type, dataObj.Type)
+    if err != nil {
+        return err
+    }
+    if parentObj.LftId >= dataObj.LftId && parentObj.LftId <= dataObj.RgtId {
+        log.Error("Cannot move record %s into its own subtree %s",
+                   dataObj.Uid, newPuid)
+        return appErrors.INVALID_OP
+    }
+    //Name must be unique under the new parent as well.
+    dataObj.Puid = newPuid
+    rows, err := dataObj.getDataWithNameAndPID(conn)
+    if err != nil {
+        log.Error("Failed to get the record from DB, err: %s", err)
+        return err
+    }
+    if len(rows) != 0 {
+        log.Info("Cannot move data, Record already present under %s",
+                  newPuid)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    err = dataObj.updateParentId(conn)
+    if err != nil {
+        return err
+    }
+    nsObj := NewSqlNestedSet(dataObj, conn)
+    return nsObj.updateNSListLimitsOnMove(parentObj)
+}
+
+//Update the name, description, type and attributes of the record. Name must be
+// unique under the parent of the record.
+func(dataObj *sqlData)UpdateData(conn *dbConn) error {
+    var err error
+    var recData *dataStore.Data
+    log := logger.GetLoggerInstance()
+    if len(dataObj.Name) == 0 {
+        log.Error("Cannot update the record %s with empty name", dataObj.Uid)
+        return appErrors.INVALID_INPUT
+    }
+    recObj := dataObj.newTreeData(dataObj.Uid)
+    recData, err = recObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the record %s on update, Cannot update",
+                   dataObj.Uid)
+        return err
+    }
+    recObj.Data = recData
+    if recObj.IsdataRoot() == true {
+        log.Error("Cannot update root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    //Only name, description, type and attributes can be updated, keep rest
+    // of the fields.
+    dataObj.Puid = recObj.Puid
+    dataObj.LftId = recObj.LftId
+    dataObj.RgtId = recObj.RgtId
+    rows, err := dataObj.getDataWithNameAndPID(conn)
+    if err != nil {
+        log.Error("Failed to get the record from DB, err: %s", err)
+        return err
+    }
+    for _, row := range rows {
+        if row.Uid != dataObj.Uid {
+            log.Info("Cannot update data, Record %s already present under %s",
+                      dataObj.Name, dataObj.Puid)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    childTypes, err := recObj.getChildTypes(conn)
+    if err != nil {
+        return err
+    }
+    parentType, err := dataObj.getTypeOf(conn, dataObj.Puid)
+    if err != nil {
+        return err
+    }
+    err = dataStore.CheckRecordType(dataObj.Data, parentType, childTypes)
+    if err != nil {
+        return err
+    }
+    _, err = conn.Exec(dataUpdateInfo, dataObj.Name, dataObj.Desc,
+                       dataObj.Type, dataObj.Attributes, dataObj.Uid)
+    if err != nil {
+        log.Error("Failed to update the record %s err : %s", dataObj.Uid, err)
+        return err
+    }
+    return nil
+}
+
+//Delete all the records in the tree including its root.
+func(dataObj *sqlData)DeleteTreeData(conn *dbConn) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    _, err = conn.Exec(dataDeleteTree, dataObj.TreeId)
+    if err != nil {
+        log.Error("Failed to delete records of tree %s err : %s",
+                   dataObj.TreeId, err)
+        return err
+    }
+    return nil
+}
\ No newline at end of file
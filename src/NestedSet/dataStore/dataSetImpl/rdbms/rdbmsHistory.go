@@ -0,0 +1,260 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rdbms
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/appErrors"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+)
+
+const (
+    SQL_HISTORY_TABLE_NAME = "historySet"
+    HISTORY_ID = "Id"
+    HISTORY_TREEID = "TreeId"
+    HISTORY_UID = "Uid"
+    HISTORY_SEQ = "Seq"
+    HISTORY_ACTION = "Action"
+    HISTORY_ACTOR = "Actor"
+    HISTORY_CHANGED_AT = "ChangedAt"
+    HISTORY_BEFORE = "Before"
+    HISTORY_AFTER = "After"
+)
+
+//Schema of the history table, records before and after the change are kept
+// as JSON.
+func historySchema(dialect Dialect) string {
+    return fmt.Sprintf(
+                 `CREATE TABLE IF NOT EXISTS "%s" ("%s" %s PRIMARY KEY,
+                 "%s" %s NOT NULL,
+                 "%s" %s NOT NULL,
+                 "%s" %s NOT NULL,
+                 "%s" %s NOT NULL,
+                 "%s" %s,
+                 "%s" %s NOT NULL,
+                 "%s" %s,
+                 "%s" %s)`,
+                 SQL_HISTORY_TABLE_NAME,
+                 HISTORY_ID, dialect.StringType(),
+                 HISTORY_TREEID, dialect.StringType(),
+                 HISTORY_UID, dialect.StringType(),
+                 HISTORY_SEQ, dialect.IntegerType(),
+                 HISTORY_ACTION, dialect.StringType(),
+                 HISTORY_ACTOR, dialect.StringType(),
+                 HISTORY_CHANGED_AT, dialect.StringType(),
+                 HISTORY_BEFORE, dialect.TextType(),
+                 HISTORY_AFTER, dialect.TextType())
+}
+
+var historyIndexes = []sqlIndex{
+    newSqlIndex(SQL_HISTORY_TABLE_NAME, HISTORY_TREEID, HISTORY_UID),
+    newSqlIndex(SQL_HISTORY_TABLE_NAME, HISTORY_CHANGED_AT),
+}
+
+var (
+    historyCreate = fmt.Sprintf(`INSERT INTO "%s"
+                                ("%s", "%s", "%s", "%s", "%s", "%s", "%s",
+                                 "%s", "%s")
+                                VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+                                SQL_HISTORY_TABLE_NAME,
+                                HISTORY_ID, HISTORY_TREEID, HISTORY_UID,
+                                HISTORY_SEQ, HISTORY_ACTION, HISTORY_ACTOR,
+                                HISTORY_CHANGED_AT, HISTORY_BEFORE,
+                                HISTORY_AFTER)
+    historyGetwthUid = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                                AND "%s"=(?) ORDER BY "%s", "%s", "%s"`,
+                                SQL_HISTORY_TABLE_NAME, HISTORY_TREEID,
+                                HISTORY_UID, HISTORY_CHANGED_AT, HISTORY_SEQ,
+                                HISTORY_ID)
+    historyGetSince = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s">=(?)
+                                ORDER BY "%s", "%s", "%s"`,
+                                SQL_HISTORY_TABLE_NAME, HISTORY_CHANGED_AT,
+                                HISTORY_CHANGED_AT, HISTORY_SEQ, HISTORY_ID)
+)
+
+//Row of the history table, the record images are null when they are not
+// present.
+type sqlHistory struct {
+    Id string               `db:"Id"`
+    TreeId string           `db:"TreeId"`
+    Uid string              `db:"Uid"`
+    Seq int                 `db:"Seq"`
+    Action string           `db:"Action"`
+    Actor string            `db:"Actor"`
+    ChangedAt string        `db:"ChangedAt"`
+    Before sql.NullString   `db:"Before"`
+    After sql.NullString    `db:"After"`
+}
+
+//Encode the record image as JSON, null when there is no image.
+func encodeRecordImage(image *dataStore.RecordImage) (sql.NullString, error) {
+    if image == nil {
+        return sql.NullString{}, nil
+    }
+    value, err := json.Marshal(image)
+    return sql.NullString{String: string(value), Valid: err == nil}, err
+}
+
+func decodeRecordImage(value sql.NullString) (*dataStore.RecordImage, error) {
+    if !value.Valid {
+        return nil, nil
+    }
+    image := new(dataStore.RecordImage)
+    err := json.Unmarshal([]byte(value.String), image)
+    if err != nil {
+        return nil, err
+    }
+    return image, nil
+}
+
+func (row *sqlHistory)toHistoryEntry() (*dataStore.HistoryEntry, error) {
+    var err error
+    entry := &dataStore.HistoryEntry{Id: row.Id, TreeId: row.TreeId,
+                                     Uid: row.Uid, Seq: row.Seq,
+                                     Action: row.Action, Actor: row.Actor,
+                                     ChangedAt: row.ChangedAt}
+    entry.Before, err = decodeRecordImage(row.Before)
+    if err == nil {
+        entry.After, err = decodeRecordImage(row.After)
+    }
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to decode the records of " +
+                                         "history %s err : %s", row.Id, err)
+        return nil, err
+    }
+    return entry, nil
+}
+
+func createHistoryTable(conn *dbConn) error {
+    _, err := conn.Exec(historySchema(conn.dialect))
+    if err != nil {
+        return err
+    }
+    return createIndexes(conn, historyIndexes)
+}
+
+func getHistory(conn *dbConn, query string,
+                args ...interface{}) ([]dataStore.HistoryEntry, error) {
+    rows := []sqlHistory{}
+    err := sqlx.Select(conn, &rows, query, args...)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to retereive the history " +
+                                         "err : %s", err)
+        return nil, err
+    }
+    entries := make([]dataStore.HistoryEntry, 0, len(rows))
+    for i := range rows {
+        entry, err := rows[i].toHistoryEntry()
+        if err != nil {
+            return nil, err
+        }
+        entries = append(entries, *entry)
+    }
+    return entries, nil
+}
+
+//Get the history of the record, it is present after the record is deleted.
+func(dataObj *sqlData)GetHistory(conn *dbConn) ([]dataStore.HistoryEntry,
+                                                error) {
+    return getHistory(conn, historyGetwthUid, dataObj.TreeId, dataObj.Uid)
+}
+
+//Get the history of all the trees from 'since'.
+func getAudit(conn *dbConn, since string) ([]dataStore.HistoryEntry, error) {
+    return getHistory(conn, historyGetSince, since)
+}
+
+//Add the changes from the records 'before' to the records 'after' the
+// operation to the history.
+func addHistory(conn *dbConn, change *dataStore.Change,
+                before []dataStore.Data, after []dataStore.Data) error {
+    log := logger.GetLoggerInstance()
+    entries, err := change.GetHistory(before, after)
+    if err != nil {
+        return err
+    }
+    for _, entry := range entries {
+        beforeImg, err := encodeRecordImage(entry.Before)
+        if err != nil {
+            return err
+        }
+        afterImg, err := encodeRecordImage(entry.After)
+        if err != nil {
+            return err
+        }
+        _, err = conn.Exec(historyCreate, entry.Id, entry.TreeId, entry.Uid,
+                           entry.Seq, entry.Action, entry.Actor,
+                           entry.ChangedAt, beforeImg, afterImg)
+        if err != nil {
+            log.Error("Failed to add the change of %s to the history " +
+                      "err : %s", entry.Uid, err)
+            return err
+        }
+    }
+    return nil
+}
+
+//Get the record 'uid' and all its childrens in pre-order.
+func(dataObj *sqlData)getSubtreeRecords(conn *dbConn,
+                                         uid string) ([]dataStore.Data, error) {
+    recObj := dataObj.newTreeData(uid)
+    rec, err := recObj.GetdataById(conn)
+    if err != nil {
+        return nil, err
+    }
+    recObj.Data = rec
+    rows, err := recObj.GetAllChildrens(conn)
+    if err != nil {
+        return nil, err
+    }
+    return append([]dataStore.Data{*rec}, rows...), nil
+}
+
+//Get the record 'uid' and its direct childrens.
+func(dataObj *sqlData)getChildRecords(conn *dbConn,
+                                       uid string) ([]dataStore.Data, error) {
+    recObj := dataObj.newTreeData(uid)
+    rec, err := recObj.GetdataById(conn)
+    if err != nil {
+        return nil, err
+    }
+    recObj.Data = rec
+    rows, err := recObj.GetDirectChildrens(conn)
+    if err != nil {
+        return nil, err
+    }
+    return append([]dataStore.Data{*rec}, rows...), nil
+}
+
+//Read the records again to get them after a change, the records that are not
+// present anymore are left out.
+func(dataObj *sqlData)reloadRecords(conn *dbConn,
+                            recs []dataStore.Data) ([]dataStore.Data, error) {
+    rows := []dataStore.Data{}
+    for _, rec := range recs {
+        row, err := dataObj.newTreeData(rec.Uid).GetdataById(conn)
+        if err == appErrors.DATA_NOT_UNIQUE_ERROR {
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        rows = append(rows, *row)
+    }
+    return rows, nil
+}
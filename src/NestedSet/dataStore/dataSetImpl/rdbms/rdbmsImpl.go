@@ -0,0 +1,709 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rdbms
+
+import (
+    "fmt"
+    "time"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/logger"
+    "NestedSet/dataStore"
+    "NestedSet/appErrors"
+)
+
+//Datastore implementation for the SQL databases. The queries are shared by
+// all the databases, the database specific parts are provided by the dialect.
+type RdbmsDataStore struct {
+    dblogger *logger.Logging
+    DBConn *sqlx.DB
+    dialect Dialect
+}
+
+
+//Create a sql connection and store in the datastore object.
+// Return '0' on success and errorcode otherwise.
+// It is advised to make single handle in entire application as every handle
+// uses a connection pool to manage multiple DB requests.
+func (sqlds *RdbmsDataStore)CreateDBConnection(
+                                dataSource string) error{
+    dbHandle, err := sqlds.dialect.Connect(dataSource)
+    if err != nil {
+        sqlds.dblogger.Error("Failed to connect %s DB %s",
+                             sqlds.dialect.Name(), err.Error())
+        return err
+    }
+    sqlds.DBConn = dbHandle
+    sqlds.dblogger.Trace("Created %s DB connection", sqlds.dialect.Name())
+    return nil
+}
+
+//Create all the tables for the application.
+func (sqlds *RdbmsDataStore)CreateDataStoreTables() error {
+    var err error
+    if sqlds.DBConn == nil {
+        return fmt.Errorf("Null DB connection, cannot create tables")
+    }
+    //Tables are created and upgraded by the schema migrations.
+    err = sqlds.ApplyMigrations()
+    if err != nil {
+        return err
+    }
+    treeObj := new(sqlTree)
+    treeObj.Tree = new(dataStore.Tree) //Must allocate internal pointer too.
+    //Create the default tree and its root node if not exisits.
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        return treeObj.InsertDefaultTree(conn)
+    })
+}
+
+//Run a tree update in a single transaction. The record change and all the
+// nested set updates are either committed together or rolled back on error.
+func (sqlds *RdbmsDataStore)runInTransaction(
+                                updateFn func(conn *dbConn) error) error {
+    tx, err := sqlds.DBConn.Beginx()
+    if err != nil {
+        sqlds.dblogger.Error("Failed to start the transaction, err : %s", err)
+        return err
+    }
+    err = updateFn(sqlds.newDBConn(tx))
+    if err != nil {
+        sqlds.dblogger.Error("Rolling back the transaction, err : %s", err)
+        rollbackErr := tx.Rollback()
+        if rollbackErr != nil {
+            sqlds.dblogger.Error("Failed to roll back the transaction, " +
+                                 "err : %s", rollbackErr)
+        }
+        return err
+    }
+    err = tx.Commit()
+    if err != nil {
+        sqlds.dblogger.Error("Failed to commit the transaction, err : %s", err)
+        return err
+    }
+    return nil
+}
+
+func (sqlds *RdbmsDataStore)CreateTree(tree *dataStore.Tree) error {
+    sqlTreeObj := new(sqlTree)
+    sqlTreeObj.Tree = tree
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        return sqlTreeObj.InsertTree(conn)
+    })
+}
+
+func (sqlds *RdbmsDataStore)DeleteTree(treeId string) error {
+    sqlTreeObj := new(sqlTree)
+    sqlTreeObj.Tree = new(dataStore.Tree)
+    sqlTreeObj.TreeId = treeId
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        return sqlTreeObj.DeleteTree(conn)
+    })
+}
+
+func (sqlds *RdbmsDataStore)GetTree(treeId string) (*dataStore.Tree, error) {
+    sqlTreeObj := new(sqlTree)
+    sqlTreeObj.Tree = new(dataStore.Tree)
+    sqlTreeObj.TreeId = treeId
+    return sqlTreeObj.GetTreeById(sqlds.newDBConn(sqlds.DBConn))
+}
+
+func (sqlds *RdbmsDataStore)GetAllTrees() ([]dataStore.Tree, error) {
+    sqlTreeObj := new(sqlTree)
+    sqlTreeObj.Tree = new(dataStore.Tree)
+    return sqlTreeObj.GetAllTrees(sqlds.newDBConn(sqlds.DBConn))
+}
+
+func (sqlds *RdbmsDataStore)VerifyTree(treeId string) (*dataStore.TreeReport,
+                                                         error) {
+    var report *dataStore.TreeReport
+    sqlTreeObj := new(sqlTree)
+    sqlTreeObj.Tree = new(dataStore.Tree)
+    sqlTreeObj.TreeId = treeId
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        var err error
+        report, err = sqlTreeObj.VerifyTree(conn)
+        return err
+    })
+    return report, err
+}
+
+func (sqlds *RdbmsDataStore)RebuildTree(treeId string) error {
+    sqlTreeObj := new(sqlTree)
+    sqlTreeObj.Tree = new(dataStore.Tree)
+    sqlTreeObj.TreeId = treeId
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        return sqlTreeObj.RebuildTree(conn)
+    })
+}
+
+func (sqlds *RdbmsDataStore)CreateRecord(treeId string, rec *dataStore.Data,
+                                          actor string) error {
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = rec
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, true)
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.InsertData(conn)
+        if err != nil {
+            return err
+        }
+        after, err := sqlDataObj.reloadRecords(conn, []dataStore.Data{*rec})
+        if err != nil {
+            return err
+        }
+        return sqlDataObj.addChange(conn, dataStore.NewChange(actor), nil,
+                                    after)
+    })
+}
+
+//Run the delete of the record in a transaction, the record and all its
+// childrens before the delete are recorded as removed.
+func (sqlds *RdbmsDataStore)runDelete(treeId string, recid string,
+                        version int64, change *dataStore.Change,
+                        deleteFn func(*sqlData, *dbConn) error) error {
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = recid
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, true)
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.checkVersion(conn, recid, version, true)
+        if err != nil {
+            return err
+        }
+        before, err := sqlDataObj.getSubtreeRecords(conn, recid)
+        if err != nil {
+            return err
+        }
+        err = deleteFn(sqlDataObj, conn)
+        if err != nil {
+            return err
+        }
+        return sqlDataObj.addChange(conn, change, before, nil)
+    })
+}
+
+func (sqlds *RdbmsDataStore)DeleteRecord(treeId string, recid string,
+                                          version int64, actor string) error {
+    return sqlds.runDelete(treeId, recid, version, dataStore.NewChange(actor),
+                           (*sqlData).DeleteData)
+}
+
+func (sqlds *RdbmsDataStore)DeleteLeafRecord(treeId string, recid string,
+                                              version int64,
+                                              actor string) error {
+    return sqlds.runDelete(treeId, recid, version, dataStore.NewChange(actor),
+                           (*sqlData).DeleteLeafData)
+}
+
+func (sqlds *RdbmsDataStore)PromoteRecord(treeId string, recid string,
+                                           version int64, actor string) error {
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = recid
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, true)
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.checkVersion(conn, recid, version, true)
+        if err != nil {
+            return err
+        }
+        before, err := sqlDataObj.getChildRecords(conn, recid)
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.PromoteData(conn)
+        if err != nil {
+            return err
+        }
+        after, err := sqlDataObj.reloadRecords(conn, before)
+        if err != nil {
+            return err
+        }
+        return sqlDataObj.addChange(conn, dataStore.NewChange(actor), before,
+                                    after)
+    })
+}
+
+func (sqlds *RdbmsDataStore)UpdateRecord(treeId string, rec *dataStore.Data,
+                                          version int64, actor string) error {
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = rec
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, true)
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.checkVersion(conn, rec.Uid, version, false)
+        if err != nil {
+            return err
+        }
+        before, err := sqlDataObj.reloadRecords(conn,
+                                                []dataStore.Data{*rec})
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.UpdateData(conn)
+        if err != nil {
+            return err
+        }
+        after, err := sqlDataObj.reloadRecords(conn, before)
+        if err != nil {
+            return err
+        }
+        return sqlDataObj.addChange(conn, dataStore.NewChange(actor), before,
+                                    after)
+    })
+}
+
+func (sqlds *RdbmsDataStore)MoveRecord(treeId string, recid string,
+                                        newPuid string, version int64,
+                                        actor string) error {
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = recid
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, true)
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.checkVersion(conn, recid, version, true)
+        if err != nil {
+            return err
+        }
+        before, err := sqlDataObj.reloadRecords(conn,
+                                    []dataStore.Data{{Uid: recid}})
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.MoveData(conn, newPuid)
+        if err != nil {
+            return err
+        }
+        after, err := sqlDataObj.reloadRecords(conn, before)
+        if err != nil {
+            return err
+        }
+        return sqlDataObj.addChange(conn, dataStore.NewChange(actor), before,
+                                    after)
+    })
+}
+
+func (sqlds *RdbmsDataStore)CopySubtree(treeId string, srcId string,
+                                        destPuid string,
+                                        actor string) (*dataStore.Data,
+                                                       error) {
+    var row *dataStore.Data
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = srcId
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, true)
+        if err != nil {
+            return err
+        }
+        uid, err := sqlDataObj.CopyData(conn, destPuid)
+        if err != nil {
+            return err
+        }
+        after, err := sqlDataObj.getSubtreeRecords(conn, uid)
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.addChange(conn, dataStore.NewChange(actor), nil,
+                                   after)
+        if err != nil {
+            return err
+        }
+        row, err = sqlDataObj.newTreeData(uid).GetdataInfoById(conn)
+        return err
+    })
+    return row, err
+}
+
+func (sqlds *RdbmsDataStore)GetRecord(treeId string,
+                                       recid string) (*dataStore.Data, error) {
+    var row *dataStore.Data
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = recid
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        row, err = sqlDataObj.getRecordWithVersion(conn, recid)
+        return err
+    })
+    return row, err
+}
+
+func (sqlds *RdbmsDataStore)GetAncestors(treeId string,
+                                          recid string) ([]dataStore.Data,
+                                                         error) {
+    var rows []dataStore.Data
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = recid
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        sqlDataObj.Data, err = sqlDataObj.GetdataById(conn)
+        if err != nil {
+            return err
+        }
+        rows, err = sqlDataObj.GetAllAncestors(conn)
+        return err
+    })
+    return rows, err
+}
+
+func (sqlds *RdbmsDataStore)GetDescendants(treeId string, recid string,
+                                            maxDepth int) ([]dataStore.Data,
+                                                            error) {
+    var rows []dataStore.Data
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = recid
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        sqlDataObj.Data, err = sqlDataObj.GetdataInfoById(conn)
+        if err != nil {
+            return err
+        }
+        rows, err = sqlDataObj.GetAllChildrens(conn)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    if maxDepth > 0 {
+        rows = dataStore.FilterByDepth(rows,
+                                       sqlDataObj.Depth + int64(maxDepth))
+    }
+    return rows, nil
+}
+
+func (sqlds *RdbmsDataStore)GetChildren(treeId string,
+                                         recid string) ([]dataStore.Data,
+                                                        error) {
+    var rows []dataStore.Data
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = recid
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        sqlDataObj.Data, err = sqlDataObj.GetdataInfoById(conn)
+        if err != nil {
+            return err
+        }
+        rows, err = sqlDataObj.GetDirectChildrens(conn)
+        return err
+    })
+    return rows, err
+}
+
+func (sqlds *RdbmsDataStore)GetRecordByName(treeId string,
+                                             name string)([]dataStore.Data,
+                                                          error) {
+    var rows []dataStore.Data
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Name = name
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        rows, err = sqlDataObj.getDataWithName(conn)
+        return err
+    })
+    return rows, err
+}
+
+func (sqlds *RdbmsDataStore)GetAllRecords(treeId string)([]dataStore.Data,
+                                                          error) {
+    var rows []dataStore.Data
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        rows, err = sqlDataObj.GetAllRecords(conn)
+        return err
+    })
+    return rows, err
+}
+
+func (sqlds *RdbmsDataStore)GetRecordsPage(treeId string,
+                        request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                         error) {
+    var page *dataStore.RecordPage
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        page, err = sqlDataObj.getPage(conn, request)
+        return err
+    })
+    return page, err
+}
+
+func (sqlds *RdbmsDataStore)GetRecordByNamePage(treeId string, name string,
+                        request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                         error) {
+    var page *dataStore.RecordPage
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Name = name
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        page, err = sqlDataObj.getPageWithName(conn, request)
+        return err
+    })
+    return page, err
+}
+
+func (sqlds *RdbmsDataStore)TrashRecord(treeId string, recid string,
+                                         version int64, actor string) error {
+    change := dataStore.NewChange(actor)
+    change.RemoveAction = dataStore.HISTORY_TRASH
+    return sqlds.runDelete(treeId, recid, version, change,
+                           (*sqlData).TrashData)
+}
+
+func (sqlds *RdbmsDataStore)GetTrash(treeId string) ([]dataStore.TrashEntry,
+                                                     error) {
+    var entries []dataStore.TrashEntry
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        entries, err = sqlDataObj.GetTrash(conn)
+        return err
+    })
+    return entries, err
+}
+
+func (sqlds *RdbmsDataStore)RestoreRecord(treeId string, recid string,
+                                          puid string, actor string) error {
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = recid
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, true)
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.RestoreData(conn, puid)
+        if err != nil {
+            return err
+        }
+        after, err := sqlDataObj.getSubtreeRecords(conn, recid)
+        if err != nil {
+            return err
+        }
+        change := dataStore.NewChange(actor)
+        change.AddAction = dataStore.HISTORY_RESTORE
+        return sqlDataObj.addChange(conn, change, nil, after)
+    })
+}
+
+//Trash of all the trees is purged with all the trees locked, so that no
+// restore of the trees is in progress.
+func (sqlds *RdbmsDataStore)PurgeTrash(before time.Time) (int, error) {
+    var count int
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := lockAllTrees(conn, true)
+        if err != nil {
+            return err
+        }
+        count, err = purgeTrash(conn, before)
+        return err
+    })
+    return count, err
+}
+
+//History is kept after the tree is deleted, so the tree is locked only when
+// its present.
+func (sqlds *RdbmsDataStore)GetRecordHistory(treeId string,
+                            recid string) ([]dataStore.HistoryEntry, error) {
+    var entries []dataStore.HistoryEntry
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    sqlDataObj.Uid = recid
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil && err != appErrors.DATA_NOT_FOUND {
+            return err
+        }
+        sqlDataObj.TreeId = treeId
+        entries, err = sqlDataObj.GetHistory(conn)
+        return err
+    })
+    return entries, err
+}
+
+func (sqlds *RdbmsDataStore)GetAudit(
+                        since time.Time) ([]dataStore.HistoryEntry, error) {
+    var entries []dataStore.HistoryEntry
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := lockAllTrees(conn, false)
+        if err != nil {
+            return err
+        }
+        entries, err = getAudit(conn, dataStore.HistoryTimeString(since))
+        return err
+    })
+    return entries, err
+}
+
+func (sqlds *RdbmsDataStore)CreateSnapshot(treeId string,
+                                        snapshot *dataStore.Snapshot) error {
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        return sqlDataObj.CreateSnapshot(conn, snapshot)
+    })
+}
+
+func (sqlds *RdbmsDataStore)DeleteSnapshot(treeId string,
+                                           snapshotId string) error {
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        return sqlDataObj.DeleteSnapshot(conn, snapshotId)
+    })
+}
+
+func (sqlds *RdbmsDataStore)GetSnapshot(treeId string,
+                            snapshotId string) (*dataStore.Snapshot, error) {
+    var snapshot *dataStore.Snapshot
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        snapshot, err = sqlDataObj.GetSnapshot(conn, snapshotId)
+        return err
+    })
+    return snapshot, err
+}
+
+func (sqlds *RdbmsDataStore)GetAllSnapshots(
+                            treeId string) ([]dataStore.Snapshot, error) {
+    var snapshots []dataStore.Snapshot
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        snapshots, err = sqlDataObj.GetAllSnapshots(conn)
+        return err
+    })
+    return snapshots, err
+}
+
+func (sqlds *RdbmsDataStore)GetSnapshotRecords(treeId string,
+                                    snapshotId string,
+                                    recid string) ([]dataStore.Data, error) {
+    var rows []dataStore.Data
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    err := sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, false)
+        if err != nil {
+            return err
+        }
+        rows, err = sqlDataObj.GetSnapshotRecords(conn, snapshotId, recid)
+        return err
+    })
+    return rows, err
+}
+
+//All the records of the tree before and after the restore are compared for
+// the history.
+func (sqlds *RdbmsDataStore)RestoreSnapshot(treeId string,
+                                            snapshotId string, recid string,
+                                            actor string) error {
+    sqlDataObj := new(sqlData)
+    sqlDataObj.Data = new(dataStore.Data)
+    return sqlds.runInTransaction(func(conn *dbConn) error {
+        err := sqlDataObj.setTree(conn, treeId, true)
+        if err != nil {
+            return err
+        }
+        before, err := sqlDataObj.GetAllRecords(conn)
+        if err != nil {
+            return err
+        }
+        err = sqlDataObj.RestoreSnapshot(conn, snapshotId, recid)
+        if err != nil {
+            return err
+        }
+        after, err := sqlDataObj.GetAllRecords(conn)
+        if err != nil {
+            return err
+        }
+        change := dataStore.NewChange(actor)
+        change.AddAction = dataStore.HISTORY_RESTORE
+        change.RemoveAction = dataStore.HISTORY_TRASH
+        return sqlDataObj.addChange(conn, change, before, after)
+    })
+}
+
+//Create a datastore for the database 'dialect'.
+func NewRdbmsDataStore(dialect Dialect) *RdbmsDataStore {
+    sqlds := new(RdbmsDataStore)
+    sqlds.dblogger = logger.GetLoggerInstance()
+    sqlds.dialect = dialect
+    return sqlds
+}
@@ -0,0 +1,341 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdbms
+
+import (
+    "fmt"
+    "time"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+)
+
+const (
+    SQL_SCHEMA_TABLE_NAME = "schemaVersion"
+    SCHEMA_VERSION = "Version"
+    SCHEMA_DESC = "Desc"
+    SCHEMA_APPLIED_AT = "AppliedAt"
+    //Version of the databases created before the schema migrations, they
+    // have the trees and the nested set limit indexes.
+    SCHEMA_TREE_VERSION = 3
+)
+
+//Schema of the table that keeps the applied migrations.
+func schemaVersionSchema(dialect Dialect) string {
+    return fmt.Sprintf(
+                 `CREATE TABLE IF NOT EXISTS "%s" ("%s" %s PRIMARY KEY,
+                 "%s" %s,
+                 "%s" %s)`,
+                 SQL_SCHEMA_TABLE_NAME,
+                 SCHEMA_VERSION, dialect.IntegerType(),
+                 SCHEMA_DESC, dialect.TextType(),
+                 SCHEMA_APPLIED_AT, dialect.StringType())
+}
+
+var (
+    schemaVersionCreate = fmt.Sprintf(`INSERT INTO "%s" ("%s", "%s", "%s")
+                                VALUES (?, ?, ?)`,
+                                SQL_SCHEMA_TABLE_NAME, SCHEMA_VERSION,
+                                SCHEMA_DESC, SCHEMA_APPLIED_AT)
+    schemaVersionGetAll = fmt.Sprintf(`SELECT * FROM "%s" ORDER BY "%s"`,
+                                SQL_SCHEMA_TABLE_NAME, SCHEMA_VERSION)
+)
+
+//Records of the databases created before the trees belong to the default
+// tree.
+func dataAddTreeId(dialect Dialect) string {
+    return fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s NOT NULL
+                       DEFAULT '%s'`,
+                       SQL_DATA_TABLE_NAME, DATA_TREEID,
+                       dialect.StringType(), dataStore.DEFAULT_TREE_ID)
+}
+
+//Free-form attributes of the records as JSON text, NULL when the record has
+// no attributes.
+func dataAddAttributes(dialect Dialect) string {
+    return fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s`,
+                       SQL_DATA_TABLE_NAME, DATA_ATTRIBUTES,
+                       dialect.TextType())
+}
+
+//Type of the records, records created before the types have no type.
+func dataAddType(dialect Dialect) string {
+    return fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s NOT NULL
+                       DEFAULT ''`,
+                       SQL_DATA_TABLE_NAME, DATA_TYPE, dialect.StringType())
+}
+
+//Versions of the trees and the records, the ones created before the versions
+// start at '0'.
+func addColumnVersion(dialect Dialect, table string, column string) string {
+    return fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s NOT NULL
+                       DEFAULT 0`,
+                       table, column, dialect.IntegerType())
+}
+
+//Migration of the schema from previous version. Migrations are applied in
+// the order of version, each one in its own transaction along with its
+// version entry. MySQL commits the transaction on every schema change, so a
+// migration that failed midway is left partly applied without its version
+// entry. Every step of a migration checks its change is not present, so the
+// migration is applied again over the partial changes.
+type sqlMigration struct {
+    version int
+    desc string
+    upFn func(conn *dbConn) error
+}
+
+//All the migrations of the schema, new migrations are appended with the next
+// version. Applied migrations must not be changed.
+var sqlMigrations = []sqlMigration{
+    {1, "Create the data table", createDataTable},
+    {2, "Add the tree table and the tree of the records", addTrees},
+    {3, "Index the nested set limits of the records", indexNSLimits},
+    {4, "Index the parent and name of the records", indexParentName},
+    {5, "Add the trash table", createTrashTable},
+    {6, "Add the attributes of the records", addAttributes},
+    {7, "Add the type of the records", addType},
+    {8, "Add the history table", createHistoryTable},
+    {9, "Add the snapshot tables", createSnapshotTables},
+    {10, "Add the versions of the trees and the records", addVersions},
+}
+
+func createDataTable(conn *dbConn) error {
+    _, err := conn.Exec(dataSchema(conn.dialect))
+    return err
+}
+
+//Add the column to the table with the 'alterQuery', only when the column is
+// not present.
+func addColumn(conn *dbConn, table string, column string,
+               alterQuery string) error {
+    columns, err := getTableColumns(conn, table)
+    if err != nil {
+        return err
+    }
+    for _, name := range columns {
+        if name == column {
+            logger.GetLoggerInstance().Info("Column %s is already present " +
+                                            "in %s", column, table)
+            return nil
+        }
+    }
+    _, err = conn.Exec(alterQuery)
+    return err
+}
+
+func addAttributes(conn *dbConn) error {
+    return addColumn(conn, SQL_DATA_TABLE_NAME, DATA_ATTRIBUTES,
+                     dataAddAttributes(conn.dialect))
+}
+
+func addType(conn *dbConn) error {
+    return addColumn(conn, SQL_DATA_TABLE_NAME, DATA_TYPE,
+                     dataAddType(conn.dialect))
+}
+
+func addVersions(conn *dbConn) error {
+    columns := [][]string{
+        {SQL_TREE_TABLE_NAME, TREE_VERSION},
+        {SQL_DATA_TABLE_NAME, DATA_VERSION},
+        {SQL_DATA_TABLE_NAME, DATA_CHILD_VERSION},
+    }
+    for _, column := range columns {
+        err := addColumn(conn, column[0], column[1],
+                         addColumnVersion(conn.dialect, column[0], column[1]))
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+//Every record belongs to a tree. Records that are already present are in the
+// default tree, which is created here when the data table has its root.
+func addTrees(conn *dbConn) error {
+    err := addColumn(conn, SQL_DATA_TABLE_NAME, DATA_TREEID,
+                     dataAddTreeId(conn.dialect))
+    if err != nil {
+        return err
+    }
+    _, err = conn.Exec(treeSchema(conn.dialect))
+    if err != nil {
+        return err
+    }
+    rows := []dataStore.Data{}
+    err = sqlx.Select(conn, &rows, dataGetwthUid, dataStore.DEFAULT_TREE_ID,
+                      dataStore.ROOT_UID)
+    if err != nil || len(rows) == 0 {
+        return err
+    }
+    trees := []dataStore.Tree{}
+    err = sqlx.Select(conn, &trees, treeGetwthId, dataStore.DEFAULT_TREE_ID)
+    if err != nil || len(trees) != 0 {
+        return err
+    }
+    _, err = conn.Exec(treeCreate, dataStore.DEFAULT_TREE_ID,
+                       dataStore.DEFAULT_TREE_ID,
+                       "The default tree in the system", dataStore.ROOT_UID)
+    return err
+}
+
+func createIndexes(conn *dbConn, indexes []sqlIndex) error {
+    for _, index := range indexes {
+        err := index.createIndex(conn)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func indexNSLimits(conn *dbConn) error {
+    return createIndexes(conn, dataNSIndexes)
+}
+
+func indexParentName(conn *dbConn) error {
+    return createIndexes(conn, dataInfoIndexes)
+}
+
+//Get the columns of a table, fails when the table is not present.
+func getTableColumns(conn *dbConn, table string) ([]string, error) {
+    rows, err := conn.Query(fmt.Sprintf(`SELECT * FROM "%s" WHERE 1=0`,
+                                        table))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    return rows.Columns()
+}
+
+//Find the schema version of a database created before the migrations from
+// the data table, '0' when there is no data table.
+func getLegacySchemaVersion(conn *dbConn) int {
+    columns, err := getTableColumns(conn, SQL_DATA_TABLE_NAME)
+    if err != nil {
+        return 0
+    }
+    for _, column := range columns {
+        if column == DATA_TREEID {
+            return SCHEMA_TREE_VERSION
+        }
+    }
+    return 1
+}
+
+//Get the applied migrations, or the versions of the legacy schema when the
+// version table is not present. The version table is created only when
+// 'create' is set.
+func (sqlds *RdbmsDataStore)getAppliedMigrations(
+                        create bool) ([]dataStore.Migration, error) {
+    var err error
+    conn := sqlds.newDBConn(sqlds.DBConn)
+    rows := []dataStore.Migration{}
+    _, err = getTableColumns(conn, SQL_SCHEMA_TABLE_NAME)
+    if err == nil {
+        err = sqlx.Select(conn, &rows, schemaVersionGetAll)
+        if err != nil {
+            sqlds.dblogger.Error("Failed to retereive the schema version " +
+                                 "err : %s", err)
+            return nil, err
+        }
+        return rows, nil
+    }
+    legacyVersion := getLegacySchemaVersion(conn)
+    appliedAt := time.Now().UTC().Format(time.RFC3339)
+    for _, migration := range sqlMigrations[:legacyVersion] {
+        rows = append(rows, dataStore.Migration{
+                                Version: migration.version,
+                                Desc: migration.desc,
+                                AppliedAt: appliedAt})
+    }
+    if create == false {
+        return rows, nil
+    }
+    err = sqlds.runInTransaction(func(conn *dbConn) error {
+        _, err := conn.Exec(schemaVersionSchema(conn.dialect))
+        if err != nil {
+            return err
+        }
+        for _, row := range rows {
+            _, err = conn.Exec(schemaVersionCreate, row.Version, row.Desc,
+                               row.AppliedAt)
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        sqlds.dblogger.Error("Failed to create the schema version table " +
+                             "err : %s", err)
+        return nil, err
+    }
+    if legacyVersion != 0 {
+        sqlds.dblogger.Info("Found %s DB at schema version %d",
+                            sqlds.dialect.Name(), legacyVersion)
+    }
+    return rows, nil
+}
+
+//Get all the migrations of the schema, pending ones have no applied time.
+func (sqlds *RdbmsDataStore)GetMigrations() ([]dataStore.Migration, error) {
+    applied, err := sqlds.getAppliedMigrations(false)
+    if err != nil {
+        return nil, err
+    }
+    migrations := applied
+    for _, migration := range sqlMigrations[len(applied):] {
+        migrations = append(migrations, dataStore.Migration{
+                                Version: migration.version,
+                                Desc: migration.desc})
+    }
+    return migrations, nil
+}
+
+//Apply all the pending migrations in order, stops at the first failure.
+func (sqlds *RdbmsDataStore)ApplyMigrations() error {
+    log := logger.GetLoggerInstance()
+    applied, err := sqlds.getAppliedMigrations(true)
+    if err != nil {
+        return err
+    }
+    if len(applied) > len(sqlMigrations) {
+        log.Error("Schema version %d of the DB is newer than the " +
+                  "application", applied[len(applied) - 1].Version)
+        return fmt.Errorf("Unknown schema version %d",
+                          applied[len(applied) - 1].Version)
+    }
+    for _, migration := range sqlMigrations[len(applied):] {
+        err = sqlds.runInTransaction(func(conn *dbConn) error {
+            err := migration.upFn(conn)
+            if err != nil {
+                return err
+            }
+            _, err = conn.Exec(schemaVersionCreate, migration.version,
+                               migration.desc,
+                               time.Now().UTC().Format(time.RFC3339))
+            return err
+        })
+        if err != nil {
+            log.Error("Failed to apply schema migration %d err : %s",
+                      migration.version, err)
+            return err
+        }
+        log.Info("Applied schema migration %d, %s", migration.version,
+                 migration.desc)
+    }
+    return nil
+}
@@ -0,0 +1,295 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdbms
+
+import (
+    "database/sql"
+    "fmt"
+    "NestedSet/appErrors"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+)
+
+var (
+    // Open/close a gap in the nested set limits. The limits of every record
+    // at or after the position are shifted by the given value.
+    // Nested set limits are maintained per tree.
+    nsShiftLftIds = fmt.Sprintf(`UPDATE "%s" SET "%s"="%s"+(?)
+                                WHERE "%s"=(?) AND "%s">=(?)`,
+                                SQL_DATA_TABLE_NAME,
+                                DATA_LFTID, DATA_LFTID, DATA_TREEID,
+                                DATA_LFTID)
+    nsShiftRgtIds = fmt.Sprintf(`UPDATE "%s" SET "%s"="%s"+(?)
+                                WHERE "%s"=(?) AND "%s">=(?)`,
+                                SQL_DATA_TABLE_NAME,
+                                DATA_RGTID, DATA_RGTID, DATA_TREEID,
+                                DATA_RGTID)
+    // Delete all the childrens of a record in one go.
+    nsDeleteChildrens = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?) AND
+                                "%s">(?) AND "%s"<(?)`,
+                                SQL_DATA_TABLE_NAME, DATA_TREEID,
+                                DATA_LFTID, DATA_LFTID)
+    // Move the childrens of a record one level up, to the place of the
+    // record in its parent.
+    nsPromoteChildrens = fmt.Sprintf(`UPDATE "%s" SET "%s"="%s"-1,
+                                "%s"="%s"-1 WHERE "%s"=(?) AND
+                                "%s">(?) AND "%s"<(?)`,
+                                SQL_DATA_TABLE_NAME,
+                                DATA_LFTID, DATA_LFTID,
+                                DATA_RGTID, DATA_RGTID, DATA_TREEID,
+                                DATA_LFTID, DATA_LFTID)
+    nsPromoteParent = fmt.Sprintf(`UPDATE "%s" SET "%s"=(?) WHERE "%s"=(?)
+                                AND "%s"=(?)`,
+                                SQL_DATA_TABLE_NAME, PARENT_UID,
+                                DATA_TREEID, PARENT_UID)
+    // Move a subtree and shift the records in between the old and new
+    // position of the subtree, in a single statement. The limits of the
+    // subtree are shifted by first value and the gap by the second one.
+    nsMoveSubtree = fmt.Sprintf(`UPDATE "%s" SET
+                    "%s" = CASE WHEN "%s" BETWEEN (?) AND (?) THEN "%s"+(?)
+                              WHEN "%s" BETWEEN (?) AND (?) THEN "%s"+(?)
+                              ELSE "%s" END,
+                    "%s" = CASE WHEN "%s" BETWEEN (?) AND (?) THEN "%s"+(?)
+                              WHEN "%s" BETWEEN (?) AND (?) THEN "%s"+(?)
+                              ELSE "%s" END
+                    WHERE "%s"=(?) AND "%s">=(?) AND "%s"<=(?)`,
+                    SQL_DATA_TABLE_NAME,
+                    DATA_LFTID, DATA_LFTID, DATA_LFTID,
+                    DATA_LFTID, DATA_LFTID, DATA_LFTID,
+                    DATA_RGTID, DATA_RGTID, DATA_RGTID,
+                    DATA_RGTID, DATA_RGTID, DATA_RGTID,
+                    DATA_TREEID, DATA_RGTID, DATA_LFTID)
+)
+
+//Structure to perform nested set data update for record add/delete/move.
+// All the updates are executed on the connection handle of the operation, so
+// when its a transaction the record and its nested set limits are committed or
+// rolled back together.
+type sqlNSOP struct {
+    dataObj *sqlData
+    conn *dbConn
+}
+
+//Shift the nested set limits of all the records at or after the position
+// 'pos' by 'updateVal'. Positive value opens a gap for new records and negative
+// value closes the gap left by deleted records.
+func (nsOp *sqlNSOP)shiftNSLimits(pos int64, updateVal int64) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    _, err = nsOp.conn.Exec(nsShiftRgtIds, updateVal, nsOp.dataObj.TreeId,
+                            pos)
+    if err != nil {
+        log.Error("Failed to shift the right limits from %d err : %s",
+                   pos, err)
+        return err
+    }
+    _, err = nsOp.conn.Exec(nsShiftLftIds, updateVal, nsOp.dataObj.TreeId,
+                            pos)
+    if err != nil {
+        log.Error("Failed to shift the left limits from %d err : %s",
+                   pos, err)
+        return err
+    }
+    return nil
+}
+
+//Find the left limit of the new record from its insert position in parent.
+func(nsOp *sqlNSOP)getInsertNSLimit(parentObj *sqlData) (int64, error) {
+    switch nsOp.dataObj.Position {
+    case dataStore.POSITION_FIRST_CHILD:
+        return parentObj.LftId + 1, nil
+    case dataStore.POSITION_BEFORE, dataStore.POSITION_AFTER:
+        siblingObj, err := nsOp.dataObj.getInsertSibling(nsOp.conn)
+        if err != nil {
+            return 0, err
+        }
+        if nsOp.dataObj.Position == dataStore.POSITION_BEFORE {
+            return siblingObj.LftId, nil
+        }
+        return siblingObj.RgtId + 1, nil
+    }
+    //Insert as last child by default.
+    return parentObj.RgtId, nil
+}
+
+//Set the left and right child with its new limit values.
+func(nsOp *sqlNSOP)updateNSListLimitsOnAdd() error{
+    log := logger.GetLoggerInstance()
+    var parentObj *sqlData
+    var pos int64
+    var err error
+    err = nil
+    if len(nsOp.dataObj.Puid) == 0 {
+        //Something is wrong. Cannot find the parent for new node.
+        log.Error("Invalid parent for record to update the NS list limits")
+        return appErrors.INVALID_INPUT
+    }
+    parentObj = nsOp.dataObj.newTreeData(nsOp.dataObj.Puid)
+    parentObj.Data, err = parentObj.GetdataById(nsOp.conn)
+    if err != nil {
+        log.Error("Failed to get the parent record err : %s", err)
+        return err
+    }
+    pos, err = nsOp.getInsertNSLimit(parentObj)
+    if err != nil {
+        return err
+    }
+    // Make room for the new node at its position by shifting every record
+    // at or after it, including right end of all its parents.
+    err = nsOp.shiftNSLimits(pos, 2)
+    if err != nil {
+        log.Error("Failed to update the records on adding new record" +
+                  " %s err : %s", nsOp.dataObj.Uid, err)
+        return err
+    }
+    nsOp.dataObj.LftId = pos
+    nsOp.dataObj.RgtId = nsOp.dataObj.LftId + 1
+    return nsOp.dataObj.updateIds(nsOp.conn)
+}
+
+//Function to update the nested set values on deleting an node from the db.
+// On deleting a node, we must need to delete all its children and update other
+// records in the system.
+func(nsOp *sqlNSOP)updateNSListLimitsOnDel() error {
+    var diff int64
+    var err error
+    log := logger.GetLoggerInstance()
+    diff = nsOp.dataObj.RgtId - nsOp.dataObj.LftId
+    if diff > 1 {
+        // Deleting a node with childrens, Need to delete all its children first
+        var res sql.Result
+        var count int64
+        res, err = nsOp.conn.Exec(nsDeleteChildrens, nsOp.dataObj.TreeId,
+                                  nsOp.dataObj.LftId, nsOp.dataObj.RgtId)
+        if err != nil {
+            log.Error("Failed to delete childrens of %s record err : %s",
+                       nsOp.dataObj.Uid, err)
+            return err
+        }
+        count, err = res.RowsAffected()
+        if err != nil {
+            log.Error("Failed to get deleted childrens of %s record err : %s",
+                       nsOp.dataObj.Uid, err)
+            return err
+        }
+        if count != (diff - 1) / 2 {
+            log.Error("Corrupted tree in the system, as " +
+                      "parent node %s has %d childrens, expected %d. " +
+                      "Rebuild the tree to fix the limits",
+                      nsOp.dataObj.Uid, count, (diff - 1) / 2)
+            return appErrors.INVALID_STATE
+        }
+    }
+    //Close the gap left by the record and its childrens.
+    err = nsOp.shiftNSLimits(nsOp.dataObj.RgtId + 1, -(diff + 1))
+    if err != nil {
+        log.Error("Failed to update records in the sytem on delete of %s" +
+                  " err :%s", nsOp.dataObj.Uid, err)
+        return err
+    }
+    return nil
+}
+
+//Function to update the nested set values on deleting a node without its
+// childrens. The childrens take the place of the node in its parent, so their
+// limits are shifted by one and the records after the node by two.
+func(nsOp *sqlNSOP)updateNSListLimitsOnPromote() error {
+    var err error
+    log := logger.GetLoggerInstance()
+    _, err = nsOp.conn.Exec(nsPromoteParent, nsOp.dataObj.Puid,
+                            nsOp.dataObj.TreeId, nsOp.dataObj.Uid)
+    if err != nil {
+        log.Error("Failed to update the parent of childrens of %s err : %s",
+                   nsOp.dataObj.Uid, err)
+        return err
+    }
+    _, err = nsOp.conn.Exec(nsPromoteChildrens, nsOp.dataObj.TreeId,
+                            nsOp.dataObj.LftId, nsOp.dataObj.RgtId)
+    if err != nil {
+        log.Error("Failed to update the limits of childrens of %s err : %s",
+                   nsOp.dataObj.Uid, err)
+        return err
+    }
+    err = nsOp.shiftNSLimits(nsOp.dataObj.RgtId + 1, -2)
+    if err != nil {
+        log.Error("Failed to update records in the sytem on delete of %s" +
+                  " err :%s", nsOp.dataObj.Uid, err)
+        return err
+    }
+    return nil
+}
+
+//Function to update the nested set values on moving a node under a new parent.
+// The node and all its childrens are placed at the right end of the new
+// parent, every record between old and new position is shifted accordingly.
+func(nsOp *sqlNSOP)updateNSListLimitsOnMove(parentObj *sqlData) error {
+    var err error
+    var subtreeShift, gapLftId, gapRgtId, gapShift int64
+    log := logger.GetLoggerInstance()
+    lftId := nsOp.dataObj.LftId
+    rgtId := nsOp.dataObj.RgtId
+    width := rgtId - lftId + 1
+    pos := parentObj.RgtId
+    if pos > rgtId {
+        //Moving right, records upto the new position fill the gap.
+        subtreeShift = pos - rgtId - 1
+        gapLftId = rgtId + 1
+        gapRgtId = pos - 1
+        gapShift = -width
+    } else {
+        //Moving left, records from the new position make room for subtree.
+        subtreeShift = pos - lftId
+        gapLftId = pos
+        gapRgtId = lftId - 1
+        gapShift = width
+    }
+    _, err = nsOp.conn.Exec(nsMoveSubtree,
+                            lftId, rgtId, subtreeShift,
+                            gapLftId, gapRgtId, gapShift,
+                            lftId, rgtId, subtreeShift,
+                            gapLftId, gapRgtId, gapShift,
+                            nsOp.dataObj.TreeId,
+                            minNSLimit(lftId, gapLftId),
+                            maxNSLimit(rgtId, gapRgtId))
+    if err != nil {
+        log.Error("Failed to update records on move of %s err: %s",
+                   nsOp.dataObj.Uid, err)
+        return err
+    }
+    return nil
+}
+
+func minNSLimit(a int64, b int64) int64 {
+    if a < b {
+        return a
+    }
+    return b
+}
+
+func maxNSLimit(a int64, b int64) int64 {
+    if a > b {
+        return a
+    }
+    return b
+}
+
+//use this function to initialize the sqlNSOP. Do not use just new to create
+// the objs.
+func NewSqlNestedSet(dataObj *sqlData, conn *dbConn) *sqlNSOP {
+    NSObj := new(sqlNSOP)
+    NSObj.dataObj = dataObj
+    NSObj.conn = conn
+    return NSObj
+}
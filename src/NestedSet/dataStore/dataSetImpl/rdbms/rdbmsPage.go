@@ -0,0 +1,152 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rdbms
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+    "NestedSet/appErrors"
+)
+
+//Read the records of the query in order till 'addRecord' needs no more
+// records. The records are not kept in memory, only the page is.
+func readPage(conn *dbConn, addRecord func(*dataStore.Data) bool,
+              query string, args ...interface{}) error {
+    rows, err := conn.Queryx(query, args...)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        rec := new(dataStore.Data)
+        err = rows.StructScan(rec)
+        if err != nil {
+            return err
+        }
+        if !addRecord(rec) {
+            break
+        }
+    }
+    return rows.Err()
+}
+
+//Conditions of the page queries on the filter of the request along with their
+// arguments, the depth is not checked in the queries.
+func(dataObj *sqlData)getPageConditions(conn *dbConn,
+                    filter *dataStore.RecordFilter) (string, []interface{}) {
+    conditions := []string{dataTreeCond}
+    args := []interface{}{dataObj.TreeId}
+    if filter.Name != nil {
+        conditions = append(conditions, dataNameCond)
+        args = append(args, *filter.Name)
+    }
+    if filter.Type != nil {
+        conditions = append(conditions, dataTypeCond)
+        args = append(args, *filter.Type)
+    }
+    if filter.IsLeaf != nil {
+        if *filter.IsLeaf == true {
+            conditions = append(conditions, dataLeafCond)
+        } else {
+            conditions = append(conditions, dataParentCond)
+        }
+    }
+    keys := []string{}
+    for key := range filter.Attributes {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        attrCond, attrArgs := conn.dialect.AttributeCond(key,
+                                                 filter.Attributes[key])
+        conditions = append(conditions, attrCond)
+        args = append(args, attrArgs...)
+    }
+    return strings.Join(conditions, " AND "), args
+}
+
+//Count the records in all the pages. The records of the tree are read in
+// pre-order to find their depth when they are filtered on the depth.
+func(dataObj *sqlData)countPage(conn *dbConn,
+                                filter *dataStore.RecordFilter) (int64, error) {
+    var total int64
+    if filter.Depth == nil {
+        conditions, args := dataObj.getPageConditions(conn, filter)
+        err := sqlx.Get(conn, &total, fmt.Sprintf(dataCountPage, conditions),
+                        args...)
+        return total, err
+    }
+    counter := dataStore.NewPageBuilder(&dataStore.PageRequest{
+        Filter: *filter,
+    }, nil)
+    err := readPage(conn, counter.AddRecord,
+                    fmt.Sprintf(dataGetPage, dataTreeCond), dataObj.TreeId, 0)
+    if err != nil {
+        return 0, err
+    }
+    return counter.GetPage(0).Total, nil
+}
+
+//Get a page of the records in the tree. The records are read from the cursor
+// in pre-order, their depth is found from the records before them, and they
+// are filtered till the page is full.
+func(dataObj *sqlData)getPage(conn *dbConn,
+                       request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                        error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    parents := []dataStore.Data{}
+    err = sqlx.Select(conn, &parents, dataGetOpenParents, dataObj.TreeId,
+                      request.After, request.After)
+    if err != nil {
+        log.Error("Failed to retereive the parents of the page err : %s", err)
+        return nil, err
+    }
+    builder := dataStore.NewSeekPageBuilder(request, parents, &request.Filter)
+    query := fmt.Sprintf(dataGetPage, dataTreeCond)
+    args := []interface{}{dataObj.TreeId, request.After}
+    if request.Filter.IsEmpty() {
+        query += ` LIMIT ?`
+        args = append(args, request.Limit + 1)
+    }
+    err = readPage(conn, builder.AddRecord, query, args...)
+    if err != nil {
+        log.Error("Failed to retereive the page of the records err : %s", err)
+        return nil, err
+    }
+    total, err := dataObj.countPage(conn, &request.Filter)
+    if err != nil {
+        log.Error("Failed to count the records of the pages err : %s", err)
+        return nil, err
+    }
+    return builder.GetPage(total), nil
+}
+
+//Get a page of the records with the name.
+func(dataObj *sqlData)getPageWithName(conn *dbConn,
+                       request *dataStore.PageRequest) (*dataStore.RecordPage,
+                                                        error) {
+    log := logger.GetLoggerInstance()
+    if len(dataObj.Name) == 0 {
+        log.Error("Failed to get the records, name is null")
+        return nil, appErrors.INVALID_INPUT
+    }
+    nameRequest := *request
+    nameRequest.Filter.Name = &dataObj.Name
+    return dataObj.getPage(conn, &nameRequest)
+}
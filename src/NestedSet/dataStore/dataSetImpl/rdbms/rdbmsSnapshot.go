@@ -0,0 +1,414 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rdbms
+
+import (
+    "fmt"
+    "time"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/appErrors"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+)
+
+const (
+    SQL_SNAPSHOT_TABLE_NAME = "snapshotSet"
+    SNAPSHOT_ID = "SnapshotId"
+    SNAPSHOT_TREEID = "TreeId"
+    SNAPSHOT_NAME = "Name"
+    SNAPSHOT_DESC = "Desc"
+    SNAPSHOT_CREATED_AT = "CreatedAt"
+    SNAPSHOT_SIZE = "Size"
+    //Records of the snapshots, with the columns of the data table.
+    SQL_SNAPSHOT_DATA_TABLE_NAME = "snapshotDataSet"
+)
+
+//Schema of the snapshot table.
+func snapshotSchema(dialect Dialect) string {
+    return fmt.Sprintf(
+                 `CREATE TABLE IF NOT EXISTS "%s" ("%s" %s PRIMARY KEY,
+                 "%s" %s NOT NULL,
+                 "%s" %s NOT NULL,
+                 "%s" %s,
+                 "%s" %s NOT NULL,
+                 "%s" %s NOT NULL)`,
+                 SQL_SNAPSHOT_TABLE_NAME,
+                 SNAPSHOT_ID, dialect.StringType(),
+                 SNAPSHOT_TREEID, dialect.StringType(),
+                 SNAPSHOT_NAME, dialect.StringType(),
+                 SNAPSHOT_DESC, dialect.TextType(),
+                 SNAPSHOT_CREATED_AT, dialect.StringType(),
+                 SNAPSHOT_SIZE, dialect.IntegerType())
+}
+
+//Schema of the snapshot data table, a record is present once in a snapshot.
+func snapshotDataSchema(dialect Dialect) string {
+    return fmt.Sprintf(
+                 `CREATE TABLE IF NOT EXISTS "%s" ("%s" %s NOT NULL,
+                 "%s" %s NOT NULL,
+                 "%s" %s NOT NULL,
+                 "%s" %s,
+                 "%s" %s NOT NULL,
+                 "%s" %s,
+                 "%s" %s NOT NULL DEFAULT '',
+                 "%s" %s,
+                 "%s" %s NOT NULL,
+                 "%s" %s NOT NULL,
+                 PRIMARY KEY ("%s", "%s"))`,
+                 SQL_SNAPSHOT_DATA_TABLE_NAME,
+                 SNAPSHOT_ID, dialect.StringType(),
+                 DATA_UID, dialect.StringType(),
+                 DATA_TREEID, dialect.StringType(),
+                 PARENT_UID, dialect.StringType(),
+                 DATA_NAME, dialect.StringType(),
+                 DATA_DESC, dialect.TextType(),
+                 DATA_TYPE, dialect.StringType(),
+                 DATA_ATTRIBUTES, dialect.TextType(),
+                 DATA_LFTID, dialect.IntegerType(),
+                 DATA_RGTID, dialect.IntegerType(),
+                 SNAPSHOT_ID, DATA_UID)
+}
+
+var snapshotIndexes = []sqlIndex{
+    newSqlIndex(SQL_SNAPSHOT_TABLE_NAME, SNAPSHOT_TREEID, SNAPSHOT_CREATED_AT),
+    newSqlIndex(SQL_SNAPSHOT_DATA_TABLE_NAME, SNAPSHOT_ID, DATA_LFTID),
+}
+
+//Columns of the records in the snapshot data table.
+var snapshotDataColumns = fmt.Sprintf(`"%s", "%s", "%s", "%s", "%s", "%s",
+                                       "%s", "%s", "%s"`,
+                                       DATA_UID, DATA_TREEID, PARENT_UID,
+                                       DATA_NAME, DATA_DESC, DATA_TYPE,
+                                       DATA_ATTRIBUTES, DATA_LFTID,
+                                       DATA_RGTID)
+
+var (
+    snapshotCreate = fmt.Sprintf(`INSERT INTO "%s"
+                                ("%s", "%s", "%s", "%s", "%s", "%s")
+                                VALUES (?, ?, ?, ?, ?, ?)`,
+                                SQL_SNAPSHOT_TABLE_NAME,
+                                SNAPSHOT_ID, SNAPSHOT_TREEID, SNAPSHOT_NAME,
+                                SNAPSHOT_DESC, SNAPSHOT_CREATED_AT,
+                                SNAPSHOT_SIZE)
+    snapshotGetAll = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                                ORDER BY "%s", "%s"`,
+                                SQL_SNAPSHOT_TABLE_NAME, SNAPSHOT_TREEID,
+                                SNAPSHOT_CREATED_AT, SNAPSHOT_NAME)
+    snapshotGetwthId = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                                AND "%s"=(?)`,
+                                SQL_SNAPSHOT_TABLE_NAME, SNAPSHOT_TREEID,
+                                SNAPSHOT_ID)
+    snapshotGetwthName = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                                AND "%s"=(?)`,
+                                SQL_SNAPSHOT_TABLE_NAME, SNAPSHOT_TREEID,
+                                SNAPSHOT_NAME)
+    snapshotDeleteOnId = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?)`,
+                                SQL_SNAPSHOT_TABLE_NAME, SNAPSHOT_ID)
+    snapshotDeleteTree = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?)`,
+                                SQL_SNAPSHOT_TABLE_NAME, SNAPSHOT_TREEID)
+    snapshotDataCreate = fmt.Sprintf(`INSERT INTO "%s" ("%s", %s)
+                                VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+                                SQL_SNAPSHOT_DATA_TABLE_NAME, SNAPSHOT_ID,
+                                snapshotDataColumns)
+    snapshotDataGetAll = fmt.Sprintf(`SELECT %s FROM "%s" WHERE "%s"=(?)
+                                ORDER BY "%s"`,
+                                snapshotDataColumns,
+                                SQL_SNAPSHOT_DATA_TABLE_NAME, SNAPSHOT_ID,
+                                DATA_LFTID)
+    snapshotDataGetwthUid = fmt.Sprintf(`SELECT %s FROM "%s" WHERE "%s"=(?)
+                                AND "%s"=(?)`,
+                                snapshotDataColumns,
+                                SQL_SNAPSHOT_DATA_TABLE_NAME, SNAPSHOT_ID,
+                                DATA_UID)
+    //Childrens of a record in the snapshot in order.
+    snapshotDataGetwthPID = fmt.Sprintf(`SELECT %s FROM "%s" WHERE "%s"=(?)
+                                AND "%s"=(?) ORDER BY "%s"`,
+                                snapshotDataColumns,
+                                SQL_SNAPSHOT_DATA_TABLE_NAME, SNAPSHOT_ID,
+                                PARENT_UID, DATA_LFTID)
+    //Records of the snapshot in the limits, used for the subtree.
+    snapshotDataGetRange = fmt.Sprintf(`SELECT %s FROM "%s" WHERE "%s"=(?)
+                                AND "%s" BETWEEN (?) AND (?) ORDER BY "%s"`,
+                                snapshotDataColumns,
+                                SQL_SNAPSHOT_DATA_TABLE_NAME, SNAPSHOT_ID,
+                                DATA_LFTID, DATA_LFTID)
+    //Depth of a record in the snapshot is the number of its parents.
+    snapshotDataDepth = fmt.Sprintf(`SELECT COUNT(*) FROM "%s" WHERE "%s"=(?)
+                                AND "%s"<(?) AND "%s">(?)`,
+                                SQL_SNAPSHOT_DATA_TABLE_NAME, SNAPSHOT_ID,
+                                DATA_LFTID, DATA_RGTID)
+    snapshotDataDeleteOnId = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?)`,
+                                SQL_SNAPSHOT_DATA_TABLE_NAME, SNAPSHOT_ID)
+    snapshotDataDeleteTree = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?)`,
+                                SQL_SNAPSHOT_DATA_TABLE_NAME, DATA_TREEID)
+)
+
+func createSnapshotTables(conn *dbConn) error {
+    _, err := conn.Exec(snapshotSchema(conn.dialect))
+    if err != nil {
+        return err
+    }
+    _, err = conn.Exec(snapshotDataSchema(conn.dialect))
+    if err != nil {
+        return err
+    }
+    return createIndexes(conn, snapshotIndexes)
+}
+
+//Create the snapshot of the tree of the record with all its records.
+func(dataObj *sqlData)CreateSnapshot(conn *dbConn,
+                                     snapshot *dataStore.Snapshot) error {
+    log := logger.GetLoggerInstance()
+    recs, err := dataObj.GetAllRecords(conn)
+    if err != nil {
+        return err
+    }
+    err = snapshot.Init(dataObj.TreeId, len(recs), time.Now())
+    if err != nil {
+        return err
+    }
+    rows := []dataStore.Snapshot{}
+    err = sqlx.Select(conn, &rows, snapshotGetwthName, dataObj.TreeId,
+                      snapshot.Name)
+    if err != nil {
+        log.Error("Failed to retereive the snapshot with name %s err : %s",
+                   snapshot.Name, err)
+        return err
+    }
+    if len(rows) != 0 {
+        log.Info("Cannot create snapshot, %s already present in the tree %s",
+                  snapshot.Name, dataObj.TreeId)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    _, err = conn.Exec(snapshotCreate, snapshot.SnapshotId, snapshot.TreeId,
+                       snapshot.Name, snapshot.Desc, snapshot.CreatedAt,
+                       snapshot.Size)
+    if err != nil {
+        log.Error("Failed to insert snapshot %s err : %s", snapshot.Name, err)
+        return err
+    }
+    for _, rec := range recs {
+        _, err = conn.Exec(snapshotDataCreate, snapshot.SnapshotId, rec.Uid,
+                           rec.TreeId, rec.Puid, rec.Name, rec.Desc, rec.Type,
+                           rec.Attributes, rec.LftId, rec.RgtId)
+        if err != nil {
+            log.Error("Failed to insert record %s in snapshot %s err : %s",
+                       rec.Uid, snapshot.Name, err)
+            return err
+        }
+    }
+    return nil
+}
+
+//Get the snapshot of the tree of the record.
+func(dataObj *sqlData)GetSnapshot(conn *dbConn,
+                        snapshotId string) (*dataStore.Snapshot, error) {
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Snapshot{}
+    if len(snapshotId) == 0 {
+        log.Error("Cannot retrieve a snapshot with empty id")
+        return nil, appErrors.INVALID_INPUT
+    }
+    err := sqlx.Select(conn, &rows, snapshotGetwthId, dataObj.TreeId,
+                       snapshotId)
+    if err != nil {
+        log.Error("Failed to get snapshot %s err : %s", snapshotId, err)
+        return nil, err
+    }
+    if len(rows) == 0 {
+        log.Error("Snapshot %s is not present in the tree %s", snapshotId,
+                   dataObj.TreeId)
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    return &rows[0], nil
+}
+
+func(dataObj *sqlData)GetAllSnapshots(conn *dbConn) ([]dataStore.Snapshot,
+                                                     error) {
+    rows := []dataStore.Snapshot{}
+    err := sqlx.Select(conn, &rows, snapshotGetAll, dataObj.TreeId)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to retereive the snapshots " +
+                                         "of tree %s err : %s",
+                                         dataObj.TreeId, err)
+        return nil, err
+    }
+    return rows, nil
+}
+
+//Delete the snapshot along with its records.
+func(dataObj *sqlData)DeleteSnapshot(conn *dbConn, snapshotId string) error {
+    _, err := dataObj.GetSnapshot(conn, snapshotId)
+    if err != nil {
+        return err
+    }
+    _, err = conn.Exec(snapshotDataDeleteOnId, snapshotId)
+    if err == nil {
+        _, err = conn.Exec(snapshotDeleteOnId, snapshotId)
+    }
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to delete snapshot %s " +
+                                         "err : %s", snapshotId, err)
+    }
+    return err
+}
+
+//Get the record 'recid' and all its childrens in pre-order from the
+// snapshot, all the records of the snapshot when 'recid' is empty.
+func(dataObj *sqlData)GetSnapshotRecords(conn *dbConn, snapshotId string,
+                                recid string) ([]dataStore.Data, error) {
+    log := logger.GetLoggerInstance()
+    _, err := dataObj.GetSnapshot(conn, snapshotId)
+    if err != nil {
+        return nil, err
+    }
+    rows := []dataStore.Data{}
+    if len(recid) == 0 {
+        err = sqlx.Select(conn, &rows, snapshotDataGetAll, snapshotId)
+        if err != nil {
+            log.Error("Failed to retereive the records of snapshot %s " +
+                      "err : %s", snapshotId, err)
+            return nil, err
+        }
+        dataStore.UpdateTreeInfo(rows, 0)
+        return rows, nil
+    }
+    err = sqlx.Select(conn, &rows, snapshotDataGetwthUid, snapshotId, recid)
+    if err != nil {
+        log.Error("Failed to get record %s of snapshot %s err : %s", recid,
+                   snapshotId, err)
+        return nil, err
+    }
+    if len(rows) == 0 {
+        log.Error("Record %s is not present in the snapshot", recid)
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    rec := rows[0]
+    var depth int64
+    err = sqlx.Get(conn, &depth, snapshotDataDepth, snapshotId, rec.LftId,
+                   rec.RgtId)
+    if err != nil {
+        log.Error("Failed to get the depth of %s in snapshot %s err : %s",
+                   recid, snapshotId, err)
+        return nil, err
+    }
+    rows = []dataStore.Data{}
+    err = sqlx.Select(conn, &rows, snapshotDataGetRange, snapshotId,
+                      rec.LftId, rec.RgtId)
+    if err != nil {
+        log.Error("Failed to retereive the subtree of %s in snapshot %s " +
+                  "err : %s", recid, snapshotId, err)
+        return nil, err
+    }
+    dataStore.UpdateTreeInfo(rows, depth)
+    return rows, nil
+}
+
+//Move the records dropped by restoring the snapshot records 'recs' on the
+// records 'current' to the trash.
+func(dataObj *sqlData)trashDropped(conn *dbConn, current []dataStore.Data,
+                                   recs []dataStore.Data) error {
+    dropped, err := dataStore.GetSnapshotDropped(current, recs)
+    if err != nil {
+        return err
+    }
+    for _, rec := range dropped {
+        err = dataObj.newTreeData(rec.Uid).TrashData(conn)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+//Restore the subtree of 'recid' from the snapshot at its position among the
+// siblings in the snapshot, the record and its childrens in the tree are
+// replaced by the subtree. Entire tree is restored when 'recid' is empty or
+// the root of the tree. The records not in the snapshot are moved to the
+// trash.
+func(dataObj *sqlData)RestoreSnapshot(conn *dbConn, snapshotId string,
+                                      recid string) error {
+    recs, err := dataObj.GetSnapshotRecords(conn, snapshotId, recid)
+    if err != nil {
+        return err
+    }
+    if len(recs[0].Puid) == 0 {
+        current, err := dataObj.GetAllRecords(conn)
+        if err == nil {
+            err = dataObj.trashDropped(conn, current, recs)
+        }
+        if err == nil {
+            err = dataObj.DeleteTreeData(conn)
+        }
+        if err != nil {
+            return err
+        }
+        err = insertDataWithLimits(conn,
+                            dataStore.GetSnapshotRestoreRecords(recs))
+        if err != nil {
+            return err
+        }
+        return dataObj.deleteRestoredTrash(conn, recs)
+    }
+    recObj := dataObj.newTreeData(recid)
+    recObj.Data, err = recObj.GetdataById(conn)
+    if err == nil {
+        var childs []dataStore.Data
+        childs, err = recObj.GetAllChildrens(conn)
+        if err == nil {
+            err = dataObj.trashDropped(conn,
+                        append([]dataStore.Data{*recObj.Data}, childs...),
+                        recs)
+        }
+        if err == nil {
+            err = recObj.DeleteData(conn)
+        }
+    } else if err == appErrors.DATA_NOT_UNIQUE_ERROR {
+        //Record is not present in the tree anymore.
+        err = nil
+    }
+    if err != nil {
+        return err
+    }
+    siblings := []dataStore.Data{}
+    err = sqlx.Select(conn, &siblings, snapshotDataGetwthPID, snapshotId,
+                      recs[0].Puid)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to get the siblings of %s " +
+                                         "in snapshot %s err : %s", recid,
+                                         snapshotId, err)
+        return err
+    }
+    entry := dataStore.NewTrashEntry(recs, time.Now())
+    err = dataObj.restoreSubtree(conn, entry, entry.Puid,
+                        dataStore.GetSnapshotPrevSiblings(siblings, &recs[0]))
+    if err != nil {
+        return err
+    }
+    return dataObj.deleteRestoredTrash(conn, recs)
+}
+
+//Delete the snapshots of the tree along with the tree.
+func(treeObj *sqlTree)DeleteTreeSnapshots(conn *dbConn) error {
+    _, err := conn.Exec(snapshotDataDeleteTree, treeObj.TreeId)
+    if err == nil {
+        _, err = conn.Exec(snapshotDeleteTree, treeObj.TreeId)
+    }
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to delete the snapshots of " +
+                                         "tree %s err : %s", treeObj.TreeId,
+                                         err)
+    }
+    return err
+}
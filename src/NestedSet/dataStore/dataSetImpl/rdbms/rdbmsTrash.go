@@ -0,0 +1,316 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdbms
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/appErrors"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+)
+
+const (
+    SQL_TRASH_TABLE_NAME = "trashSet"
+    TRASH_UID = "Uid"
+    TRASH_TREEID = "TreeId"
+    TRASH_PUID = "Puid"
+    TRASH_NAME = "Name"
+    TRASH_DELETED_AT = "DeletedAt"
+    TRASH_RECORDS = "Records"
+)
+
+//Schema of the trash table, records of the subtree are kept as JSON.
+func trashSchema(dialect Dialect) string {
+    return fmt.Sprintf(
+                 `CREATE TABLE IF NOT EXISTS "%s" ("%s" %s PRIMARY KEY,
+                 "%s" %s NOT NULL,
+                 "%s" %s,
+                 "%s" %s,
+                 "%s" %s NOT NULL,
+                 "%s" %s)`,
+                 SQL_TRASH_TABLE_NAME,
+                 TRASH_UID, dialect.StringType(),
+                 TRASH_TREEID, dialect.StringType(),
+                 TRASH_PUID, dialect.StringType(),
+                 TRASH_NAME, dialect.StringType(),
+                 TRASH_DELETED_AT, dialect.StringType(),
+                 TRASH_RECORDS, dialect.TextType())
+}
+
+var trashIndexes = []sqlIndex{
+    newSqlIndex(SQL_TRASH_TABLE_NAME, TRASH_TREEID, TRASH_DELETED_AT),
+}
+
+var (
+    trashCreate = fmt.Sprintf(`INSERT INTO "%s"
+                                ("%s", "%s", "%s", "%s", "%s", "%s")
+                                VALUES (?, ?, ?, ?, ?, ?)`,
+                                SQL_TRASH_TABLE_NAME,
+                                TRASH_UID, TRASH_TREEID, TRASH_PUID,
+                                TRASH_NAME, TRASH_DELETED_AT, TRASH_RECORDS)
+    trashGetAll = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                                ORDER BY "%s", "%s"`,
+                                SQL_TRASH_TABLE_NAME, TRASH_TREEID,
+                                TRASH_DELETED_AT, TRASH_UID)
+    trashGetwthUid = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)
+                                AND "%s"=(?)`,
+                                SQL_TRASH_TABLE_NAME, TRASH_TREEID, TRASH_UID)
+    trashDeleteOnId = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?)`,
+                                SQL_TRASH_TABLE_NAME, TRASH_UID)
+    trashDeleteTree = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?)`,
+                                SQL_TRASH_TABLE_NAME, TRASH_TREEID)
+    trashPurge = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"<(?)`,
+                                SQL_TRASH_TABLE_NAME, TRASH_DELETED_AT)
+)
+
+//Row of the trash table.
+type sqlTrash struct {
+    Uid string          `db:"Uid"`
+    TreeId string       `db:"TreeId"`
+    Puid string         `db:"Puid"`
+    Name string         `db:"Name"`
+    DeletedAt string    `db:"DeletedAt"`
+    Records string      `db:"Records"`
+}
+
+func (row *sqlTrash)toTrashEntry() (*dataStore.TrashEntry, error) {
+    entry := &dataStore.TrashEntry{Uid: row.Uid, TreeId: row.TreeId,
+                                   Puid: row.Puid, Name: row.Name,
+                                   DeletedAt: row.DeletedAt}
+    err := json.Unmarshal([]byte(row.Records), &entry.Records)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to decode the records of " +
+                                         "trash %s err : %s", row.Uid, err)
+        return nil, err
+    }
+    return entry, nil
+}
+
+func createTrashTable(conn *dbConn) error {
+    _, err := conn.Exec(trashSchema(conn.dialect))
+    if err != nil {
+        return err
+    }
+    return createIndexes(conn, trashIndexes)
+}
+
+//Get the trash entries of the tree of the record.
+func(dataObj *sqlData)GetTrash(conn *dbConn) ([]dataStore.TrashEntry, error) {
+    log := logger.GetLoggerInstance()
+    rows := []sqlTrash{}
+    err := sqlx.Select(conn, &rows, trashGetAll, dataObj.TreeId)
+    if err != nil {
+        log.Error("Failed to retereive the trash of tree %s err : %s",
+                   dataObj.TreeId, err)
+        return nil, err
+    }
+    entries := make([]dataStore.TrashEntry, 0, len(rows))
+    for i := range rows {
+        entry, err := rows[i].toTrashEntry()
+        if err != nil {
+            return nil, err
+        }
+        entries = append(entries, *entry)
+    }
+    return entries, nil
+}
+
+func(dataObj *sqlData)getTrashEntry(conn *dbConn) (*dataStore.TrashEntry,
+                                                   error) {
+    log := logger.GetLoggerInstance()
+    rows := []sqlTrash{}
+    if len(dataObj.Uid) == 0 {
+        log.Error("Cannot retrieve a trash entry with empty Uid")
+        return nil, appErrors.INVALID_INPUT
+    }
+    err := sqlx.Select(conn, &rows, trashGetwthUid, dataObj.TreeId,
+                       dataObj.Uid)
+    if err != nil {
+        log.Error("Failed to get trash entry %s err : %s", dataObj.Uid, err)
+        return nil, err
+    }
+    if len(rows) == 0 {
+        log.Error("Record %s is not present in the trash", dataObj.Uid)
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    return rows[0].toTrashEntry()
+}
+
+//Move the record and all its childrens to the trash.
+func(dataObj *sqlData)TrashData(conn *dbConn) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    dataObj.Data, err = dataObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the record %s on delete, Cannot delete",
+                   dataObj.Uid)
+        return err
+    }
+    if dataObj.IsdataRoot() == true {
+        log.Error("Cannot delete root node, as its not owned by user")
+        return appErrors.INVALID_INPUT
+    }
+    rows, err := dataObj.GetAllChildrens(conn)
+    if err != nil {
+        return err
+    }
+    entry := dataStore.NewTrashEntry(append([]dataStore.Data{*dataObj.Data},
+                                            rows...), time.Now())
+    records, err := json.Marshal(entry.Records)
+    if err != nil {
+        return err
+    }
+    _, err = conn.Exec(trashCreate, entry.Uid, entry.TreeId, entry.Puid,
+                       entry.Name, entry.DeletedAt, string(records))
+    if err != nil {
+        log.Error("Failed to move %s to the trash err : %s", entry.Uid, err)
+        return err
+    }
+    return dataObj.DeleteData(conn)
+}
+
+//Restore the subtree from the trash as the last child of 'puid', or of its
+// original parent when 'puid' is empty.
+func(dataObj *sqlData)RestoreData(conn *dbConn, puid string) error {
+    entry, err := dataObj.getTrashEntry(conn)
+    if err != nil {
+        return err
+    }
+    if len(puid) == 0 {
+        puid = entry.Puid
+    }
+    err = dataObj.restoreSubtree(conn, entry, puid, nil)
+    if err != nil {
+        return err
+    }
+    _, err = conn.Exec(trashDeleteOnId, entry.Uid)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to remove %s from the " +
+                                         "trash err : %s", entry.Uid, err)
+        return err
+    }
+    return nil
+}
+
+//Remove the trash entries of the records 'recs' put back in the tree, they
+// are stale as the records can be deleted again.
+func(dataObj *sqlData)deleteRestoredTrash(conn *dbConn,
+                                          recs []dataStore.Data) error {
+    entries, err := dataObj.GetTrash(conn)
+    if err != nil {
+        return err
+    }
+    for _, uid := range dataStore.GetRestoredTrash(entries, recs) {
+        _, err = conn.Exec(trashDeleteOnId, uid)
+        if err != nil {
+            logger.GetLoggerInstance().Error("Failed to remove %s from the " +
+                                             "trash err : %s", uid, err)
+            return err
+        }
+    }
+    return nil
+}
+
+//Insert the subtree of the entry under 'puid' after the first of the
+// 'siblings' present under it, or as the first child when none is present.
+// Its the last child when 'siblings' is nil. Fails when a sibling with the
+// same name or any record of the subtree is present in the tree.
+func(dataObj *sqlData)restoreSubtree(conn *dbConn,
+                                     entry *dataStore.TrashEntry,
+                                     puid string, siblings []string) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    parentObj := dataObj.newTreeData(puid)
+    parentObj.Data, err = parentObj.GetdataById(conn)
+    if err != nil {
+        log.Error("Failed to get the parent %s to restore %s err : %s",
+                   puid, entry.Uid, err)
+        return err
+    }
+    err = dataStore.CheckChildType(parentObj.Type, entry.Records[0].Type)
+    if err != nil {
+        return err
+    }
+    //Name must be unique under the parent.
+    dataObj.Name = entry.Name
+    dataObj.Puid = puid
+    rows, err := dataObj.getDataWithNameAndPID(conn)
+    if err != nil {
+        return err
+    }
+    if len(rows) != 0 {
+        log.Info("Cannot restore %s, Record %s already present under %s",
+                  entry.Uid, entry.Name, puid)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    for _, rec := range entry.Records {
+        _, err = dataObj.newTreeData(rec.Uid).GetdataById(conn)
+        if err == nil {
+            log.Error("Cannot restore %s, record %s is already present",
+                       entry.Uid, rec.Uid)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+        if err != appErrors.DATA_NOT_UNIQUE_ERROR {
+            return err
+        }
+    }
+    pos := parentObj.RgtId
+    if siblings != nil {
+        pos = parentObj.LftId + 1
+    }
+    for _, uid := range siblings {
+        sibling, err := dataObj.newTreeData(uid).GetdataById(conn)
+        if err == nil && sibling.Puid == puid {
+            pos = sibling.RgtId + 1
+            break
+        }
+        if err != nil && err != appErrors.DATA_NOT_UNIQUE_ERROR {
+            return err
+        }
+    }
+    nsObj := NewSqlNestedSet(dataObj, conn)
+    err = nsObj.shiftNSLimits(pos, entry.Width())
+    if err != nil {
+        return err
+    }
+    return insertDataWithLimits(conn,
+                    entry.GetRestoreRecords(dataObj.TreeId, puid, pos))
+}
+
+//Delete the trash of the tree along with the tree.
+func(treeObj *sqlTree)DeleteTreeTrash(conn *dbConn) error {
+    _, err := conn.Exec(trashDeleteTree, treeObj.TreeId)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to delete the trash of " +
+                                         "tree %s err : %s", treeObj.TreeId,
+                                         err)
+    }
+    return err
+}
+
+//Remove the trash entries of all the trees deleted before 'before'.
+func purgeTrash(conn *dbConn, before time.Time) (int, error) {
+    res, err := conn.Exec(trashPurge, dataStore.TrashTimeString(before))
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to purge the trash err : %s",
+                                         err)
+        return 0, err
+    }
+    count, err := res.RowsAffected()
+    return int(count), err
+}
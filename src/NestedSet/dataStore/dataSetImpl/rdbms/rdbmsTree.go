@@ -0,0 +1,337 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdbms
+
+import (
+    "fmt"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+    "NestedSet/appErrors"
+    "NestedSet/sys"
+)
+
+const (
+    SQL_TREE_TABLE_NAME = "treeSet"
+    TREE_ID = "TreeId"
+    TREE_NAME = "Name"
+    TREE_DESC = "Desc"
+    TREE_ROOT_UID = "RootUid"
+    TREE_VERSION = "Version"
+)
+
+//Schema of the tree table, column types are provided by the database dialect.
+func treeSchema(dialect Dialect) string {
+    return fmt.Sprintf(
+                 `CREATE TABLE IF NOT EXISTS "%s" ("%s" %s PRIMARY KEY,
+                 "%s" %s NOT NULL UNIQUE,
+                 "%s" %s,
+                 "%s" %s NOT NULL)`,
+                 SQL_TREE_TABLE_NAME,
+                 TREE_ID, dialect.StringType(),
+                 TREE_NAME, dialect.StringType(),
+                 TREE_DESC, dialect.TextType(),
+                 TREE_ROOT_UID, dialect.StringType())
+}
+
+var (
+    treeCreate = fmt.Sprintf(`INSERT INTO "%s"
+                                ("%s", "%s", "%s", "%s")
+                                VALUES (?, ?, ?, ?)`,
+                                SQL_TREE_TABLE_NAME,
+                                TREE_ID,
+                                TREE_NAME,
+                                TREE_DESC,
+                                TREE_ROOT_UID)
+    treeDeleteOnId = fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=(?)`,
+                                 SQL_TREE_TABLE_NAME, TREE_ID)
+    treeGetAll = fmt.Sprintf(`SELECT * FROM "%s" ORDER BY "%s"`,
+                                 SQL_TREE_TABLE_NAME, TREE_NAME)
+    treeGetAllOnId = fmt.Sprintf(`SELECT * FROM "%s" ORDER BY "%s"`,
+                                 SQL_TREE_TABLE_NAME, TREE_ID)
+    treeGetwthId = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)`,
+                                 SQL_TREE_TABLE_NAME, TREE_ID)
+    treeGetwthName = fmt.Sprintf(`SELECT * FROM "%s" WHERE "%s"=(?)`,
+                                 SQL_TREE_TABLE_NAME, TREE_NAME)
+    //Increment the version of the tree on a change of its records.
+    treeUpdateVersion = fmt.Sprintf(`UPDATE "%s" SET "%s"="%s"+1
+                                 WHERE "%s"=(?)`,
+                                 SQL_TREE_TABLE_NAME, TREE_VERSION,
+                                 TREE_VERSION, TREE_ID)
+)
+
+// Anonymous pointer to Tree struct, similar to sqlData.
+type sqlTree struct {
+    *dataStore.Tree
+}
+
+func(treeObj *sqlTree)IsDefaultTree() bool {
+    if treeObj.TreeId == dataStore.DEFAULT_TREE_ID {
+        return true
+    }
+    return false
+}
+
+//Retrieve a tree with its id.
+func(treeObj *sqlTree)GetTreeById(conn *dbConn) (*dataStore.Tree, error) {
+    return treeObj.getTree(conn, treeGetwthId)
+}
+
+//Retrieve a tree with its id and lock it till the end of the transaction.
+// All the updates of a tree are serialized on the exclusive lock, readers
+// take the shared lock to see the tree only between the updates.
+func(treeObj *sqlTree)LockTreeById(conn *dbConn,
+                                   exclusive bool) (*dataStore.Tree, error) {
+    return treeObj.getTree(conn,
+                           treeGetwthId + conn.dialect.LockClause(exclusive))
+}
+
+func(treeObj *sqlTree)getTree(conn *dbConn,
+                              query string) (*dataStore.Tree, error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Tree{}
+    if len(treeObj.TreeId) == 0 {
+        log.Error("Cannot retrieve a tree with empty id")
+        return nil, appErrors.INVALID_INPUT
+    }
+    err = sqlx.Select(conn, &rows, query, treeObj.TreeId)
+    if err != nil {
+        log.Error("Failed to get tree with id %s err : %s", treeObj.TreeId,
+                   err)
+        return nil, err
+    }
+    if len(rows) == 0 {
+        log.Error("Tree %s is not present in the system", treeObj.TreeId)
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    return &rows[0], nil
+}
+
+func(treeObj *sqlTree)GetAllTrees(conn *dbConn) ([]dataStore.Tree, error) {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Tree{}
+    err = sqlx.Select(conn, &rows, treeGetAll)
+    if err != nil {
+        log.Error("Failed to retereive all the trees err : %s", err)
+        return nil, err
+    }
+    return rows, nil
+}
+
+//Create a new tree along with its root record. Tree id is generated when its
+// not provided by the user.
+func(treeObj *sqlTree)InsertTree(conn *dbConn) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Tree{}
+    if len(treeObj.Name) == 0 {
+        log.Error("Cannot create a tree with empty name")
+        return appErrors.INVALID_INPUT
+    }
+    err = sqlx.Select(conn, &rows, treeGetwthName, treeObj.Name)
+    if err != nil {
+        log.Error("Failed to retereive the tree with name %s err : %s",
+                   treeObj.Name, err)
+        return err
+    }
+    if len(rows) != 0 {
+        log.Info("Cannot create tree, %s already present in the system",
+                  treeObj.Name)
+        return appErrors.DATA_PRESENT_IN_SYSTEM
+    }
+    if len(treeObj.TreeId) == 0 {
+        treeObj.TreeId, err = sys.NewUUIDString()
+        if err != nil {
+            log.Error("Failed to create UUID, cannot create a tree")
+            return err
+        }
+    } else {
+        err = sqlx.Select(conn, &rows, treeGetwthId, treeObj.TreeId)
+        if err != nil {
+            log.Error("Failed to retereive the tree %s err : %s",
+                       treeObj.TreeId, err)
+            return err
+        }
+        if len(rows) != 0 {
+            log.Info("Cannot create tree, %s already present in the system",
+                      treeObj.TreeId)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+    }
+    if treeObj.IsDefaultTree() == true {
+        treeObj.RootUid = dataStore.ROOT_UID
+    } else {
+        treeObj.RootUid, err = sys.NewUUIDString()
+        if err != nil {
+            log.Error("Failed to create UUID, cannot create a tree root")
+            return err
+        }
+    }
+    _, err = conn.Exec(treeCreate, treeObj.TreeId, treeObj.Name,
+                       treeObj.Desc, treeObj.RootUid)
+    if err != nil {
+        log.Error("Failed to insert tree %s err %s", treeObj.Name, err)
+        return err
+    }
+    rootObj := new(sqlData)
+    rootObj.Data = new(dataStore.Data)
+    rootObj.TreeId = treeObj.TreeId
+    rootObj.Uid = treeObj.RootUid
+    return rootObj.InsertRoot(conn)
+}
+
+//Create the default tree if its not present. Default tree is used by all the
+// APIs that are not scoped to a tree.
+func(treeObj *sqlTree)InsertDefaultTree(conn *dbConn) error {
+    treeObj.TreeId = dataStore.DEFAULT_TREE_ID
+    treeObj.Name = dataStore.DEFAULT_TREE_ID
+    treeObj.Desc = "The default tree in the system"
+    tree, err := treeObj.GetTreeById(conn)
+    if err == nil && tree != nil {
+        logger.GetLoggerInstance().Trace("Default tree already present.")
+        return nil
+    }
+    if err != appErrors.DATA_NOT_FOUND {
+        return err
+    }
+    return treeObj.InsertTree(conn)
+}
+
+//Delete the tree and all its records. Default tree cannot be deleted.
+func(treeObj *sqlTree)DeleteTree(conn *dbConn) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    if treeObj.IsDefaultTree() == true {
+        log.Error("Cannot delete default tree, as its owned by application")
+        return appErrors.INVALID_INPUT
+    }
+    _, err = treeObj.LockTreeById(conn, true)
+    if err != nil {
+        return err
+    }
+    dataObj := new(sqlData)
+    dataObj.Data = new(dataStore.Data)
+    dataObj.TreeId = treeObj.TreeId
+    err = dataObj.DeleteTreeData(conn)
+    if err != nil {
+        return err
+    }
+    err = treeObj.DeleteTreeTrash(conn)
+    if err != nil {
+        return err
+    }
+    err = treeObj.DeleteTreeSnapshots(conn)
+    if err != nil {
+        return err
+    }
+    _, err = conn.Exec(treeDeleteOnId, treeObj.TreeId)
+    if err != nil {
+        log.Error("Failed to delete tree %s err : %s", treeObj.TreeId, err)
+        return err
+    }
+    return nil
+}
+
+//Set the tree of the record and lock the tree for the transaction, fails when
+// the tree is not present.
+func(dataObj *sqlData)setTree(conn *dbConn, treeId string,
+                             exclusive bool) error {
+    treeObj := new(sqlTree)
+    treeObj.Tree = new(dataStore.Tree)
+    treeObj.TreeId = treeId
+    _, err := treeObj.LockTreeById(conn, exclusive)
+    if err != nil {
+        return err
+    }
+    dataObj.TreeId = treeId
+    return nil
+}
+
+//Lock all the trees till the end of the transaction, for the operations on
+// the records of all the trees. Trees are locked in the order of their id, so
+// the transactions never wait on each other in a loop.
+func lockAllTrees(conn *dbConn, exclusive bool) error {
+    log := logger.GetLoggerInstance()
+    rows := []dataStore.Tree{}
+    err := sqlx.Select(conn, &rows, treeGetAllOnId)
+    if err != nil {
+        log.Error("Failed to retereive all the trees err : %s", err)
+        return err
+    }
+    for i := range rows {
+        treeObj := new(sqlTree)
+        treeObj.Tree = &rows[i]
+        _, err = treeObj.LockTreeById(conn, exclusive)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+//Check the nested set limits of all the records in the tree.
+func(treeObj *sqlTree)VerifyTree(conn *dbConn) (*dataStore.TreeReport,
+                                                 error) {
+    tree, err := treeObj.LockTreeById(conn, false)
+    if err != nil {
+        return nil, err
+    }
+    dataObj := new(sqlData)
+    dataObj.Data = new(dataStore.Data)
+    dataObj.TreeId = tree.TreeId
+    rows, err := dataObj.GetAllRecords(conn)
+    if err != nil {
+        return nil, err
+    }
+    return dataStore.VerifyTree(tree.TreeId, tree.RootUid, rows), nil
+}
+
+//Recompute the nested set limits of all the records in the tree from their
+// parent links. Only the changed records are updated.
+func(treeObj *sqlTree)RebuildTree(conn *dbConn) error {
+    log := logger.GetLoggerInstance()
+    tree, err := treeObj.LockTreeById(conn, true)
+    if err != nil {
+        return err
+    }
+    dataObj := new(sqlData)
+    dataObj.Data = new(dataStore.Data)
+    dataObj.TreeId = tree.TreeId
+    rows, err := dataObj.GetAllRecords(conn)
+    if err != nil {
+        return err
+    }
+    changedRows, err := dataStore.RebuildTree(tree.RootUid, rows)
+    if err != nil {
+        log.Error("Failed to rebuild the tree %s err : %s", tree.TreeId, err)
+        return err
+    }
+    for i := range changedRows {
+        dataObj.Data = &changedRows[i]
+        err = dataObj.updateIds(conn)
+        if err != nil {
+            return err
+        }
+        err = dataObj.updateParentId(conn)
+        if err != nil {
+            return err
+        }
+    }
+    log.Info("Rebuilt the tree %s, %d records are updated", tree.TreeId,
+              len(changedRows))
+    return nil
+}
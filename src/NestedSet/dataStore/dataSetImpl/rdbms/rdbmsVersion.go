@@ -0,0 +1,130 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package rdbms
+
+import (
+    "github.com/jmoiron/sqlx"
+    "NestedSet/dataStore"
+    "NestedSet/logger"
+)
+
+//Increment the version of the tree of the record for a change, returns the
+// new version.
+func(dataObj *sqlData)updateTreeVersion(conn *dbConn) (int64, error) {
+    _, err := conn.Exec(treeUpdateVersion, dataObj.TreeId)
+    if err != nil {
+        logger.GetLoggerInstance().Error("Failed to update the version of " +
+                                         "tree %s err : %s", dataObj.TreeId,
+                                         err)
+        return 0, err
+    }
+    return dataObj.getTreeVersion(conn)
+}
+
+//Get the current version of the tree of the record.
+func(dataObj *sqlData)getTreeVersion(conn *dbConn) (int64, error) {
+    treeObj := new(sqlTree)
+    treeObj.Tree = new(dataStore.Tree)
+    treeObj.TreeId = dataObj.TreeId
+    tree, err := treeObj.GetTreeById(conn)
+    if err != nil {
+        return 0, err
+    }
+    return tree.Version, nil
+}
+
+//Update the versions of the records changed from the records 'before' to the
+// records 'after' the operation.
+func(dataObj *sqlData)updateVersions(conn *dbConn, before []dataStore.Data,
+                                     after []dataStore.Data) error {
+    log := logger.GetLoggerInstance()
+    version, err := dataObj.updateTreeVersion(conn)
+    if err != nil {
+        return err
+    }
+    versions, parents := dataStore.GetRecordVersions(before, after, version)
+    for _, recVersion := range versions {
+        _, err = conn.Exec(dataUpdateVersion, recVersion.Version,
+                           recVersion.ChildVersion, recVersion.Uid)
+        if err != nil {
+            log.Error("Failed to update the version of %s err : %s",
+                       recVersion.Uid, err)
+            return err
+        }
+    }
+    //Parents that are deleted along with the records are not updated.
+    for _, puid := range parents {
+        rows := []dataStore.Data{}
+        err = sqlx.Select(conn, &rows, dataGetwthUid, dataObj.TreeId, puid)
+        if err != nil {
+            log.Error("Failed to get the parent %s err : %s", puid, err)
+            return err
+        }
+        if len(rows) == 0 {
+            continue
+        }
+        _, err = conn.Exec(dataUpdateParentsVersion, version, dataObj.TreeId,
+                           rows[0].LftId, rows[0].RgtId)
+        if err != nil {
+            log.Error("Failed to update the version of %s err : %s", puid,
+                       err)
+            return err
+        }
+    }
+    return nil
+}
+
+//Update the versions of the records changed by the operation and add the
+// changes to the history.
+func(dataObj *sqlData)addChange(conn *dbConn, change *dataStore.Change,
+                                before []dataStore.Data,
+                                after []dataStore.Data) error {
+    err := dataObj.updateVersions(conn, before, after)
+    if err != nil {
+        return err
+    }
+    return addHistory(conn, change, before, after)
+}
+
+//Get the record 'uid' along with its depth and the version of its subtree.
+func(dataObj *sqlData)getRecordWithVersion(conn *dbConn,
+                                           uid string) (*dataStore.Data,
+                                                        error) {
+    recObj := dataObj.newTreeData(uid)
+    rec, err := recObj.GetdataInfoById(conn)
+    if err != nil {
+        return nil, err
+    }
+    rec.UpdateSubtreeVersion()
+    return rec, nil
+}
+
+//Check the version of the record 'uid' from the user before a change of the
+// record, or of its subtree when 'subtree' is set.
+func(dataObj *sqlData)checkVersion(conn *dbConn, uid string, version int64,
+                                   subtree bool) error {
+    if version == dataStore.ANY_VERSION {
+        return nil
+    }
+    rec, err := dataObj.newTreeData(uid).GetdataById(conn)
+    if err != nil {
+        return err
+    }
+    rec.UpdateSubtreeVersion()
+    treeVersion, err := dataObj.getTreeVersion(conn)
+    if err != nil {
+        return err
+    }
+    return rec.CheckVersion(version, treeVersion, subtree)
+}
@@ -14,114 +14,101 @@
 package sqlite
 
 import (
+    "database/sql"
     "fmt"
-    "sync"
     "path/filepath"
     "github.com/jmoiron/sqlx"
-    _ "github.com/mattn/go-sqlite3"
-    "NestedSet/logger"
+    "github.com/mattn/go-sqlite3"
     "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl/rdbms"
 )
 
-var dbOnce sync.Once
-var sqlObj *SqliteDataStore
+const (
+    BACKEND_NAME = "sqlite"
+    //sqlite driver with the functions of the datastore.
+    SQLITE_DRIVER_NAME = "sqlite3NestedSet"
+)
+
+func init() {
+    sql.Register(SQLITE_DRIVER_NAME, &sqlite3.SQLiteDriver{
+        ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+            return conn.RegisterFunc("hasAttribute", hasAttribute, true)
+        },
+    })
+    err := dataStore.RegisterBackend(BACKEND_NAME, newDataStore)
+    if err != nil {
+        panic(err)
+    }
+}
+
+//SQL function to check the attribute 'key' of the record has the 'value',
+// sqlite is built without the JSON functions.
+func hasAttribute(attributes interface{}, key string, value string) bool {
+    var attrs dataStore.Attributes
+    err := attrs.Scan(attributes)
+    return err == nil && attrs.HasValue(key, value)
+}
 
-type SqliteDataStore struct {
-    dblogger *logger.Logging
-    DBConn *sqlx.DB
+type sqliteDialect struct {
 }
 
+func (dialect *sqliteDialect)Name() string {
+    return "sqlite3"
+}
 
-//Create a sql connection and store in the datastore object.
-// Return '0' on success and errorcode otherwise.
-// It is advised to make single handle in entire application as every handle
-// uses a connection pool to manage multiple DB requests.
-func (sqlds *SqliteDataStore)CreateDBConnection(
-                                dbPath string) error{
-    dbDriver := "sqlite3"
+//Open the sqlite DB file at 'dbPath'.
+func (dialect *sqliteDialect)Connect(dbPath string) (*sqlx.DB, error) {
     dbFile, err := filepath.Abs(dbPath)
     if err != nil {
-        sqlds.dblogger.Error("Failed to open DB file, %s", err.Error())
-        return err
+        return nil, err
     }
-    var dbHandle *sqlx.DB
-    dbHandle, err = sqlx.Open(dbDriver, dbFile)
+    dbHandle, err := sqlx.Open(SQLITE_DRIVER_NAME, dbFile)
     if err != nil {
-        sqlds.dblogger.Error("Failed to connect DB %s", err.Error())
-        return err
+        return nil, err
     }
-    sqlds.DBConn = dbHandle
     // Serialize the DB access by limiting open connections to 1.
     // This will ensure there are no issues when concurrent threads are
-    // accessing the DB file.
-    //sqlds.DBConn.SetMaxOpenConns(1)
-    sqlds.dblogger.Trace("Created sqlite3 DB connection to %s", dbFile)
-    //Start the single thread to update the nested set data now. Its safe to do
-    // it here as DB connection is created only once in the entire application.
-    go updateNestedSetLimitsInDB()
-    return nil
+    // accessing the DB file. It also makes sure readers never see a tree in
+    // the middle of a nested set update, as the transaction holds the only
+    // connection until its committed.
+    dbHandle.SetMaxOpenConns(1)
+    return dbHandle, nil
 }
 
-//Create all the sqlite tables for the application.
-func (sqlds *SqliteDataStore)CreateDataStoreTables() error {
-    var err error
-    if sqlds.DBConn == nil {
-        return fmt.Errorf("Null DB connection, cannot create tables")
-    }
-    dataObj := new(sqlData) 
-    dataObj.Data = new(dataStore.Data) //Must allocate internal pointer too.
-    err = dataObj.CreateTable(sqlds.DBConn)
-    if err != nil {
-        return err
-    }
-    //Create the root node if not exisits.
-    return dataObj.InsertRoot(sqlds.DBConn)
+func (dialect *sqliteDialect)StringType() string {
+    return "TEXT"
 }
 
-func (sqlds *SqliteDataStore)CreateRecord(rec *dataStore.Data) error {
-    sqlDataObj := new(sqlData)
-    sqlDataObj.Data = rec
-    return sqlDataObj.InsertData(sqlds.DBConn)
+func (dialect *sqliteDialect)TextType() string {
+    return "TEXT"
 }
 
-func (sqlds *SqliteDataStore)DeleteRecord(recid string) error {
-    sqlDataObj := new(sqlData)
-    sqlDataObj.Data = new(dataStore.Data)
-    sqlDataObj.Uid = recid
-    sqlDataObj.DeleteData(sqlds.DBConn)
-    return nil
+func (dialect *sqliteDialect)IntegerType() string {
+    return "INTEGER"
 }
 
-func (sqlds *SqliteDataStore)GetRecord(recid string) (*dataStore.Data, error) {
-    sqlDataObj := new(sqlData)
-    sqlDataObj.Data = new(dataStore.Data)
-    sqlDataObj.Uid = recid
-    row, err := sqlDataObj.GetdataById(sqlds.DBConn)
-    return row, err
+func (dialect *sqliteDialect)IndexExistsQuery() string {
+    return `SELECT name FROM sqlite_master WHERE type='index' AND
+            tbl_name=(?) AND name=(?)`
 }
 
-func (sqlds *SqliteDataStore)GetRecordByName(name string)([]dataStore.Data, error) {
-    sqlDataObj := new(sqlData)
-    sqlDataObj.Data = new(dataStore.Data)
-    sqlDataObj.Name = name
-    row, err := sqlDataObj.getDataWithName(sqlds.DBConn)
-    return row, err
+//No row locks in sqlite, transactions are serialized on the only connection.
+func (dialect *sqliteDialect)LockClause(exclusive bool) string {
+    return ""
 }
 
- func (sqlds *SqliteDataStore)GetAllRecords()([]dataStore.Data, error) {
-    sqlDataObj := new(sqlData)
-    sqlDataObj.Data = new(dataStore.Data)
-    rows, err := sqlDataObj.GetAllRecords(sqlds.DBConn)
-     return rows, err
+func (dialect *sqliteDialect)AttributeCond(key string,
+                                     value string) (string, []interface{}) {
+    return fmt.Sprintf(`hasAttribute("%s", ?, ?)`, rdbms.DATA_ATTRIBUTES),
+           []interface{}{key, value}
 }
- 
- // Only one SQL datastore object can be present in the system as connection
-//pool can be handled inside the database connection itself
-func GetsqliteDataStoreObj() *SqliteDataStore {
-    //Initialize the global variable.
-    dbOnce.Do(func() {
-        sqlObj = new(SqliteDataStore)
-        sqlObj.dblogger = logger.GetLoggerInstance()
-    })
-    return sqlObj
+
+//sqlite has no options, the DB file is the data source.
+func newDataStore(
+        options dataStore.BackendOptions) (dataStore.DataSetInterface, error) {
+    err := options.Check()
+    if err != nil {
+        return nil, err
+    }
+    return rdbms.NewRdbmsDataStore(new(sqliteDialect)), nil
 }
@@ -15,6 +15,7 @@
 package dataStore
 
 import (
+    "time"
 )
 
 //Dataset Interface that provides the APIs exposed by datastore implementation.
@@ -24,10 +25,104 @@ type DataSetInterface interface {
     //implementation.
     CreateDataStoreTables() error
 
-    //APIs to intract with dataset
-    CreateRecord(rec *Data) error
-    DeleteRecord(recid string) error
-    GetRecord(recid string) (*Data, error)
-    GetRecordByName(name string)([]Data, error)
-    GetAllRecords()([]Data, error)
-}
\ No newline at end of file
+    //APIs to manage the trees. Every tree is created with its own root.
+    CreateTree(tree *Tree) error
+    DeleteTree(treeId string) error
+    GetTree(treeId string) (*Tree, error)
+    GetAllTrees() ([]Tree, error)
+    // Check the nested set limits of the tree against the parent links.
+    VerifyTree(treeId string) (*TreeReport, error)
+    // Recompute the nested set limits of the tree from the parent links.
+    RebuildTree(treeId string) error
+
+    //APIs to intract with dataset, all of them are scoped to a tree. The
+    // changes are recorded in the history along with the 'actor' who made
+    // them. Changes of a record with a 'version' fail when the record, or
+    // its subtree for the delete and move, is changed after the version.
+    // ANY_VERSION skips the check.
+    CreateRecord(treeId string, rec *Data, actor string) error
+    DeleteRecord(treeId string, recid string, version int64,
+                 actor string) error
+    // Delete the record only when it has no childrens.
+    DeleteLeafRecord(treeId string, recid string, version int64,
+                     actor string) error
+    // Delete the record and move its childrens to its parent.
+    PromoteRecord(treeId string, recid string, version int64,
+                  actor string) error
+    // Update the name, description, type and attributes of the record.
+    UpdateRecord(treeId string, rec *Data, version int64, actor string) error
+    // Move the record and all its childrens under a new parent.
+    MoveRecord(treeId string, recid string, newPuid string, version int64,
+               actor string) error
+    // Copy the record and all its childrens under 'destPuid' with new ids,
+    // returns the copied record.
+    CopySubtree(treeId string, srcId string, destPuid string,
+                actor string) (*Data, error)
+    // Get the record along with the version of its subtree.
+    GetRecord(treeId string, recid string) (*Data, error)
+    // Get the path from root to the record, including the record itself.
+    GetAncestors(treeId string, recid string) ([]Data, error)
+    // Get all the childrens of the record in pre-order, upto 'maxDepth'
+    // levels below the record. 'maxDepth' <= 0 returns the entire subtree.
+    GetDescendants(treeId string, recid string, maxDepth int) ([]Data, error)
+    // Get the direct childrens of the record in order.
+    GetChildren(treeId string, recid string) ([]Data, error)
+    GetRecordByName(treeId string, name string)([]Data, error)
+    GetAllRecords(treeId string)([]Data, error)
+    // Get a page of the records in pre-order, only the records matching the
+    // filter of the request are returned and counted.
+    GetRecordsPage(treeId string, request *PageRequest) (*RecordPage, error)
+    // Get a page of the records with the name in pre-order.
+    GetRecordByNamePage(treeId string, name string,
+                        request *PageRequest) (*RecordPage, error)
+
+    //APIs of the trash, soft deleted subtrees are kept in the trash of their
+    // tree till they are restored or purged.
+    // Move the record and all its childrens to the trash.
+    TrashRecord(treeId string, recid string, version int64,
+                actor string) error
+    // Get the subtrees in the trash of the tree, ordered on delete time.
+    GetTrash(treeId string) ([]TrashEntry, error)
+    // Restore the subtree as the last child of 'puid', or of its original
+    // parent when 'puid' is empty.
+    RestoreRecord(treeId string, recid string, puid string,
+                  actor string) error
+    // Remove the subtrees deleted before 'before' from all the trees, returns
+    // the number of subtrees removed.
+    PurgeTrash(before time.Time) (int, error)
+
+    //APIs of the history, it keeps every change of the records and is never
+    // updated. History of the records is kept after they are deleted.
+    // Get the changes of the record in the order of time.
+    GetRecordHistory(treeId string, recid string) ([]HistoryEntry, error)
+    // Get the changes of the records in all the trees made at or after
+    // 'since', in the order of time.
+    GetAudit(since time.Time) ([]HistoryEntry, error)
+
+    //APIs of the snapshots, a snapshot is a named copy of all the records of
+    // a tree at the time its created.
+    // Create the snapshot of the tree, the id of the snapshot is generated.
+    CreateSnapshot(treeId string, snapshot *Snapshot) error
+    DeleteSnapshot(treeId string, snapshotId string) error
+    GetSnapshot(treeId string, snapshotId string) (*Snapshot, error)
+    // Get the snapshots of the tree, ordered on create time.
+    GetAllSnapshots(treeId string) ([]Snapshot, error)
+    // Get the record and all its childrens in pre-order as of the snapshot,
+    // all the records of the snapshot when 'recid' is empty.
+    GetSnapshotRecords(treeId string, snapshotId string,
+                       recid string) ([]Data, error)
+    // Restore the subtree of the record from the snapshot in place of the
+    // current record and its childrens, the entire tree is restored when
+    // 'recid' is empty.
+    RestoreSnapshot(treeId string, snapshotId string, recid string,
+                    actor string) error
+}
+
+//Datastores with a versioned schema provide the migrations along with the
+// DataSetInterface. Pending migrations are applied on CreateDataStoreTables.
+type MigrationInterface interface {
+    // Get all the migrations of the datastore in the order of version.
+    GetMigrations() ([]Migration, error)
+    // Apply all the pending migrations in order.
+    ApplyMigrations() error
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dataStore
+
+import (
+    "reflect"
+    "time"
+    "NestedSet/logger"
+    "NestedSet/sys"
+)
+
+//Actions of the changes in the history. Records removed and added by the
+// trash and restore are recorded as 'trash' and 'restore'.
+const (
+    HISTORY_CREATE = "create"
+    HISTORY_UPDATE = "update"
+    HISTORY_MOVE = "move"
+    HISTORY_DELETE = "delete"
+    HISTORY_TRASH = "trash"
+    HISTORY_RESTORE = "restore"
+)
+
+//Stored fields of a record at the time of a change, the nested set limits are
+// not part of it as they change with the other records.
+type RecordImage struct {
+    Puid string              `json:"puid"`
+    Name string              `json:"name"`
+    Desc string              `json:"desc"`
+    Type string              `json:"type,omitempty"`
+    Attributes Attributes    `json:"attributes,omitempty"`
+}
+
+func newRecordImage(rec *Data) *RecordImage {
+    return &RecordImage{rec.Puid, rec.Name, rec.Desc, rec.Type,
+                        rec.Attributes.Copy()}
+}
+
+//Change of a record in the history, 'Before' is the record before the change
+// and 'After' is the record after it. Changes made by an operation have the
+// same time and are ordered on 'Seq'.
+type HistoryEntry struct {
+    Id string               `json:"id"`
+    TreeId string           `json:"treeId"`
+    Uid string              `json:"uid"`
+    Seq int                 `json:"seq"`
+    Action string           `json:"action"`
+    Actor string            `json:"actor"`
+    ChangedAt string        `json:"changedAt"`
+    Before *RecordImage     `json:"before,omitempty"`
+    After *RecordImage      `json:"after,omitempty"`
+}
+
+//Time format of the history, its sortable as a string.
+const HISTORY_TIME_FORMAT = "2006-01-02T15:04:05.000000000Z07:00"
+
+func HistoryTimeString(t time.Time) string {
+    return t.UTC().Format(HISTORY_TIME_FORMAT)
+}
+
+//Operation on the records by 'Actor' that is recorded in the history. The
+// records added and removed by the operation are recorded with 'AddAction'
+// and 'RemoveAction'.
+type Change struct {
+    Actor string
+    AddAction string
+    RemoveAction string
+}
+
+func NewChange(actor string) *Change {
+    return &Change{actor, HISTORY_CREATE, HISTORY_DELETE}
+}
+
+//Get the history entries of the change from the records 'before' to the
+// records 'after' the operation. Records only in 'before' are removed, only
+// in 'after' are added and rest are recorded when they are moved or updated.
+func (change *Change)GetHistory(before []Data,
+                                after []Data) ([]HistoryEntry, error) {
+    entries := []HistoryEntry{}
+    changedAt := HistoryTimeString(time.Now())
+    addEntry := func(rec *Data, action string, beforeImg *RecordImage,
+                     afterImg *RecordImage) error {
+        id, err := sys.NewUUIDString()
+        if err != nil {
+            logger.GetLoggerInstance().Error("Failed to create UUID, " +
+                                             "cannot record the change of %s",
+                                             rec.Uid)
+            return err
+        }
+        entries = append(entries, HistoryEntry{id, rec.TreeId, rec.Uid,
+                                               len(entries), action,
+                                               change.Actor, changedAt,
+                                               beforeImg, afterImg})
+        return nil
+    }
+    afterRecs := make(map[string]*Data)
+    for i := range after {
+        afterRecs[after[i].Uid] = &after[i]
+    }
+    beforeRecs := make(map[string]bool)
+    var err error
+    for i := range before {
+        rec := &before[i]
+        beforeRecs[rec.Uid] = true
+        beforeImg := newRecordImage(rec)
+        afterRec, ok := afterRecs[rec.Uid]
+        if !ok {
+            err = addEntry(rec, change.RemoveAction, beforeImg, nil)
+        } else if afterImg := newRecordImage(afterRec);
+                  afterImg.Puid != beforeImg.Puid {
+            err = addEntry(rec, HISTORY_MOVE, beforeImg, afterImg)
+        } else if !reflect.DeepEqual(afterImg, beforeImg) {
+            err = addEntry(rec, HISTORY_UPDATE, beforeImg, afterImg)
+        }
+        if err != nil {
+            return nil, err
+        }
+    }
+    for i := range after {
+        rec := &after[i]
+        if beforeRecs[rec.Uid] {
+            continue
+        }
+        err = addEntry(rec, change.AddAction, nil, newRecordImage(rec))
+        if err != nil {
+            return nil, err
+        }
+    }
+    return entries, nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dataStore
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "github.com/xeipuuv/gojsonschema"
+    "NestedSet/appErrors"
+    "NestedSet/logger"
+)
+
+//Type of the records, attributes of a typed record must be valid for the JSON
+// schema of its type. 'Children' are the types allowed as the childrens of a
+// record of the type, any type is allowed when its null and none when its
+// empty. Records without type are not validated and take any childrens.
+type NodeType struct {
+    Name string                 `json:"name"`
+    Desc string                 `json:"desc,omitempty"`
+    Schema json.RawMessage      `json:"schema,omitempty"`
+    Children []string           `json:"children"`
+    schema *gojsonschema.Schema
+}
+
+//Violation of the type rules by a record, 'Field' is the field of the record
+// or the path of the attribute.
+type TypeViolation struct {
+    Field string    `json:"field"`
+    Message string  `json:"message"`
+}
+
+//Error of a record that violates the type rules, it lists all the violations.
+type TypeError struct {
+    Violations []TypeViolation  `json:"violations"`
+}
+
+func (typeErr *TypeError)Error() string {
+    msgs := []string{}
+    for _, violation := range typeErr.Violations {
+        msgs = append(msgs, violation.Field + ": " + violation.Message)
+    }
+    return "Record violates the type rules, " + strings.Join(msgs, ", ")
+}
+
+var nodeTypeLock sync.RWMutex
+var nodeTypes = make(map[string]*NodeType)
+
+//Set the types of the records, the types that are set already are replaced.
+// Childrens of the types must be in the types as well.
+func SetNodeTypes(types []NodeType) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    newTypes := make(map[string]*NodeType)
+    for i := range types {
+        nodeType := types[i]
+        if len(nodeType.Name) == 0 {
+            log.Error("Cannot register a type with empty name")
+            return appErrors.INVALID_INPUT
+        }
+        if _, ok := newTypes[nodeType.Name]; ok {
+            log.Error("Type %s is present more than once", nodeType.Name)
+            return appErrors.DATA_PRESENT_IN_SYSTEM
+        }
+        if len(nodeType.Schema) != 0 {
+            nodeType.schema, err = gojsonschema.NewSchema(
+                            gojsonschema.NewBytesLoader(nodeType.Schema))
+            if err != nil {
+                log.Error("Invalid schema of type %s err : %s",
+                           nodeType.Name, err)
+                return appErrors.INVALID_INPUT
+            }
+        }
+        newTypes[nodeType.Name] = &nodeType
+    }
+    for _, nodeType := range newTypes {
+        for _, child := range nodeType.Children {
+            if _, ok := newTypes[child]; !ok {
+                log.Error("Children %s of type %s is not a type", child,
+                           nodeType.Name)
+                return appErrors.INVALID_INPUT
+            }
+        }
+    }
+    nodeTypeLock.Lock()
+    defer nodeTypeLock.Unlock()
+    nodeTypes = newTypes
+    return nil
+}
+
+//Get all the types in the order of name.
+func GetNodeTypes() []NodeType {
+    nodeTypeLock.RLock()
+    defer nodeTypeLock.RUnlock()
+    types := make([]NodeType, 0, len(nodeTypes))
+    for _, nodeType := range nodeTypes {
+        types = append(types, *nodeType)
+    }
+    sort.Slice(types, func(i, j int) bool {
+        return types[i].Name < types[j].Name
+    })
+    return types
+}
+
+//Get the type with 'name'.
+func GetNodeType(name string) (*NodeType, error) {
+    nodeTypeLock.RLock()
+    defer nodeTypeLock.RUnlock()
+    nodeType, ok := nodeTypes[name]
+    if !ok {
+        return nil, appErrors.DATA_NOT_FOUND
+    }
+    typeCopy := *nodeType
+    return &typeCopy, nil
+}
+
+//Check a record of 'childType' is allowed under a record of 'parentType'.
+func (nodeType *NodeType)allowsChild(childType string) bool {
+    if nodeType.Children == nil {
+        return true
+    }
+    for _, child := range nodeType.Children {
+        if child == childType {
+            return true
+        }
+    }
+    return false
+}
+
+//Get the violation of a record of 'childType' under a record of 'parentType',
+// nil when its allowed.
+func getChildViolation(parentType string, childType string) *TypeViolation {
+    parent, ok := nodeTypes[parentType]
+    if len(parentType) == 0 || !ok || parent.allowsChild(childType) {
+        return nil
+    }
+    if len(childType) == 0 {
+        return &TypeViolation{"type", fmt.Sprintf("Records without type " +
+                              "are not allowed under type %s", parentType)}
+    }
+    return &TypeViolation{"type", fmt.Sprintf("Type %s is not allowed " +
+                          "under type %s", childType, parentType)}
+}
+
+//Validate the type and attributes of the record, its rules on the parent and
+// childrens are not checked.
+func getTypeViolations(rec *Data) []TypeViolation {
+    if len(rec.Type) == 0 {
+        return nil
+    }
+    nodeType, ok := nodeTypes[rec.Type]
+    if !ok {
+        return []TypeViolation{{"type", fmt.Sprintf("Type %s is not " +
+                                                    "registered", rec.Type)}}
+    }
+    if nodeType.schema == nil {
+        return nil
+    }
+    //Record without attributes is validated as an empty object.
+    attrs := map[string]interface{}(rec.Attributes)
+    if attrs == nil {
+        attrs = map[string]interface{}{}
+    }
+    result, err := nodeType.schema.Validate(gojsonschema.NewGoLoader(attrs))
+    if err != nil {
+        return []TypeViolation{{"attributes", err.Error()}}
+    }
+    violations := []TypeViolation{}
+    for _, resErr := range result.Errors() {
+        field := "attributes"
+        if resErr.Field() != gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+            field += "." + resErr.Field()
+        }
+        violations = append(violations,
+                            TypeViolation{field, resErr.Description()})
+    }
+    return violations
+}
+
+func newTypeError(violations []TypeViolation) error {
+    if len(violations) == 0 {
+        return nil
+    }
+    return &TypeError{violations}
+}
+
+//Validate the type and attributes of the record, returns the TypeError with
+// the violations.
+func ValidateRecordType(rec *Data) error {
+    nodeTypeLock.RLock()
+    defer nodeTypeLock.RUnlock()
+    return newTypeError(getTypeViolations(rec))
+}
+
+//Validate the record along with the rules of the types on its parent and its
+// childrens, returns the TypeError with all the violations.
+func CheckRecordType(rec *Data, parentType string, childTypes []string) error {
+    nodeTypeLock.RLock()
+    defer nodeTypeLock.RUnlock()
+    violations := getTypeViolations(rec)
+    violation := getChildViolation(parentType, rec.Type)
+    if violation != nil {
+        violations = append(violations, *violation)
+    }
+    for _, childType := range childTypes {
+        violation = getChildViolation(rec.Type, childType)
+        if violation != nil {
+            violation.Field = "children"
+            violations = append(violations, *violation)
+            break
+        }
+    }
+    return newTypeError(violations)
+}
+
+//Check a record of 'childType' can be placed under a record of 'parentType',
+// used when the records are moved.
+func CheckChildType(parentType string, childType string) error {
+    nodeTypeLock.RLock()
+    defer nodeTypeLock.RUnlock()
+    violation := getChildViolation(parentType, childType)
+    if violation == nil {
+        return nil
+    }
+    return &TypeError{[]TypeViolation{*violation}}
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dataStore
+
+import (
+)
+
+//Records are listed in pages in pre-order(sorted on LftId). The cursor of a
+// page is the LftId of the last record in the previous page, so the pages
+// stay in tree order without reading the records before the cursor.
+const (
+    DEFAULT_PAGE_LIMIT = 100
+    MAX_PAGE_LIMIT = 1000
+)
+
+//Filter of the records on their name, computed fields, type and attributes.
+// Empty fields are not checked.
+type RecordFilter struct {
+    Name *string
+    Depth *int64
+    IsLeaf *bool
+    Type *string
+    Attributes map[string]string
+}
+
+//Request of a page, records with LftId greater than 'After' are returned upto
+// 'Limit' records. 'After' is 0 for the first page.
+type PageRequest struct {
+    After int64
+    Limit int
+    Filter RecordFilter
+}
+
+//A page of the records, 'Total' is the number of records in all the pages and
+// 'Next' is the cursor of the next page, 0 on the last page.
+type RecordPage struct {
+    Records []Data
+    Total int64
+    Next int64
+}
+
+func (filter *RecordFilter)IsEmpty() bool {
+    return filter.Name == nil && filter.Depth == nil && filter.IsLeaf == nil &&
+           filter.Type == nil && len(filter.Attributes) == 0
+}
+
+//Check the record matches the filter, computed fields of the record must be
+// updated before.
+func (filter *RecordFilter)Match(rec *Data) bool {
+    if filter.Name != nil && rec.Name != *filter.Name {
+        return false
+    }
+    if filter.Depth != nil && rec.Depth != *filter.Depth {
+        return false
+    }
+    if filter.IsLeaf != nil && rec.IsLeaf != *filter.IsLeaf {
+        return false
+    }
+    if filter.Type != nil && rec.Type != *filter.Type {
+        return false
+    }
+    for key, value := range filter.Attributes {
+        if !rec.Attributes.HasValue(key, value) {
+            return false
+        }
+    }
+    return true
+}
+
+//Get the LftId after which the records are read for the page. All the records
+// are read to count the total when they are filtered, else the reading starts
+// at the cursor.
+func (request *PageRequest)ReadFrom() int64 {
+    if request.Filter.IsEmpty() {
+        return request.After
+    }
+    return 0
+}
+
+//Build a page from the records read in pre-order from 'ReadFrom'. All the
+// records are read when they are filtered, else the reading stops at the
+// first record of next page and the datastore sets the total.
+type PageBuilder struct {
+    request *PageRequest
+    //Filter checked on the records added.
+    filter *RecordFilter
+    countsAll bool
+    page *RecordPage
+    //Right limits of the parents of current record.
+    parentRgtIds []int64
+}
+
+//Create the builder of a page, 'parents' are the records before 'ReadFrom'
+// whose subtree is not ended there, ordered from the root.
+func NewPageBuilder(request *PageRequest, parents []Data) *PageBuilder {
+    builder := new(PageBuilder)
+    builder.request = request
+    builder.filter = &request.Filter
+    builder.countsAll = !request.Filter.IsEmpty()
+    builder.page = &RecordPage{Records: []Data{}}
+    for _, parent := range parents {
+        builder.parentRgtIds = append(builder.parentRgtIds, parent.RgtId)
+    }
+    return builder
+}
+
+//Create the builder of a page from the records read after the cursor, when
+// the datastore filters the records on all but 'filter' and counts the total.
+func NewSeekPageBuilder(request *PageRequest, parents []Data,
+                        filter *RecordFilter) *PageBuilder {
+    builder := NewPageBuilder(request, parents)
+    builder.filter = filter
+    builder.countsAll = false
+    return builder
+}
+
+//Check all the records are read to count the total.
+func (builder *PageBuilder)CountsAll() bool {
+    return builder.countsAll
+}
+
+//Add the next record in pre-order, the computed fields of the record are
+// updated from the previous records. Returns false when no more records are
+// needed.
+func (builder *PageBuilder)AddRecord(rec *Data) bool {
+    for len(builder.parentRgtIds) != 0 &&
+        builder.parentRgtIds[len(builder.parentRgtIds) - 1] < rec.LftId {
+        builder.parentRgtIds =
+                        builder.parentRgtIds[:len(builder.parentRgtIds) - 1]
+    }
+    rec.Depth = int64(len(builder.parentRgtIds))
+    rec.UpdateSubtreeInfo()
+    builder.parentRgtIds = append(builder.parentRgtIds, rec.RgtId)
+    return builder.AddRecordInfo(rec)
+}
+
+//Add the next record in pre-order, the computed fields of the record must be
+// updated before. Returns false when no more records are needed.
+func (builder *PageBuilder)AddRecordInfo(rec *Data) bool {
+    if !builder.filter.Match(rec) {
+        return true
+    }
+    page := builder.page
+    page.Total++
+    if rec.LftId <= builder.request.After {
+        return true
+    }
+    if len(page.Records) < builder.request.Limit {
+        page.Records = append(page.Records, *rec)
+        return true
+    }
+    if page.Next == 0 && len(page.Records) != 0 {
+        page.Next = page.Records[len(page.Records) - 1].LftId
+    }
+    return builder.CountsAll()
+}
+
+//Get the page, 'total' is the number of records in all the pages when the
+// records are not filtered.
+func (builder *PageBuilder)GetPage(total int64) *RecordPage {
+    if !builder.CountsAll() {
+        builder.page.Total = total
+    }
+    return builder.page
+}
@@ -0,0 +1,158 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dataStore
+
+import (
+    "sort"
+    "time"
+    "NestedSet/appErrors"
+    "NestedSet/logger"
+    "NestedSet/sys"
+)
+
+//Named copy of all the records of a tree at the time its created. The records
+// can be read and restored from the snapshot till its deleted.
+type Snapshot struct {
+    SnapshotId string  `json:"snapshotId" db:"SnapshotId"`
+    TreeId string      `json:"treeId" db:"TreeId"`
+    Name string        `json:"name" db:"Name"`
+    Desc string        `json:"desc" db:"Desc"`
+    CreatedAt string   `json:"createdAt" db:"CreatedAt"`
+    //Number of records in the snapshot.
+    Size int           `json:"size" db:"Size"`
+}
+
+//Time format of the snapshots, its sortable as a string.
+func SnapshotTimeString(t time.Time) string {
+    return t.UTC().Format(time.RFC3339)
+}
+
+//Set the id and the create time of a new snapshot of the tree with 'size'
+// records. The name of the snapshot must be present.
+func (snapshot *Snapshot)Init(treeId string, size int,
+                              createdAt time.Time) error {
+    var err error
+    log := logger.GetLoggerInstance()
+    if len(snapshot.Name) == 0 {
+        log.Error("Cannot create a snapshot with empty name")
+        return appErrors.INVALID_INPUT
+    }
+    snapshot.SnapshotId, err = sys.NewUUIDString()
+    if err != nil {
+        log.Error("Failed to create UUID, cannot create a snapshot")
+        return err
+    }
+    snapshot.TreeId = treeId
+    snapshot.Size = size
+    snapshot.CreatedAt = SnapshotTimeString(createdAt)
+    return nil
+}
+
+//Sort the snapshots on their create time.
+func SortSnapshots(snapshots []Snapshot) {
+    sort.Slice(snapshots, func(i, j int) bool {
+        if snapshots[i].CreatedAt != snapshots[j].CreatedAt {
+            return snapshots[i].CreatedAt < snapshots[j].CreatedAt
+        }
+        return snapshots[i].Name < snapshots[j].Name
+    })
+}
+
+//Get the record 'uid' and all its childrens in pre-order from the records of
+// a snapshot, all the records when 'uid' is empty. Records of the snapshot
+// must be in pre-order.
+func GetSnapshotSubtree(recs []Data, uid string) ([]Data, error) {
+    rows := make([]Data, 0, len(recs))
+    for _, rec := range recs {
+        rec.Attributes = rec.Attributes.Copy()
+        //Versions are of the records in the tree, not of the snapshot.
+        rec.Version = 0
+        rec.ChildVersion = 0
+        rows = append(rows, rec)
+    }
+    UpdateTreeInfo(rows, 0)
+    if len(uid) == 0 {
+        return rows, nil
+    }
+    for i := range rows {
+        if rows[i].Uid != uid {
+            continue
+        }
+        end := i + 1
+        for end < len(rows) && rows[end].LftId < rows[i].RgtId {
+            end++
+        }
+        return rows[i:end], nil
+    }
+    logger.GetLoggerInstance().Error("Record %s is not present in the " +
+                                     "snapshot", uid)
+    return nil, appErrors.DATA_NOT_FOUND
+}
+
+//Get the records of the snapshot to restore the entire tree, computed fields
+// are not restored.
+func GetSnapshotRestoreRecords(recs []Data) []Data {
+    rows := make([]Data, 0, len(recs))
+    for _, rec := range recs {
+        rows = append(rows, Data{Uid: rec.Uid, TreeId: rec.TreeId,
+                                 Puid: rec.Puid, Name: rec.Name,
+                                 Desc: rec.Desc, Type: rec.Type,
+                                 Attributes: rec.Attributes.Copy(),
+                                 LftId: rec.LftId, RgtId: rec.RgtId})
+    }
+    return rows
+}
+
+//Get the records of the tree dropped by restoring the snapshot records on
+// them, as the roots of the dropped subtrees in pre-order. 'current' are the
+// records replaced in pre-order, the dropped ones are not in the snapshot.
+// Fails when a record of the snapshot is under a dropped record, as it cannot
+// be both restored and moved to the trash.
+func GetSnapshotDropped(current []Data, recs []Data) ([]Data, error) {
+    inSnapshot := make(map[string]bool)
+    for _, rec := range recs {
+        inSnapshot[rec.Uid] = true
+    }
+    dropped := []Data{}
+    for _, rec := range current {
+        if len(dropped) != 0 &&
+           rec.LftId < dropped[len(dropped) - 1].RgtId {
+            if inSnapshot[rec.Uid] == true {
+                logger.GetLoggerInstance().Error("Cannot restore the " +
+                                       "snapshot, record %s is under %s " +
+                                       "which is not in the snapshot",
+                                       rec.Uid, dropped[len(dropped) - 1].Uid)
+                return nil, appErrors.DATA_PRESENT_IN_SYSTEM
+            }
+            continue
+        }
+        if inSnapshot[rec.Uid] == false {
+            dropped = append(dropped, rec)
+        }
+    }
+    return dropped, nil
+}
+
+//Get the siblings before the record 'rec' of a snapshot from the records of
+// the snapshot, the nearest first. The record is restored after the first of
+// them present in the tree.
+func GetSnapshotPrevSiblings(recs []Data, rec *Data) []string {
+    siblings := []string{}
+    for i := len(recs) - 1; i >= 0; i-- {
+        if recs[i].Puid == rec.Puid && recs[i].RgtId < rec.LftId {
+            siblings = append(siblings, recs[i].Uid)
+        }
+    }
+    return siblings
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dataStore
+
+import (
+    "fmt"
+    "NestedSet/appErrors"
+    "NestedSet/logger"
+    "NestedSet/sys"
+)
+
+const (
+    //Copies of a record under a parent that already has its name are named
+    // 'name (copy)', 'name (copy 2)' and so on, upto the limit.
+    MAX_COPY_NAMES = 100
+)
+
+//Name of the n-th copy of a record, 0 is the name of the record itself.
+func GetCopyName(name string, n int) string {
+    switch n {
+    case 0:
+        return name
+    case 1:
+        return name + " (copy)"
+    }
+    return fmt.Sprintf("%s (copy %d)", name, n)
+}
+
+//Find the first name of the copy that is not present under the parent,
+// 'isPresent' checks a name under the parent.
+func FindCopyName(name string,
+                  isPresent func(string) (bool, error)) (string, error) {
+    for n := 0; n < MAX_COPY_NAMES; n++ {
+        copyName := GetCopyName(name, n)
+        present, err := isPresent(copyName)
+        if err != nil {
+            return "", err
+        }
+        if !present {
+            return copyName, nil
+        }
+    }
+    logger.GetLoggerInstance().Info("Cannot copy %s, too many copies " +
+                                    "present under the parent", name)
+    return "", appErrors.DATA_PRESENT_IN_SYSTEM
+}
+
+//Copy the record and all its childrens in pre-order with fresh record ids.
+// The copied record gets the 'name' and the parent 'puid', and its placed at
+// the limit 'pos' of the tree.
+func CopySubtreeRecords(recs []Data, treeId string, puid string, name string,
+                        pos int64) ([]Data, error) {
+    var err error
+    if len(recs) == 0 {
+        return nil, appErrors.INVALID_INPUT
+    }
+    base := recs[0].LftId
+    newIds := make(map[string]string)
+    copies := make([]Data, 0, len(recs))
+    for _, rec := range recs {
+        newRec := Data{TreeId: treeId, Puid: newIds[rec.Puid],
+                       Name: rec.Name, Desc: rec.Desc, Type: rec.Type,
+                       Attributes: rec.Attributes.Copy(),
+                       LftId: rec.LftId - base + pos,
+                       RgtId: rec.RgtId - base + pos}
+        newRec.Uid, err = sys.NewUUIDString()
+        if err != nil {
+            logger.GetLoggerInstance().Error("Failed to create UUID, " +
+                                             "cannot copy %s", rec.Uid)
+            return nil, err
+        }
+        newIds[rec.Uid] = newRec.Uid
+        copies = append(copies, newRec)
+    }
+    copies[0].Puid = puid
+    copies[0].Name = name
+    return copies, nil
+}
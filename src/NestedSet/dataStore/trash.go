@@ -0,0 +1,121 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataStore
+
+import (
+    "sort"
+    "time"
+)
+
+//Subtree in the trash of a tree, its moved to the trash on soft delete and
+// kept till its restored or purged. The entry is identified by the id of the
+// deleted record, 'Puid' is its original parent.
+type TrashEntry struct {
+    Uid string          `json:"uid"`
+    TreeId string       `json:"treeId"`
+    Puid string         `json:"puid"`
+    Name string         `json:"name"`
+    DeletedAt string    `json:"deletedAt"`
+    //Deleted record and all its childrens in pre-order, nested set limits
+    // and depth are relative to the deleted record.
+    Records []Data      `json:"records"`
+}
+
+//Time format of the trash entries, its sortable as a string.
+func TrashTimeString(t time.Time) string {
+    return t.UTC().Format(time.RFC3339)
+}
+
+//Create the trash entry of a subtree from the record and all its childrens in
+// pre-order. The subtree keeps its structure, limits of the records are made
+// relative to the deleted record so it can be restored anywhere.
+func NewTrashEntry(recs []Data, deletedAt time.Time) *TrashEntry {
+    entry := new(TrashEntry)
+    if len(recs) == 0 {
+        return entry
+    }
+    base := recs[0].LftId - 1
+    entry.Uid = recs[0].Uid
+    entry.TreeId = recs[0].TreeId
+    entry.Puid = recs[0].Puid
+    entry.Name = recs[0].Name
+    entry.DeletedAt = TrashTimeString(deletedAt)
+    entry.Records = make([]Data, 0, len(recs))
+    for _, rec := range recs {
+        entry.Records = append(entry.Records,
+                               Data{Uid: rec.Uid, TreeId: rec.TreeId,
+                                    Puid: rec.Puid, Name: rec.Name,
+                                    Desc: rec.Desc, Type: rec.Type,
+                                    Attributes: rec.Attributes.Copy(),
+                                    LftId: rec.LftId - base,
+                                    RgtId: rec.RgtId - base})
+    }
+    UpdateTreeInfo(entry.Records, 0)
+    return entry
+}
+
+//Width of the nested set limits of the subtree.
+func (entry *TrashEntry)Width() int64 {
+    return 2 * int64(len(entry.Records))
+}
+
+//Get the records of the subtree to restore it under 'puid', the deleted
+// record is placed at the limit 'pos'.
+func (entry *TrashEntry)GetRestoreRecords(treeId string, puid string,
+                                          pos int64) []Data {
+    recs := make([]Data, 0, len(entry.Records))
+    for _, rec := range entry.Records {
+        //Computed fields are not restored.
+        rec = Data{Uid: rec.Uid, Puid: rec.Puid, Name: rec.Name,
+                   Desc: rec.Desc, Type: rec.Type,
+                   Attributes: rec.Attributes.Copy(),
+                   LftId: rec.LftId, RgtId: rec.RgtId}
+        rec.TreeId = treeId
+        rec.LftId += pos - 1
+        rec.RgtId += pos - 1
+        if rec.Uid == entry.Uid {
+            rec.Puid = puid
+        }
+        recs = append(recs, rec)
+    }
+    return recs
+}
+
+//Sort the trash entries on their delete time.
+func SortTrashEntries(entries []TrashEntry) {
+    sort.Slice(entries, func(i, j int) bool {
+        if entries[i].DeletedAt != entries[j].DeletedAt {
+            return entries[i].DeletedAt < entries[j].DeletedAt
+        }
+        return entries[i].Uid < entries[j].Uid
+    })
+}
+
+//Get the ids of the trash entries whose deleted record is put back in the
+// tree by restoring the records 'recs', e.g. from a snapshot. The entries are
+// stale as the record can be deleted again.
+func GetRestoredTrash(entries []TrashEntry, recs []Data) []string {
+    uids := make(map[string]bool)
+    for _, rec := range recs {
+        uids[rec.Uid] = true
+    }
+    restored := []string{}
+    for _, entry := range entries {
+        if uids[entry.Uid] {
+            restored = append(restored, entry.Uid)
+        }
+    }
+    return restored
+}
@@ -0,0 +1,246 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataStore
+
+import (
+    "fmt"
+    "sort"
+    "NestedSet/appErrors"
+)
+
+//Type of issues reported by the tree integrity check.
+const (
+    ISSUE_ROOT = "root"
+    ISSUE_UNSET_LIMITS = "unset-limits"
+    ISSUE_INVALID_LIMITS = "invalid-limits"
+    ISSUE_DUPLICATE_LIMITS = "duplicate-limits"
+    ISSUE_LIMIT_GAPS = "limit-gaps"
+    ISSUE_OVERLAP = "overlap"
+    ISSUE_ORPHAN = "orphan"
+    ISSUE_PARENT_MISMATCH = "parent-mismatch"
+)
+
+type TreeIssue struct {
+    Uid string      `json:"uid"`
+    Issue string    `json:"issue"`
+    Detail string   `json:"detail"`
+}
+
+//Result of the integrity check of a tree, tree is valid when there are no
+// issues.
+type TreeReport struct {
+    TreeId string       `json:"treeId"`
+    Records int         `json:"records"`
+    Issues []TreeIssue  `json:"issues"`
+}
+
+func (report *TreeReport)IsValid() bool {
+    return len(report.Issues) == 0
+}
+
+func (report *TreeReport)addIssue(uid string, issue string,
+                                  detailfmt string, args ...interface{}) {
+    report.Issues = append(report.Issues,
+                           TreeIssue{uid, issue, fmt.Sprintf(detailfmt, args...)})
+}
+
+func isNSLimitUnset(rec *Data) bool {
+    return rec.LftId == DEFAULT_LFTID || rec.RgtId == DEFAULT_RGTID
+}
+
+//Verify the nested set limits of all the records in a tree against their
+// parent links. 'rootUid' is the root record of the tree.
+func VerifyTree(treeId string, rootUid string, recs []Data) *TreeReport {
+    report := new(TreeReport)
+    report.TreeId = treeId
+    report.Records = len(recs)
+    report.Issues = []TreeIssue{}
+    recMap := make(map[string]*Data, len(recs))
+    for i := range recs {
+        recMap[recs[i].Uid] = &recs[i]
+    }
+    if _, ok := recMap[rootUid]; !ok {
+        report.addIssue(rootUid, ISSUE_ROOT, "Root record is not present")
+    }
+    limitRecs := []*Data{}
+    limitOwners := make(map[int64]string, 2 * len(recs))
+    for i := range recs {
+        rec := &recs[i]
+        if len(rec.Puid) == 0 && rec.Uid != rootUid {
+            report.addIssue(rec.Uid, ISSUE_ROOT,
+                            "Record without parent is not the tree root")
+        }
+        if len(rec.Puid) != 0 {
+            if _, ok := recMap[rec.Puid]; !ok {
+                report.addIssue(rec.Uid, ISSUE_ORPHAN,
+                                "Parent %s is not present", rec.Puid)
+            }
+        }
+        if isNSLimitUnset(rec) {
+            report.addIssue(rec.Uid, ISSUE_UNSET_LIMITS,
+                            "Limits are not set (%d, %d)",
+                            rec.LftId, rec.RgtId)
+            continue
+        }
+        if rec.LftId >= rec.RgtId {
+            report.addIssue(rec.Uid, ISSUE_INVALID_LIMITS,
+                            "Left limit %d is not less than right limit %d",
+                            rec.LftId, rec.RgtId)
+            continue
+        }
+        for _, limit := range []int64{rec.LftId, rec.RgtId} {
+            if owner, ok := limitOwners[limit]; ok {
+                report.addIssue(rec.Uid, ISSUE_DUPLICATE_LIMITS,
+                                "Limit %d is also used by %s", limit, owner)
+                continue
+            }
+            limitOwners[limit] = rec.Uid
+        }
+        limitRecs = append(limitRecs, rec)
+    }
+    contiguous := int64(len(limitOwners)) == 2 * int64(len(recs))
+    for limit := int64(1); contiguous == true &&
+                           limit <= int64(len(limitOwners)); limit++ {
+        _, contiguous = limitOwners[limit]
+    }
+    if contiguous == false {
+        report.addIssue("", ISSUE_LIMIT_GAPS,
+                        "Limits of %d records are not contiguous from 1 to %d",
+                        len(recs), 2 * len(recs))
+    }
+    //Walk the records in pre-order, the closest enclosing record of every
+    // record must be its parent.
+    sort.Slice(limitRecs, func(i, j int) bool {
+        return limitRecs[i].LftId < limitRecs[j].LftId
+    })
+    parents := []*Data{}
+    for _, rec := range limitRecs {
+        for len(parents) != 0 &&
+            parents[len(parents) - 1].RgtId < rec.LftId {
+            parents = parents[:len(parents) - 1]
+        }
+        if len(parents) == 0 {
+            if rec.Uid != rootUid {
+                report.addIssue(rec.Uid, ISSUE_PARENT_MISMATCH,
+                                "Limits (%d, %d) are outside of the root",
+                                rec.LftId, rec.RgtId)
+            }
+            parents = append(parents, rec)
+            continue
+        }
+        parent := parents[len(parents) - 1]
+        if parent.RgtId < rec.RgtId {
+            report.addIssue(rec.Uid, ISSUE_OVERLAP,
+                            "Limits (%d, %d) overlap with %s (%d, %d)",
+                            rec.LftId, rec.RgtId, parent.Uid,
+                            parent.LftId, parent.RgtId)
+        } else if parent.Uid != rec.Puid {
+            report.addIssue(rec.Uid, ISSUE_PARENT_MISMATCH,
+                            "Limits are inside %s, but parent is %s",
+                            parent.Uid, rec.Puid)
+        }
+        parents = append(parents, rec)
+    }
+    return report
+}
+
+//Recompute the nested set limits of all the records in a tree from their
+// parent links. Childrens keep their current order and records with unset
+// limits are placed after their siblings. Records that cannot be reached from
+// the root(orphans and parent loops) are moved under the root.
+// Returns only the records that are changed by the rebuild.
+func RebuildTree(rootUid string, recs []Data) ([]Data, error) {
+    recMap := make(map[string]*Data, len(recs))
+    for i := range recs {
+        recMap[recs[i].Uid] = &recs[i]
+    }
+    if _, ok := recMap[rootUid]; !ok {
+        return nil, appErrors.INVALID_STATE
+    }
+    orderedRecs := make([]*Data, 0, len(recs))
+    for i := range recs {
+        orderedRecs = append(orderedRecs, &recs[i])
+    }
+    sort.SliceStable(orderedRecs, func(i, j int) bool {
+        iUnset := isNSLimitUnset(orderedRecs[i])
+        jUnset := isNSLimitUnset(orderedRecs[j])
+        if iUnset != jUnset {
+            return jUnset
+        }
+        return orderedRecs[i].LftId < orderedRecs[j].LftId
+    })
+    childrens := make(map[string][]*Data, len(recs))
+    for _, rec := range orderedRecs {
+        if rec.Uid == rootUid {
+            continue
+        }
+        childrens[rec.Puid] = append(childrens[rec.Puid], rec)
+    }
+    newRecs := make(map[string]Data, len(recs))
+    var counter int64
+    var assignLimits func(rec *Data)
+    assignLimits = func(rec *Data) {
+        newRec := *rec
+        counter++
+        newRec.LftId = counter
+        newRecs[rec.Uid] = newRec
+        for _, child := range childrens[rec.Uid] {
+            if _, visited := newRecs[child.Uid]; visited {
+                continue
+            }
+            assignLimits(child)
+        }
+        counter++
+        newRec.RgtId = counter
+        newRecs[rec.Uid] = newRec
+    }
+    root := recMap[rootUid]
+    root.Puid = ""
+    assignLimits(root)
+    //Records with missing parent are moved under root first, so their
+    // subtrees are kept. Remaining records are in parent loops.
+    unreachable := []*Data{}
+    for _, rec := range orderedRecs {
+        if _, visited := newRecs[rec.Uid]; visited {
+            continue
+        }
+        if _, ok := recMap[rec.Puid]; !ok {
+            unreachable = append([]*Data{rec}, unreachable...)
+            continue
+        }
+        unreachable = append(unreachable, rec)
+    }
+    rootRec := newRecs[rootUid]
+    counter = rootRec.RgtId - 1
+    for _, rec := range unreachable {
+        if _, visited := newRecs[rec.Uid]; visited {
+            continue
+        }
+        rec.Puid = rootUid
+        assignLimits(rec)
+    }
+    counter++
+    rootRec.RgtId = counter
+    newRecs[rootUid] = rootRec
+    changedRecs := []Data{}
+    for _, rec := range recs {
+        newRec := newRecs[rec.Uid]
+        if newRec.LftId != rec.LftId || newRec.RgtId != rec.RgtId ||
+           newRec.Puid != rec.Puid {
+            changedRecs = append(changedRecs, newRec)
+        }
+    }
+    return changedRecs, nil
+}
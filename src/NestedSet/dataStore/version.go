@@ -0,0 +1,154 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dataStore
+
+import (
+    "reflect"
+    "sort"
+    "NestedSet/appErrors"
+    "NestedSet/logger"
+)
+
+//Versions of the records come from the version of their tree, which is
+// incremented on every change of the tree. Version of a record is the tree
+// version when the record was last created, updated or moved. Child version
+// of a record is the tree version when the records below it were last added,
+// removed, updated or moved.
+//
+//Version of the record and its subtree is the latest of these two versions,
+// users give it back to make sure the record or its subtree is not changed
+// after they read it.
+const ANY_VERSION = -1
+
+//Version of a record after a change of its tree.
+type RecordVersion struct {
+    Uid string
+    Version int64
+    ChildVersion int64
+}
+
+//Get the versions of the records changed from the records 'before' to the
+// records 'after' a change, 'version' is the tree version of the change.
+// Versions of the records in 'after' are returned when they are different
+// from the ones in 'before'. Parents that are not in 'after' and whose
+// childrens are changed are returned separately, the child version of them
+// and of all their parents is the tree version.
+func GetRecordVersions(before []Data, after []Data,
+                       version int64) ([]RecordVersion, []string) {
+    beforeRecs := make(map[string]*Data)
+    for i := range before {
+        beforeRecs[before[i].Uid] = &before[i]
+    }
+    afterRecs := make(map[string]*Data)
+    for i := range after {
+        afterRecs[after[i].Uid] = &after[i]
+    }
+    changedParents := make(map[string]bool)
+    for i := range before {
+        rec := &before[i]
+        afterRec, ok := afterRecs[rec.Uid]
+        if !ok || afterRec.Puid != rec.Puid {
+            changedParents[rec.Puid] = true
+        }
+    }
+    versions := []RecordVersion{}
+    for i := range after {
+        rec := &after[i]
+        recVersion := RecordVersion{rec.Uid, version, version}
+        beforeRec, ok := beforeRecs[rec.Uid]
+        if !ok || beforeRec.Puid != rec.Puid {
+            changedParents[rec.Puid] = true
+        }
+        if ok {
+            //Versions in 'after' may not be of the tree, e.g. when the
+            // records are restored, so the versions are kept from 'before'.
+            recVersion.ChildVersion = beforeRec.ChildVersion
+            if reflect.DeepEqual(newRecordImage(rec),
+                                 newRecordImage(beforeRec)) {
+                recVersion.Version = beforeRec.Version
+            }
+        }
+        versions = append(versions, recVersion)
+    }
+    //Childrens are visited before their parents, so a changed record marks
+    // its parent changed before the parent is visited.
+    order := make([]int, len(versions))
+    for i := range order {
+        order[i] = i
+    }
+    sort.Slice(order, func(i, j int) bool {
+        return after[order[i]].LftId > after[order[j]].LftId
+    })
+    changedVersions := []RecordVersion{}
+    for _, i := range order {
+        recVersion := &versions[i]
+        if changedParents[recVersion.Uid] {
+            recVersion.ChildVersion = version
+            delete(changedParents, recVersion.Uid)
+        }
+        rec := afterRecs[recVersion.Uid]
+        if recVersion.Version == version ||
+           recVersion.ChildVersion == version {
+            changedParents[rec.Puid] = true
+        }
+        if rec.Version != recVersion.Version ||
+           rec.ChildVersion != recVersion.ChildVersion {
+            changedVersions = append(changedVersions, *recVersion)
+        }
+    }
+    parents := []string{}
+    for puid := range changedParents {
+        if len(puid) != 0 {
+            parents = append(parents, puid)
+        }
+    }
+    return changedVersions, parents
+}
+
+//Set the version of the record and its subtree from its versions.
+func (rec *Data)UpdateSubtreeVersion() {
+    rec.SubtreeVersion = rec.Version
+    if rec.ChildVersion > rec.SubtreeVersion {
+        rec.SubtreeVersion = rec.ChildVersion
+    }
+}
+
+//Check the version of the record and its subtree from the user before a
+// change, the subtree version of the record must be set. Changes of the
+// record fail when the record is changed after the version, changes of the
+// subtree fail when any record in the subtree is changed after it. Versions
+// after 'treeVersion', the current version of the tree, are never given to
+// the users so they fail as well.
+func (rec *Data)CheckVersion(version int64, treeVersion int64,
+                             subtree bool) error {
+    if version == ANY_VERSION {
+        return nil
+    }
+    if version > treeVersion {
+        logger.GetLoggerInstance().Info("Version %d of record %s is after " +
+                                        "the tree version %d", version,
+                                        rec.Uid, treeVersion)
+        return appErrors.DATA_VERSION_MISMATCH
+    }
+    recVersion := rec.Version
+    if subtree {
+        recVersion = rec.SubtreeVersion
+    }
+    if recVersion > version {
+        logger.GetLoggerInstance().Info("Record %s is changed after the " +
+                                        "version %d", rec.Uid, version)
+        return appErrors.DATA_VERSION_MISMATCH
+    }
+    return nil
+}
@@ -18,9 +18,15 @@ import (
     "os"
     "os/signal"
     "fmt"
+    "flag"
+    "strings"
+    "time"
+    "io/ioutil"
+    "encoding/json"
     "NestedSet/logger"
     "NestedSet/sys"
     "NestedSet/restAPI"
+    "NestedSet/dataStore"
     "NestedSet/dataStore/dataSetImpl"
 )
 ////////////////////////////////////////////////////////////////////////////////
@@ -31,9 +37,119 @@ const (
     SERVER_IP = "127.0.0.1"
     SERVER_PORT = "8080"
     DB_PATH = APP_DIR + "/nestedSet.db"
+    BOLT_DB_PATH = APP_DIR + "/nestedSet.bolt"
+    //Subtrees in the trash are purged after the retention, the trash is
+    // checked once in the interval.
+    TRASH_RETENTION = 30 * 24 * time.Hour
+    TRASH_PURGE_INTERVAL = time.Hour
 )
 ///////////////////////////////////////////////////////////////////////////////
 
+//Backend configuration of the application, from the config file and the
+// command line.
+type backendConfig struct {
+    Backend string                      `json:"backend"`
+    DataSource string                   `json:"dsn"`
+    Options dataStore.BackendOptions    `json:"options"`
+}
+
+//Backend options from the command line as 'key=value'.
+type optionFlags dataStore.BackendOptions
+
+func (options optionFlags)String() string {
+    pairs := []string{}
+    for key, value := range options {
+        pairs = append(pairs, key + "=" + value)
+    }
+    return strings.Join(pairs, ",")
+}
+
+func (options optionFlags)Set(option string) error {
+    pair := strings.SplitN(option, "=", 2)
+    if len(pair) != 2 || len(pair[0]) == 0 {
+        return fmt.Errorf("Option must be in key=value form")
+    }
+    options[pair[0]] = pair[1]
+    return nil
+}
+
+var (
+    configFile = flag.String("config", "",
+                    "JSON config file with the 'backend', 'dsn' and " +
+                    "'options' of the datastore")
+    backend = flag.String("backend", "",
+                    "Backend of the datastore, one of " +
+                    strings.Join(dataStore.GetBackendNames(), ", ") +
+                    " (default " + dataSetImpl.BACKEND_SQLITE + ")")
+    dataSource = flag.String("dsn", "",
+                    "Data source of the backend, DB file for sqlite and " +
+                    "bolt, connection string for postgres and mysql")
+    backendOptions = optionFlags{}
+    verifyOnStart = flag.Bool("verify", false,
+                    "Verify the nested set limits of all the trees at startup")
+    rebuildOnStart = flag.Bool("rebuild", false,
+                    "Rebuild the nested set limits of all the trees from " +
+                    "the parent links at startup")
+    softDelete = flag.Bool("soft-delete", false,
+                    "Move the deleted records to the trash, when the " +
+                    "delete request has no mode")
+    trashRetention = flag.Duration("trash-retention", TRASH_RETENTION,
+                    "Purge the records in the trash after the retention, " +
+                    "0 keeps them forever")
+    migrateOp = flag.String("migrate", "",
+                    "Print the schema migrations with 'status' or apply " +
+                    "the pending ones with 'apply', and exit")
+    typesFile = flag.String("types", "",
+                    "JSON file with the list of record types, their " +
+                    "attribute schema and allowed childrens")
+    //Data source of the backends that use a local file, when its not
+    // configured.
+    defaultDataSources = map[string]string{
+                    dataSetImpl.BACKEND_SQLITE : DB_PATH,
+                    dataSetImpl.BACKEND_BOLT : BOLT_DB_PATH,
+    }
+)
+
+func init() {
+    flag.Var(backendOptions, "option",
+             "Option of the backend as key=value, can be repeated")
+}
+
+//Read the backend configuration from the config file, the command line
+// overrides the values in the file.
+func getBackendConfig() (*backendConfig, error) {
+    config := new(backendConfig)
+    if len(*configFile) != 0 {
+        content, err := ioutil.ReadFile(*configFile)
+        if err != nil {
+            return nil, err
+        }
+        err = json.Unmarshal(content, config)
+        if err != nil {
+            return nil, err
+        }
+    }
+    if len(*backend) != 0 {
+        config.Backend = *backend
+    }
+    if len(config.Backend) == 0 {
+        config.Backend = dataSetImpl.BACKEND_SQLITE
+    }
+    if len(*dataSource) != 0 {
+        config.DataSource = *dataSource
+    }
+    if len(config.DataSource) == 0 {
+        config.DataSource = defaultDataSources[config.Backend]
+    }
+    if config.Options == nil {
+        config.Options = dataStore.BackendOptions{}
+    }
+    for key, value := range backendOptions {
+        config.Options[key] = value
+    }
+    return config, nil
+}
+
 func startLoggerService() {
     createDirectory(APP_DIR, os.FileMode(0755))
     logger := new(logger.Logging)
@@ -41,15 +157,116 @@ func startLoggerService() {
     logger.Trace("Logging service is started..")
 }
 
-func setupDataService() error {
+//Create the datastore of the configured backend and connect to it.
+func connectDataService() error {
     var err error
+    log := logger.GetLoggerInstance()
+    config, err := getBackendConfig()
+    if err != nil {
+        log.Error("Failed to read the backend config err : %s", err)
+        return err
+    }
+    err = dataSetImpl.SetBackend(config.Backend, config.Options)
+    if err != nil {
+        log.Error("Failed to create the %s backend err : %s", config.Backend,
+                  err)
+        return err
+    }
+    return dataSetImpl.GetDataSetObj().CreateDBConnection(config.DataSource)
+}
+
+func setupDataService() error {
+    err := connectDataService()
+    if err != nil {
+        return err
+    }
+    return dataSetImpl.GetDataSetObj().CreateDataStoreTables()
+}
+
+//Print the schema migrations of the datastore, the pending ones are applied
+// first on 'apply'.
+func migrateDataService() error {
+    if *migrateOp != "status" && *migrateOp != "apply" {
+        return fmt.Errorf("Invalid migrate option '%s'", *migrateOp)
+    }
+    err := connectDataService()
+    if err != nil {
+        return err
+    }
+    migrationObj, ok := dataSetImpl.GetDataSetObj().(
+                                            dataStore.MigrationInterface)
+    if !ok {
+        fmt.Println("Backend has no schema migrations")
+        return nil
+    }
+    if *migrateOp == "apply" {
+        err = migrationObj.ApplyMigrations()
+        if err != nil {
+            return err
+        }
+    }
+    migrations, err := migrationObj.GetMigrations()
+    if err != nil {
+        return err
+    }
+    for _, migration := range migrations {
+        status := "pending"
+        if len(migration.AppliedAt) != 0 {
+            status = "applied at " + migration.AppliedAt
+        }
+        fmt.Printf("%3d  %-50s %s\n", migration.Version, migration.Desc,
+                   status)
+    }
+    return nil
+}
+
+//Load the types of the records from the types file.
+func loadNodeTypes() error {
+    if len(*typesFile) == 0 {
+        return nil
+    }
+    content, err := ioutil.ReadFile(*typesFile)
+    if err != nil {
+        return err
+    }
+    types := []dataStore.NodeType{}
+    err = json.Unmarshal(content, &types)
+    if err != nil {
+        return err
+    }
+    return dataStore.SetNodeTypes(types)
+}
+
+//Verify and/or rebuild all the trees in the datastore as requested at startup.
+func checkDataService() error {
+    log := logger.GetLoggerInstance()
+    if *verifyOnStart == false && *rebuildOnStart == false {
+        return nil
+    }
     dataObj := dataSetImpl.GetDataSetObj()
-    err = dataObj.CreateDBConnection(DB_PATH)
+    trees, err := dataObj.GetAllTrees()
     if err != nil {
         return err
     }
-    err = dataObj.CreateDataStoreTables()
-    return err
+    for _, tree := range trees {
+        if *rebuildOnStart == true {
+            err = dataObj.RebuildTree(tree.TreeId)
+            if err != nil {
+                return err
+            }
+        }
+        report, err := dataObj.VerifyTree(tree.TreeId)
+        if err != nil {
+            return err
+        }
+        for _, issue := range report.Issues {
+            log.Error("Tree %s, record %s : %s, %s", tree.TreeId, issue.Uid,
+                      issue.Issue, issue.Detail)
+        }
+        fmt.Printf("Tree %s (%s) : %d records, %d issues\n", tree.Name,
+                   tree.TreeId, report.Records, len(report.Issues))
+    }
+    return nil
 }
 
 func createDirectory(path string, mode os.FileMode) {
@@ -58,7 +275,34 @@ func createDirectory(path string, mode os.FileMode) {
     }
 }
 
+//Purge the trash of all the trees periodically, in the background.
+func startTrashPurgeService() {
+    if *trashRetention <= 0 {
+        return
+    }
+    interval := TRASH_PURGE_INTERVAL
+    if *trashRetention < interval {
+        interval = *trashRetention
+    }
+    go func() {
+        log := logger.GetLoggerInstance()
+        for {
+            count, err := dataSetImpl.GetDataSetObj().PurgeTrash(
+                                            time.Now().Add(-*trashRetention))
+            if err != nil {
+                log.Error("Failed to purge the trash err : %s", err)
+            } else if count != 0 {
+                log.Info("Purged %d subtrees from the trash", count)
+            }
+            time.Sleep(interval)
+        }
+    }()
+}
+
 func setupRESTService() error {
+    if *softDelete == true {
+        restAPI.SetDefaultDeleteMode(restAPI.DELETE_MODE_TRASH)
+    }
     resthandler := new(restAPI.RestAPI)
     err := resthandler.RestAPIMainHandler(SERVER_IP, SERVER_PORT)
     if err != nil {
@@ -69,16 +313,37 @@ func setupRESTService() error {
 
 func main() {
     var err error
+    flag.Parse()
     startLoggerService()
     syncObj := sys.GetAppSyncObj()
     defer syncObj.JoinAllRoutines()    
     log := logger.GetLoggerInstance()
 
+    if len(*migrateOp) != 0 {
+        err = migrateDataService()
+        if err != nil {
+            log.Error("Failed to migrate the database err : %s", err)
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        return
+    }
+    err = loadNodeTypes()
+    if err != nil {
+        log.Error("Failed to load the record types err : %s", err)
+        panic("Cannot load the record types")
+    }
     err = setupDataService()
     if err != nil {
         log.Error("Failed to start the database, exiting the application")
         panic("Cannot start Database/backend")
     }
+    err = checkDataService()
+    if err != nil {
+        log.Error("Failed to check the trees in the database err : %s", err)
+        panic("Cannot check Database/backend")
+    }
+    startTrashPurgeService()
     err = setupRESTService()
     if err != nil {
         log.Error("Failed to start REST service")
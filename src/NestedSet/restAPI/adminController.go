@@ -0,0 +1,69 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restAPI
+
+import (
+    "net/http"
+    "encoding/json"
+    "github.com/gorilla/mux"
+    "NestedSet/logger"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl"
+)
+
+func writeTreeReport(w http.ResponseWriter, report *dataStore.TreeReport) {
+    data, _ := json.Marshal(report)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+//Report the nested set issues in a tree, tree is not modified.
+func (ctrl *controller) verifyTree(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := vars["tree-id"]
+    dbObj := dataSetImpl.GetDataSetObj()
+    report, err := dbObj.VerifyTree(treeId)
+    if err != nil {
+        log.Error("Failed to verify the tree %s err : %s", treeId, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    writeTreeReport(w, report)
+}
+
+//Rebuild the nested set limits of a tree from the parent links and report
+// the tree after the rebuild.
+func (ctrl *controller) rebuildTree(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := vars["tree-id"]
+    dbObj := dataSetImpl.GetDataSetObj()
+    err := dbObj.RebuildTree(treeId)
+    if err != nil {
+        log.Error("Failed to rebuild the tree %s err : %s", treeId, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    report, err := dbObj.VerifyTree(treeId)
+    if err != nil {
+        log.Error("Failed to verify the tree %s err : %s", treeId, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    writeTreeReport(w, report)
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "testing"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl"
+    "NestedSet/dataStore/dataSetImpl/rdbms"
+)
+
+//Check the report of the tree, 'issues' are the sorted names of the issues.
+func (client *testClient)expectReport(method string, action string,
+                                      records int, issues string) {
+    client.t.Helper()
+    report := new(dataStore.TreeReport)
+    client.decode(client.expect(http.StatusOK, method,
+                                "/admin/trees/default/" + action, ""), report)
+    got := []string{}
+    for _, issue := range report.Issues {
+        got = append(got, issue.Issue)
+    }
+    sort.Strings(got)
+    if report.TreeId != "default" || report.Records != records ||
+       strings.Join(got, " ") != issues {
+        client.t.Fatalf("%s report is %+v, expected %d records and '%s'",
+                        action, report, records, issues)
+    }
+}
+
+//Move the limits of the record outside of the tree, only the SQL datastores
+// can be changed behind the datastore. Returns false for the other backends.
+func (client *testClient)corruptLimits(name string) bool {
+    client.t.Helper()
+    sqlds, ok := dataSetImpl.GetDataSetObj().(*rdbms.RdbmsDataStore)
+    if !ok {
+        return false
+    }
+    _, err := sqlds.DBConn.Exec(sqlds.DBConn.Rebind(fmt.Sprintf(
+                                `UPDATE "%s" SET "%s"="%s"+1000,
+                                 "%s"="%s"+1000 WHERE "%s"=?`,
+                                rdbms.SQL_DATA_TABLE_NAME,
+                                rdbms.DATA_LFTID, rdbms.DATA_LFTID,
+                                rdbms.DATA_RGTID, rdbms.DATA_RGTID,
+                                rdbms.DATA_UID)), client.uids[name])
+    if err != nil {
+        client.t.Fatalf("Failed to change the limits of %s err : %s", name,
+                        err)
+    }
+    return true
+}
+
+func TestVerifyRebuild(t *testing.T) {
+    runOnBackends(t, func(client *testClient) {
+        client.createTestTree()
+        client.expectReport("GET", "verify", 8, "")
+        client.expectReport("POST", "rebuild", 8, "")
+        client.expectShape("root .a ..a1 ..a2 ...a2x .b ..b1 .c")
+        client.expect(http.StatusBadRequest, "GET",
+                      "/admin/trees/nosuch/verify", "")
+        client.expect(http.StatusBadRequest, "POST",
+                      "/admin/trees/nosuch/rebuild", "")
+        if client.corruptLimits("a1") == false {
+            return
+        }
+        client.expectReport("GET", "verify", 8, "limit-gaps parent-mismatch")
+        //Childrens keep the order of their limits.
+        client.expectReport("POST", "rebuild", 8, "")
+        client.expectShape("root .a ..a2 ...a2x ..a1 .b ..b1 .c")
+    })
+}
@@ -15,6 +15,10 @@
 package restAPI
 
 import (
+    "strconv"
+    "strings"
+    "net"
+    "net/url"
     "net/http"
     "encoding/json"
     "io"
@@ -28,32 +32,287 @@ import (
 
 type controller struct { }
 
+//Delete modes of a record, 'cascade' deletes the record and all its childrens
+// permanently and 'trash' moves them to the trash of the tree. 'promote'
+// deletes only the record and moves its childrens to its parent, 'leaf-only'
+// refuses to delete a record that has childrens.
+const (
+    DELETE_MODE_CASCADE = "cascade"
+    DELETE_MODE_TRASH = "trash"
+    DELETE_MODE_PROMOTE = "promote"
+    DELETE_MODE_LEAF_ONLY = "leaf-only"
+)
+
+//Delete mode of the requests without a mode.
+var defaultDeleteMode = DELETE_MODE_CASCADE
+
+//Set the delete mode for the delete requests without a mode.
+func SetDefaultDeleteMode(mode string) error {
+    switch mode {
+    case DELETE_MODE_CASCADE, DELETE_MODE_TRASH, DELETE_MODE_PROMOTE,
+         DELETE_MODE_LEAF_ONLY:
+        defaultDeleteMode = mode
+        return nil
+    }
+    return appErrors.INVALID_INPUT
+}
+
+//Get the tree of the request, the data APIs without a tree in the path are
+// served from the default tree.
+func getTreeId(r *http.Request) string {
+    treeId := mux.Vars(r)["tree-id"]
+    if len(treeId) == 0 {
+        return dataStore.DEFAULT_TREE_ID
+    }
+    return treeId
+}
+
+//Header of the request with the user making the change. Changes are recorded
+// with the address of the client when its not present.
+const ACTOR_HEADER = "X-Actor"
+
+//Get the actor of the request to record the changes.
+func getActor(r *http.Request) string {
+    actor := r.Header.Get(ACTOR_HEADER)
+    if len(actor) != 0 {
+        return actor
+    }
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
+
+//Header of the request with the ETag of the record the user has, changes of
+// the record fail when its changed after the ETag.
+const IF_MATCH_HEADER = "If-Match"
+
+//Times a PATCH with 'If-Match: *' is merged again, when the record is changed
+// by another request after its read.
+const PATCH_MAX_RETRIES = 10
+
+//ETag of the record is the latest version of the record and its childrens.
+func setETag(w http.ResponseWriter, rec *dataStore.Data) {
+    w.Header().Set("ETag",
+                   `"` + strconv.FormatInt(rec.SubtreeVersion, 10) + `"`)
+}
+
+//Get the version of the record from the If-Match header of the request,
+// ANY_VERSION when its '*'. Latest version is used when there are more than
+// one ETag. The status is written to the response when the header is missing,
+// or has an ETag that can never match.
+func getIfMatchVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+    log := logger.GetLoggerInstance()
+    value := strings.TrimSpace(r.Header.Get(IF_MATCH_HEADER))
+    if len(value) == 0 {
+        log.Error("Cannot change the record without %s header",
+                  IF_MATCH_HEADER)
+        w.WriteHeader(http.StatusPreconditionRequired)
+        return 0, false
+    }
+    if value == "*" {
+        return dataStore.ANY_VERSION, true
+    }
+    version := int64(dataStore.ANY_VERSION)
+    for _, etag := range strings.Split(value, ",") {
+        etag = strings.TrimSpace(etag)
+        etagVersion := int64(-1)
+        if len(etag) > 2 && strings.HasPrefix(etag, `"`) &&
+           strings.HasSuffix(etag, `"`) {
+            etagVersion, _ = strconv.ParseInt(etag[1:len(etag) - 1], 10, 64)
+        }
+        if etagVersion < 0 {
+            log.Error("Invalid ETag %s in the %s header", etag,
+                      IF_MATCH_HEADER)
+            w.WriteHeader(http.StatusPreconditionFailed)
+            return 0, false
+        }
+        if etagVersion > version {
+            version = etagVersion
+        }
+    }
+    return version, true
+}
+
+//Get the http status for the error returned by the datastore. Invalid requests
+// from the user are reported as bad request, records that violate the type
+// rules as unprocessable, records changed after the ETag of the user as
+// precondition failed and rest as server error.
+func getErrorStatus(err error) int {
+    if _, ok := err.(*dataStore.TypeError); ok {
+        return 422
+    }
+    switch err {
+    case appErrors.DATA_VERSION_MISMATCH:
+        return http.StatusPreconditionFailed
+    case appErrors.INVALID_INPUT, appErrors.INVALID_OP,
+         appErrors.DATA_PRESENT_IN_SYSTEM, appErrors.DATA_NOT_UNIQUE_ERROR,
+         appErrors.DATA_NOT_FOUND:
+        return http.StatusBadRequest
+    }
+    return http.StatusInternalServerError
+}
+
+//Write the http status for the error returned by the datastore, the type
+// violations are sent in the response.
+func writeErrorStatus(w http.ResponseWriter, err error) {
+    typeErr, ok := err.(*dataStore.TypeError)
+    if !ok {
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    data, _ := json.Marshal(typeErr)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.WriteHeader(422)
+    w.Write(data)
+}
+
+//Query parameters with the prefix filter the records on their attributes,
+// e.g. 'attr.status=active'.
+const ATTR_FILTER_PREFIX = "attr."
+
+//Get the attribute filters of the request query.
+func getAttrFilters(query url.Values) map[string]string {
+    attrFilters := make(map[string]string)
+    for key, values := range query {
+        if strings.HasPrefix(key, ATTR_FILTER_PREFIX) && len(values) != 0 {
+            name := strings.TrimPrefix(key, ATTR_FILTER_PREFIX)
+            attrFilters[name] = values[0]
+        }
+    }
+    return attrFilters
+}
+
+//Get the filter of the records on their computed fields, type and
+// attributes, the supported filters in the request query are 'depth', 'leaf',
+// 'type' and 'attr.{name}'.
+func getRecordFilter(query url.Values) (*dataStore.RecordFilter, error) {
+    filter := new(dataStore.RecordFilter)
+    if depthVal := query.Get("depth"); len(depthVal) != 0 {
+        depth, err := strconv.ParseInt(depthVal, 10, 64)
+        if err != nil {
+            return nil, appErrors.INVALID_INPUT
+        }
+        filter.Depth = &depth
+    }
+    if leafVal := query.Get("leaf"); len(leafVal) != 0 {
+        isLeaf, err := strconv.ParseBool(leafVal)
+        if err != nil {
+            return nil, appErrors.INVALID_INPUT
+        }
+        filter.IsLeaf = &isLeaf
+    }
+    if _, hasType := query["type"]; hasType {
+        typeVal := query.Get("type")
+        filter.Type = &typeVal
+    }
+    filter.Attributes = getAttrFilters(query)
+    return filter, nil
+}
+
+//Query parameters of the record pages, 'limit' is the maximum number of
+// records in the page and 'cursor' is from the Link of the previous page.
+const (
+    PAGE_LIMIT_PARAM = "limit"
+    PAGE_CURSOR_PARAM = "cursor"
+    TOTAL_COUNT_HEADER = "X-Total-Count"
+)
+
+//Get the page of the records and its filter from the request query. Limit is
+// DEFAULT_PAGE_LIMIT when its not present and MAX_PAGE_LIMIT at most.
+func getPageRequest(query url.Values) (*dataStore.PageRequest, error) {
+    var err error
+    request := new(dataStore.PageRequest)
+    request.Limit = dataStore.DEFAULT_PAGE_LIMIT
+    if limitVal := query.Get(PAGE_LIMIT_PARAM); len(limitVal) != 0 {
+        request.Limit, err = strconv.Atoi(limitVal)
+        if err != nil || request.Limit <= 0 {
+            return nil, appErrors.INVALID_INPUT
+        }
+        if request.Limit > dataStore.MAX_PAGE_LIMIT {
+            request.Limit = dataStore.MAX_PAGE_LIMIT
+        }
+    }
+    if cursorVal := query.Get(PAGE_CURSOR_PARAM); len(cursorVal) != 0 {
+        request.After, err = strconv.ParseInt(cursorVal, 10, 64)
+        if err != nil || request.After < 0 {
+            return nil, appErrors.INVALID_INPUT
+        }
+    }
+    filter, err := getRecordFilter(query)
+    if err != nil {
+        return nil, err
+    }
+    request.Filter = *filter
+    return request, nil
+}
+
+//Get the link to the page of the request at 'cursor', the first page when its
+// 0.
+func getPageLink(r *http.Request, cursor int64, rel string) string {
+    pageUrl := *r.URL
+    query := pageUrl.Query()
+    query.Del(PAGE_CURSOR_PARAM)
+    if cursor != 0 {
+        query.Set(PAGE_CURSOR_PARAM, strconv.FormatInt(cursor, 10))
+    }
+    pageUrl.RawQuery = query.Encode()
+    return "<" + pageUrl.RequestURI() + `>; rel="` + rel + `"`
+}
+
+//Write the records of the page with the total count and the links to the
+// first and next pages.
+func writePage(w http.ResponseWriter, r *http.Request,
+               page *dataStore.RecordPage) {
+    links := []string{getPageLink(r, 0, "first")}
+    if page.Next != 0 {
+        links = append(links, getPageLink(r, page.Next, "next"))
+    }
+    data, _ := json.Marshal(page.Records)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.Header().Set(TOTAL_COUNT_HEADER, strconv.FormatInt(page.Total, 10))
+    w.Header().Set("Link", strings.Join(links, ", "))
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
 func (ctrl *controller) getAllRecords(w http.ResponseWriter, r *http.Request) {
     log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
     dbObj := dataSetImpl.GetDataSetObj()
     if dbObj == nil {
         log.Error("Empty datastore handle")
         w.WriteHeader(http.StatusInternalServerError)
         w.Write([]byte("500-Server Error "))
+        return
     }
-    rows, err := dbObj.GetAllRecords()
+    request, err := getPageRequest(r.URL.Query())
+    if err != nil {
+        log.Error("Invalid page to get the records err : %s", err)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    page, err := dbObj.GetRecordsPage(treeId, request)
     if err != nil {
         log.Trace("Failed to get the records from DB")
+        if getErrorStatus(err) != http.StatusInternalServerError {
+            w.WriteHeader(getErrorStatus(err))
+            return
+        }
         w.WriteHeader(http.StatusInternalServerError)
         w.Write([]byte("500-Server Error "+ err.Error()))
         return
     }
-    data, _ := json.Marshal(rows)
-    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-    w.Header().Set("Access-Control-Allow-Origin", "*")
-    w.WriteHeader(http.StatusOK)
-    w.Write(data)
+    writePage(w, r, page)
 }
 
 func (ctrl *controller) getRecord(w http.ResponseWriter, r *http.Request) {
     var err error
     vars := mux.Vars(r)
     log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
     Uid := vars["record-id"]
     if len(Uid) == 0 {
         log.Error("Empty record id , cannot find it")
@@ -62,7 +321,7 @@ func (ctrl *controller) getRecord(w http.ResponseWriter, r *http.Request) {
     }
     var dataObj *dataStore.Data
     dbObj := dataSetImpl.GetDataSetObj()
-    dataObj,err = dbObj.GetRecord(Uid)
+    dataObj,err = dbObj.GetRecord(treeId, Uid)
     if err != nil || dataObj == nil {
         log.Error(`Failed to retrieive the record object %s` +
                     `err : %s`, Uid, err)
@@ -72,28 +331,66 @@ func (ctrl *controller) getRecord(w http.ResponseWriter, r *http.Request) {
     data, _ := json.Marshal(dataObj)
     w.Header().Set("Content-Type", "application/json; charset=UTF-8")
     w.Header().Set("Access-Control-Allow-Origin", "*")
+    setETag(w, dataObj)
     w.WriteHeader(http.StatusOK)
     w.Write(data)
     log.Trace("Getting a single record in the system")
 }
 
-func (ctrl *controller) getRecordsByName(w http.ResponseWriter,
-                                         r *http.Request) {
+func (ctrl *controller) getAncestors(w http.ResponseWriter, r *http.Request) {
     var err error
     vars := mux.Vars(r)
     log := logger.GetLoggerInstance()
-    name := vars["record-name"]
-    if len(name) == 0 {
-        log.Error("Empty record name , cannot find it")
+    treeId := getTreeId(r)
+    Uid := vars["record-id"]
+    if len(Uid) == 0 {
+        log.Error("Empty record id , cannot find its ancestors")
         w.WriteHeader(http.StatusBadRequest)
         return
     }
     var rows []dataStore.Data
     dbObj := dataSetImpl.GetDataSetObj()
-    rows,err = dbObj.GetRecordByName(name)
-    if err != nil || len(rows) == 0{
-        log.Error(`Failed to retrieive the record object: %s
-                    err : %s`, name, err)
+    rows, err = dbObj.GetAncestors(treeId, Uid)
+    if err != nil {
+        log.Error("Failed to retrieive the ancestors of %s err : %s", Uid, err)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    data, _ := json.Marshal(rows)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+func (ctrl *controller) getDescendants(w http.ResponseWriter,
+                                       r *http.Request) {
+    var err error
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    Uid := vars["record-id"]
+    if len(Uid) == 0 {
+        log.Error("Empty record id , cannot find its descendants")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    maxDepth := 0
+    depth := r.URL.Query().Get("depth")
+    if len(depth) != 0 {
+        maxDepth, err = strconv.Atoi(depth)
+        if err != nil || maxDepth < 0 {
+            log.Error("Invalid depth %s to get the descendants", depth)
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+    }
+    var rows []dataStore.Data
+    dbObj := dataSetImpl.GetDataSetObj()
+    rows, err = dbObj.GetDescendants(treeId, Uid, maxDepth)
+    if err != nil {
+        log.Error("Failed to retrieive the descendants of %s err : %s",
+                   Uid, err)
         w.WriteHeader(http.StatusBadRequest)
         return
     }
@@ -104,8 +401,63 @@ func (ctrl *controller) getRecordsByName(w http.ResponseWriter,
     w.Write(data)
 }
 
+func (ctrl *controller) getChildren(w http.ResponseWriter, r *http.Request) {
+    var err error
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    Uid := vars["record-id"]
+    if len(Uid) == 0 {
+        log.Error("Empty record id , cannot find its childrens")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    var rows []dataStore.Data
+    dbObj := dataSetImpl.GetDataSetObj()
+    rows, err = dbObj.GetChildren(treeId, Uid)
+    if err != nil {
+        log.Error("Failed to retrieive the childrens of %s err : %s", Uid, err)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    data, _ := json.Marshal(rows)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+func (ctrl *controller) getRecordsByName(w http.ResponseWriter,
+                                         r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    name := vars["record-name"]
+    if len(name) == 0 {
+        log.Error("Empty record name , cannot find it")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    request, err := getPageRequest(r.URL.Query())
+    if err != nil {
+        log.Error("Invalid page to get the records err : %s", err)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    page, err := dbObj.GetRecordByNamePage(treeId, name, request)
+    if err != nil || (page.Total == 0 && request.Filter.IsEmpty()) {
+        log.Error(`Failed to retrieive the record object: %s
+                    err : %s`, name, err)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    writePage(w, r, page)
+}
+
 func (ctrl *controller) addRecord(w http.ResponseWriter, r *http.Request) {
     log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
     body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
     if err != nil {
         log.Error("Failed to read request,")
@@ -121,19 +473,20 @@ func (ctrl *controller) addRecord(w http.ResponseWriter, r *http.Request) {
         log.Error("Failed to Unmarshal the camera input err:%s", err)
         if err := json.NewEncoder(w).Encode(err); err != nil {
             log.Error("Failed to encode marshaling err : %s", err)
-            w.WriteHeader(http.StatusInternalServerError)
-            return
         }
+        return
     }
+    if dataObj == nil {
+        log.Error("Empty record in the request")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    dataObj.Attributes.RemoveNulls()
     dbObj := dataSetImpl.GetDataSetObj()
-    err = dbObj.CreateRecord(dataObj)
+    err = dbObj.CreateRecord(treeId, dataObj, getActor(r))
     if err != nil {
         log.Error("REST API failed to create data entry in table err :%s", err)
-        if err == appErrors.DATA_PRESENT_IN_SYSTEM {
-            w.WriteHeader(400) //Bad Request.
-            return
-        }
-        w.WriteHeader(http.StatusInternalServerError)
+        writeErrorStatus(w, err)
         return
     }
     w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -145,6 +498,7 @@ func (ctrl *controller) deleteRecord(w http.ResponseWriter, r *http.Request) {
     var err error
     vars := mux.Vars(r)
     log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
     Uid := vars["record-id"]
     if len(Uid) == 0 {
         log.Error("Empty record id , cannot find it")
@@ -153,18 +507,221 @@ func (ctrl *controller) deleteRecord(w http.ResponseWriter, r *http.Request) {
     }
     var dataObj *dataStore.Data
     dbObj := dataSetImpl.GetDataSetObj()
-    dataObj,err = dbObj.GetRecord(Uid)
+    dataObj,err = dbObj.GetRecord(treeId, Uid)
     if err != nil || dataObj == nil {
         log.Error(`Failed to retrieive the record object, cannot delete %s
                     err : %s`, Uid, err)
         w.WriteHeader(http.StatusBadRequest)
         return
     }
-    err = dbObj.DeleteRecord(Uid)
+    version, ok := getIfMatchVersion(w, r)
+    if !ok {
+        return
+    }
+    mode := r.URL.Query().Get("mode")
+    if len(mode) == 0 {
+        mode = defaultDeleteMode
+    }
+    switch mode {
+    case DELETE_MODE_CASCADE:
+        err = dbObj.DeleteRecord(treeId, Uid, version, getActor(r))
+    case DELETE_MODE_TRASH:
+        err = dbObj.TrashRecord(treeId, Uid, version, getActor(r))
+    case DELETE_MODE_PROMOTE:
+        err = dbObj.PromoteRecord(treeId, Uid, version, getActor(r))
+    case DELETE_MODE_LEAF_ONLY:
+        err = dbObj.DeleteLeafRecord(treeId, Uid, version, getActor(r))
+    default:
+        log.Error("Invalid delete mode %s", mode)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
     if err != nil {
         log.Error("Failed to delete the data record err : %s", err)
+        writeErrorStatus(w, err)
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+}
+
+//Update the name, description, type and attributes of a record. PUT replaces
+// all the fields and PATCH updates only the fields present in the request.
+func (ctrl *controller) updateRecord(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    Uid := vars["record-id"]
+    if len(Uid) == 0 {
+        log.Error("Empty record id , cannot update it")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+    if err != nil {
+        log.Error("Failed to read request,")
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+    if err := r.Body.Close(); err != nil {
+        log.Error("Failed to close the request.")
+    }
+    version, ok := getIfMatchVersion(w, r)
+    if !ok {
+        return
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    var dataObj *dataStore.Data
+    for retry := 0; ; retry++ {
+        dataObj = new(dataStore.Data)
+        updateVersion := version
+        if r.Method == "PATCH" {
+            //Start with the current record, so missing fields are unchanged.
+            dataObj, err = dbObj.GetRecord(treeId, Uid)
+            if err != nil || dataObj == nil {
+                log.Error("Failed to retrieive the record object, cannot " +
+                          "update %s err : %s", Uid, err)
+                w.WriteHeader(http.StatusBadRequest)
+                return
+            }
+            //Record must not be changed after its read, or the changes in
+            // between are lost on the update.
+            if version == dataStore.ANY_VERSION {
+                updateVersion = dataObj.Version
+            }
+        }
+        if err := json.Unmarshal(body, &dataObj); err != nil {
+            log.Error("Failed to Unmarshal the update request err:%s", err)
+            w.WriteHeader(422)
+            return
+        }
+        if dataObj == nil {
+            log.Error("Empty record in the request")
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        //Attributes of the request are merged on PATCH, null removes them.
+        dataObj.Attributes.RemoveNulls()
+        dataObj.Uid = Uid
+        err = dbObj.UpdateRecord(treeId, dataObj, updateVersion, getActor(r))
+        if err == appErrors.DATA_VERSION_MISMATCH &&
+           updateVersion != version && retry < PATCH_MAX_RETRIES {
+            log.Trace("Record %s is changed on update, merging again", Uid)
+            continue
+        }
+        if err != nil {
+            log.Error("Failed to update the record %s err : %s", Uid, err)
+            writeErrorStatus(w, err)
+            return
+        }
+        break
+    }
+    dataObj, err = dbObj.GetRecord(treeId, Uid)
+    if err != nil {
+        log.Error("Failed to retrieive the updated record %s err : %s",
+                   Uid, err)
         w.WriteHeader(http.StatusInternalServerError)
         return
     }
+    data, _ := json.Marshal(dataObj)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    setETag(w, dataObj)
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+    log.Trace("Updated the record %s", Uid)
+}
+
+func (ctrl *controller) moveRecord(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    Uid := vars["record-id"]
+    if len(Uid) == 0 {
+        log.Error("Empty record id , cannot move it")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+    if err != nil {
+        log.Error("Failed to read request,")
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+    if err := r.Body.Close(); err != nil {
+        log.Error("Failed to close the request.")
+    }
+    //Only the 'puid' of the request is used to find the new parent.
+    dataObj := new(dataStore.Data)
+    if err := json.Unmarshal(body, &dataObj); err != nil {
+        log.Error("Failed to Unmarshal the move request err:%s", err)
+        w.WriteHeader(422)
+        return
+    }
+    if dataObj == nil {
+        log.Error("Empty record in the request")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    version, ok := getIfMatchVersion(w, r)
+    if !ok {
+        return
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    err = dbObj.MoveRecord(treeId, Uid, dataObj.Puid, version, getActor(r))
+    if err != nil {
+        log.Error("Failed to move the record %s under %s err : %s", Uid,
+                   dataObj.Puid, err)
+        writeErrorStatus(w, err)
+        return
+    }
     w.WriteHeader(http.StatusOK)
-}
\ No newline at end of file
+    log.Trace("Moved the record %s under %s", Uid, dataObj.Puid)
+}
+//Copy the record and all its childrens as the last child of the 'puid' of the
+// request, the copied record is returned.
+func (ctrl *controller) copyRecord(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    Uid := vars["record-id"]
+    if len(Uid) == 0 {
+        log.Error("Empty record id , cannot copy it")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+    if err != nil {
+        log.Error("Failed to read request,")
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+    if err := r.Body.Close(); err != nil {
+        log.Error("Failed to close the request.")
+    }
+    //Only the 'puid' of the request is used to find the destination.
+    dataObj := new(dataStore.Data)
+    if err := json.Unmarshal(body, &dataObj); err != nil {
+        log.Error("Failed to Unmarshal the copy request err:%s", err)
+        w.WriteHeader(422)
+        return
+    }
+    if dataObj == nil {
+        log.Error("Empty record in the request")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    copyObj, err := dbObj.CopySubtree(treeId, Uid, dataObj.Puid,
+                                      getActor(r))
+    if err != nil {
+        log.Error("Failed to copy the record %s under %s err : %s", Uid,
+                   dataObj.Puid, err)
+        writeErrorStatus(w, err)
+        return
+    }
+    data, _ := json.Marshal(copyObj)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.WriteHeader(http.StatusCreated)
+    w.Write(data)
+    log.Trace("Copied the record %s to %s", Uid, copyObj.Uid)
+}
@@ -0,0 +1,582 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "testing"
+    "NestedSet/dataStore"
+)
+
+//Shape of the test tree before the changes.
+const testTreeShape = "root .a ..a1 ..a2 ...a2x .b ..b1 .c"
+
+func TestRecordChanges(t *testing.T) {
+    tests := []struct {
+        name string
+        changeFn func(client *testClient)
+        shape string
+    }{
+        {"move under another parent",
+         func(client *testClient) {
+             client.expect(http.StatusOK, "PUT",
+                           "/data/id/" + client.uids["a2"] + "/parent",
+                           `{"puid":"` + client.uids["b"] + `"}`)
+         },
+         "root .a ..a1 .b ..b1 ..a2 ...a2x .c"},
+        {"move under own child",
+         func(client *testClient) {
+             client.expect(http.StatusBadRequest, "PUT",
+                           "/data/id/" + client.uids["a"] + "/parent",
+                           `{"puid":"` + client.uids["a2x"] + `"}`)
+         },
+         testTreeShape},
+        {"move with null body",
+         func(client *testClient) {
+             client.expect(http.StatusBadRequest, "PUT",
+                           "/data/id/" + client.uids["a"] + "/parent", "null")
+         },
+         testTreeShape},
+        {"update with null body",
+         func(client *testClient) {
+             for _, method := range []string{"PUT", "PATCH"} {
+                 client.expect(http.StatusBadRequest, method,
+                               "/data/id/" + client.uids["a"], "null")
+             }
+         },
+         testTreeShape},
+        {"rename with a name of the sibling",
+         func(client *testClient) {
+             client.expect(http.StatusBadRequest, "PATCH",
+                           "/data/id/" + client.uids["a1"], `{"name":"a2"}`)
+             client.expect(http.StatusOK, "PUT",
+                           "/data/id/" + client.uids["a1"],
+                           `{"name":"a3","desc":"x"}`)
+         },
+         "root .a ..a3 ..a2 ...a2x .b ..b1 .c"},
+        {"promote the childrens",
+         func(client *testClient) {
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a"] + "?mode=promote", "")
+         },
+         "root .a1 .a2 ..a2x .b ..b1 .c"},
+        {"leaf-only delete of a parent",
+         func(client *testClient) {
+             client.expect(http.StatusBadRequest, "DELETE",
+                           "/data/id/" + client.uids["a"] + "?mode=leaf-only",
+                           "")
+         },
+         testTreeShape},
+        {"cascade delete",
+         func(client *testClient) {
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a2"], "")
+         },
+         "root .a ..a1 .b ..b1 .c"},
+        {"copy under another parent",
+         func(client *testClient) {
+             rec := new(dataStore.Data)
+             client.decode(client.expect(http.StatusCreated, "POST",
+                           "/data/id/" + client.uids["a"] + "/copy",
+                           `{"puid":"` + client.uids["c"] + `"}`), rec)
+             if rec.Name != "a" || rec.Puid != client.uids["c"] ||
+                rec.Uid == client.uids["a"] {
+                 client.t.Fatalf("Unexpected copy %+v", rec)
+             }
+         },
+         "root .a ..a1 ..a2 ...a2x .b ..b1 .c ..a ...a1 ...a2 ....a2x"},
+        {"copy under the same parent",
+         func(client *testClient) {
+             client.expect(http.StatusCreated, "POST",
+                           "/data/id/" + client.uids["b1"] + "/copy",
+                           `{"puid":"` + client.uids["b"] + `"}`)
+         },
+         "root .a ..a1 ..a2 ...a2x .b ..b1 ..b1 (copy) .c"},
+        {"copy with null body",
+         func(client *testClient) {
+             client.expect(http.StatusBadRequest, "POST",
+                           "/data/id/" + client.uids["a"] + "/copy", "null")
+         },
+         testTreeShape},
+        {"copy into own subtree",
+         func(client *testClient) {
+             client.expect(http.StatusCreated, "POST",
+                           "/data/id/" + client.uids["a"] + "/copy",
+                           `{"puid":"` + client.uids["a2"] + `"}`)
+         },
+         "root .a ..a1 ..a2 ...a2x ...a ....a1 ....a2 .....a2x .b ..b1 .c"},
+        {"trash and restore",
+         func(client *testClient) {
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a1"] + "?mode=trash", "")
+             client.expectShape("root .a ..a2 ...a2x .b ..b1 .c")
+             entries := []dataStore.TrashEntry{}
+             client.decode(client.expect(http.StatusOK, "GET", "/trash", ""),
+                           &entries)
+             if len(entries) != 1 || entries[0].Uid != client.uids["a1"] {
+                 client.t.Fatalf("Unexpected trash %+v", entries)
+             }
+             client.expect(http.StatusOK, "POST",
+                           "/trash/" + client.uids["a1"] + "/restore", "")
+         },
+         "root .a ..a2 ...a2x ..a1 .b ..b1 .c"},
+        {"restore from trash under another parent",
+         func(client *testClient) {
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a2"] + "?mode=trash", "")
+             client.expect(http.StatusOK, "POST",
+                           "/trash/" + client.uids["a2"] + "/restore",
+                           `{"puid":"` + client.uids["c"] + `"}`)
+         },
+         "root .a ..a1 .b ..b1 .c ..a2 ...a2x"},
+        {"snapshot restore of the tree",
+         func(client *testClient) {
+             snapshotId := client.createSnapshot("s1")
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["b"], "")
+             client.expect(http.StatusOK, "PUT",
+                           "/data/id/" + client.uids["a1"] + "/parent",
+                           `{"puid":"` + client.uids["c"] + `"}`)
+             client.create("d", "c")
+             client.expect(http.StatusOK, "POST",
+                           "/snapshots/" + snapshotId + "/restore", "")
+             client.expectTrash("d")
+         },
+         testTreeShape},
+        {"trash again after a snapshot restore",
+         func(client *testClient) {
+             before := client.createSnapshot("s1")
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a1"] + "?mode=trash", "")
+             after := client.createSnapshot("s2")
+             client.expect(http.StatusOK, "POST",
+                           "/snapshots/" + before + "/restore", "")
+             client.expectTrash("")
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a1"] + "?mode=trash", "")
+             client.expect(http.StatusOK, "POST",
+                           "/trash/" + client.uids["a1"] + "/restore", "")
+             client.expect(http.StatusOK, "POST",
+                           "/snapshots/" + before + "/restore",
+                           `{"uid":"` + client.uids["a"] + `"}`)
+             client.expect(http.StatusOK, "POST",
+                           "/snapshots/" + after + "/restore", "")
+             client.expectTrash("a1")
+             client.expect(http.StatusOK, "POST",
+                           "/trash/" + client.uids["a1"] + "/restore", "")
+         },
+         "root .a ..a2 ...a2x ..a1 .b ..b1 .c"},
+        {"snapshot restore of a subtree",
+         func(client *testClient) {
+             snapshotId := client.createSnapshot("s1")
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a2x"], "")
+             client.expect(http.StatusOK, "POST",
+                           "/snapshots/" + snapshotId + "/restore",
+                           `{"uid":"` + client.uids["a2"] + `"}`)
+         },
+         "root .a ..a1 ..a2 ...a2x .b ..b1 .c"},
+        {"snapshot restore of a moved subtree",
+         func(client *testClient) {
+             snapshotId := client.createSnapshot("s1")
+             client.expect(http.StatusOK, "PUT",
+                           "/data/id/" + client.uids["a1"] + "/parent",
+                           `{"puid":"` + client.uids["b"] + `"}`)
+             client.expect(http.StatusOK, "POST",
+                           "/snapshots/" + snapshotId + "/restore",
+                           `{"uid":"` + client.uids["a1"] + `"}`)
+         },
+         testTreeShape},
+        {"snapshot restore of a deleted subtree",
+         func(client *testClient) {
+             snapshotId := client.createSnapshot("s1")
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a"], "")
+             client.expect(http.StatusOK, "POST",
+                           "/snapshots/" + snapshotId + "/restore",
+                           `{"uid":"` + client.uids["a"] + `"}`)
+         },
+         testTreeShape},
+        {"snapshot restore trashes the records added after",
+         func(client *testClient) {
+             snapshotId := client.createSnapshot("s1")
+             client.createAll("a3:a", "a3x:a3")
+             client.expect(http.StatusOK, "PUT",
+                           "/data/id/" + client.uids["b1"] + "/parent",
+                           `{"puid":"` + client.uids["a2"] + `"}`)
+             client.expect(http.StatusOK, "POST",
+                           "/snapshots/" + snapshotId + "/restore",
+                           `{"uid":"` + client.uids["a"] + `"}`)
+             client.expectTrash("a3 b1")
+         },
+         "root .a ..a1 ..a2 ...a2x .b .c"},
+        {"snapshot restore of a record moved under a new record",
+         func(client *testClient) {
+             snapshotId := client.createSnapshot("s1")
+             client.create("n", "a")
+             client.expect(http.StatusOK, "PUT",
+                           "/data/id/" + client.uids["a1"] + "/parent",
+                           `{"puid":"` + client.uids["n"] + `"}`)
+             client.expect(http.StatusBadRequest, "POST",
+                           "/snapshots/" + snapshotId + "/restore",
+                           `{"uid":"` + client.uids["a"] + `"}`)
+             client.expectTrash("")
+         },
+         "root .a ..a2 ...a2x ..n ...a1 .b ..b1 .c"},
+    }
+    for _, test := range tests {
+        test := test
+        t.Run(test.name, func(t *testing.T) {
+            runOnBackends(t, func(client *testClient) {
+                client.createTestTree()
+                client.expectShape(testTreeShape)
+                test.changeFn(client)
+                client.expectShape(test.shape)
+            })
+        })
+    }
+}
+
+//Record 'n' is created at the position, 'parent' and 'sibling' are the names
+// of the records or empty.
+func TestPositions(t *testing.T) {
+    tests := []struct {
+        parent string
+        position string
+        sibling string
+        status int
+        shape string
+    }{
+        {"a", "", "", http.StatusCreated,
+         "root .a ..a1 ..a2 ...a2x ..n .b ..b1 .c"},
+        {"a", "last-child", "", http.StatusCreated,
+         "root .a ..a1 ..a2 ...a2x ..n .b ..b1 .c"},
+        {"a", "first-child", "", http.StatusCreated,
+         "root .a ..n ..a1 ..a2 ...a2x .b ..b1 .c"},
+        {"a1", "first-child", "", http.StatusCreated,
+         "root .a ..a1 ...n ..a2 ...a2x .b ..b1 .c"},
+        {"", "first-child", "", http.StatusCreated,
+         "root .n .a ..a1 ..a2 ...a2x .b ..b1 .c"},
+        {"", "before", "a2", http.StatusCreated,
+         "root .a ..a1 ..n ..a2 ...a2x .b ..b1 .c"},
+        {"", "after", "a1", http.StatusCreated,
+         "root .a ..a1 ..n ..a2 ...a2x .b ..b1 .c"},
+        {"", "after", "a2", http.StatusCreated,
+         "root .a ..a1 ..a2 ...a2x ..n .b ..b1 .c"},
+        {"", "before", "a", http.StatusCreated,
+         "root .n .a ..a1 ..a2 ...a2x .b ..b1 .c"},
+        {"", "after", "c", http.StatusCreated,
+         "root .a ..a1 ..a2 ...a2x .b ..b1 .c .n"},
+        {"a", "before", "a2", http.StatusCreated,
+         "root .a ..a1 ..n ..a2 ...a2x .b ..b1 .c"},
+        {"b", "before", "a2", http.StatusBadRequest, testTreeShape},
+        {"", "before", "", http.StatusBadRequest, testTreeShape},
+        {"", "after", "root", http.StatusBadRequest, testTreeShape},
+        {"a", "middle", "", http.StatusBadRequest, testTreeShape},
+    }
+    for _, test := range tests {
+        test := test
+        name := test.parent + " " + test.position + " " + test.sibling
+        t.Run(name, func(t *testing.T) {
+            runOnBackends(t, func(client *testClient) {
+                client.createTestTree()
+                body := `{"name":"n","puid":"` + client.uids[test.parent] +
+                        `","position":"` + test.position +
+                        `","siblingId":"` + client.uids[test.sibling] + `"}`
+                client.expect(test.status, "POST", "/data", body)
+                client.expectShape(test.shape)
+            })
+        })
+    }
+}
+
+func TestETags(t *testing.T) {
+    tests := []struct {
+        name string
+        testFn func(client *testClient)
+    }{
+        {"stale etag of an update",
+         func(client *testClient) {
+             etag := client.etag("a")
+             client.expect(http.StatusOK, "PATCH",
+                           "/data/id/" + client.uids["a"], `{"desc":"1"}`,
+                           IF_MATCH_HEADER, etag)
+             client.expect(http.StatusPreconditionFailed, "PATCH",
+                           "/data/id/" + client.uids["a"], `{"desc":"2"}`,
+                           IF_MATCH_HEADER, etag)
+         }},
+        {"change of a child",
+         func(client *testClient) {
+             etag := client.etag("a")
+             client.expect(http.StatusOK, "PATCH",
+                           "/data/id/" + client.uids["a2x"], `{"desc":"1"}`)
+             //Update checks only the record, delete checks the subtree.
+             client.expect(http.StatusOK, "PATCH",
+                           "/data/id/" + client.uids["a"], `{"desc":"1"}`,
+                           IF_MATCH_HEADER, etag)
+             client.expect(http.StatusPreconditionFailed, "DELETE",
+                           "/data/id/" + client.uids["a"], "",
+                           IF_MATCH_HEADER, etag)
+             client.expect(http.StatusPreconditionFailed, "PUT",
+                           "/data/id/" + client.uids["a"] + "/parent",
+                           `{"puid":"` + client.uids["c"] + `"}`,
+                           IF_MATCH_HEADER, etag)
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a"], "",
+                           IF_MATCH_HEADER, client.etag("a"))
+         }},
+        {"removal and move below a child",
+         func(client *testClient) {
+             etag := client.etag("a")
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a2x"] + "?mode=trash",
+                           "")
+             client.expect(http.StatusPreconditionFailed, "DELETE",
+                           "/data/id/" + client.uids["a"], "",
+                           IF_MATCH_HEADER, etag)
+             etag = client.etag("a")
+             client.expect(http.StatusOK, "PUT",
+                           "/data/id/" + client.uids["b1"] + "/parent",
+                           `{"puid":"` + client.uids["a2"] + `"}`)
+             client.expect(http.StatusPreconditionFailed, "DELETE",
+                           "/data/id/" + client.uids["a"], "",
+                           IF_MATCH_HEADER, etag)
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["b"], "",
+                           IF_MATCH_HEADER, client.etag("b"))
+             client.expectShape("root .a ..a1 ..a2 ...b1 .c")
+         }},
+        {"invalid etags",
+         func(client *testClient) {
+             etag := client.etag("a")
+             for _, value := range []string{`"abc"`, "W/" + etag, "1"} {
+                 client.expect(http.StatusPreconditionFailed, "PATCH",
+                               "/data/id/" + client.uids["a"], `{"desc":"1"}`,
+                               IF_MATCH_HEADER, value)
+             }
+         }},
+        {"list of etags",
+         func(client *testClient) {
+             client.expect(http.StatusOK, "PATCH",
+                           "/data/id/" + client.uids["a"], `{"desc":"1"}`,
+                           IF_MATCH_HEADER, `"0", ` + client.etag("a"))
+         }},
+        {"forged etag",
+         func(client *testClient) {
+             etag := client.etag("a")
+             version, _ := strconv.ParseInt(strings.Trim(etag, `"`), 10, 64)
+             forged := `"` + strconv.FormatInt(version + 1000, 10) + `"`
+             client.expect(http.StatusPreconditionFailed, "PATCH",
+                           "/data/id/" + client.uids["a"], `{"desc":"1"}`,
+                           IF_MATCH_HEADER, forged)
+             client.expect(http.StatusPreconditionFailed, "PUT",
+                           "/data/id/" + client.uids["a"] + "/parent",
+                           `{"puid":"` + client.uids["c"] + `"}`,
+                           IF_MATCH_HEADER, forged)
+             client.expect(http.StatusPreconditionFailed, "DELETE",
+                           "/data/id/" + client.uids["a"], "",
+                           IF_MATCH_HEADER, `"999999"`)
+             client.expectShape(testTreeShape)
+         }},
+        {"concurrent merges with any version",
+         func(client *testClient) {
+             codes := make([]int, 8)
+             var wg sync.WaitGroup
+             for i := range codes {
+                 wg.Add(1)
+                 go func(i int) {
+                     defer wg.Done()
+                     codes[i] = client.do("PATCH",
+                                    "/data/id/" + client.uids["a"],
+                                    `{"attributes":{"k` + strconv.Itoa(i) +
+                                    `":1}}`).Code
+                 }(i)
+             }
+             wg.Wait()
+             rec := new(dataStore.Data)
+             client.decode(client.expect(http.StatusOK, "GET",
+                                         "/data/id/" + client.uids["a"], ""),
+                           rec)
+             for i, code := range codes {
+                 if _, ok := rec.Attributes["k" + strconv.Itoa(i)];
+                    code != http.StatusOK || !ok {
+                     client.t.Fatalf("Merge %d returned %d, attributes %v",
+                                     i, code, rec.Attributes)
+                 }
+             }
+         }},
+        {"missing If-Match",
+         func(client *testClient) {
+             for _, method := range []string{"PATCH", "PUT", "DELETE"} {
+                 client.expect(http.StatusPreconditionRequired, method,
+                               "/data/id/" + client.uids["a"], `{"desc":"1"}`,
+                               IF_MATCH_HEADER, "")
+             }
+             client.expect(http.StatusPreconditionRequired, "PUT",
+                           "/data/id/" + client.uids["a"] + "/parent",
+                           `{"puid":"` + client.uids["c"] + `"}`,
+                           IF_MATCH_HEADER, "")
+             client.expectShape(testTreeShape)
+             client.expect(http.StatusOK, "DELETE",
+                           "/data/id/" + client.uids["a"], "",
+                           IF_MATCH_HEADER, client.etag("a"))
+         }},
+    }
+    for _, test := range tests {
+        test := test
+        t.Run(test.name, func(t *testing.T) {
+            runOnBackends(t, func(client *testClient) {
+                client.createTestTree()
+                test.testFn(client)
+            })
+        })
+    }
+}
+
+var nextLinkRe = regexp.MustCompile(`<([^>]*)>; rel="next"`)
+
+func TestPages(t *testing.T) {
+    tests := []struct {
+        path string
+        pages []string
+        total string
+    }{
+        {"/data?limit=3", []string{"root a a1", "a2 a2x b", "b1 c"}, "8"},
+        {"/data?limit=8", []string{"root a a1 a2 a2x b b1 c"}, "8"},
+        {"/data?limit=2&depth=1", []string{"a b", "c"}, "3"},
+        {"/data?limit=3&leaf=true", []string{"a1 a2x b1", "c"}, "4"},
+        {"/data/name/a2x?limit=1", []string{"a2x"}, "1"},
+        {"/data/name/a2x?limit=1&leaf=false", []string{""}, "0"},
+        {"/data?limit=1&depth=2&leaf=true", []string{"a1", "b1"}, "2"},
+        {"/data?limit=2&attr.tag=x", []string{"a a2x", "c"}, "3"},
+        {"/data?limit=1&leaf=true&attr.tag=x", []string{"a2x", "c"}, "2"},
+    }
+    for _, test := range tests {
+        test := test
+        t.Run(test.path, func(t *testing.T) {
+            runOnBackends(t, func(client *testClient) {
+                client.createTestTree()
+                depths := map[string]int64{}
+                for _, name := range strings.Fields(testTreeShape) {
+                    depth := len(name) - len(strings.TrimLeft(name, "."))
+                    depths[name[depth:]] = int64(depth)
+                }
+                for _, name := range []string{"a", "a2x", "c"} {
+                    client.expect(http.StatusOK, "PATCH",
+                                  "/data/id/" + client.uids[name],
+                                  `{"attributes":{"tag":"x"}}`)
+                }
+                pages := []string{}
+                for path := test.path; len(path) != 0; {
+                    resp := client.expect(http.StatusOK, "GET", path, "")
+                    rows := []dataStore.Data{}
+                    client.decode(resp, &rows)
+                    names := []string{}
+                    for _, row := range rows {
+                        if row.Depth != depths[row.Name] {
+                            client.t.Fatalf("Depth of %s in %s is %d, " +
+                                            "expected %d", row.Name, path,
+                                            row.Depth, depths[row.Name])
+                        }
+                        names = append(names, row.Name)
+                    }
+                    pages = append(pages, strings.Join(names, " "))
+                    total := resp.Header().Get(TOTAL_COUNT_HEADER)
+                    if total != test.total {
+                        client.t.Fatalf("Total of %s is %s, expected %s",
+                                        path, total, test.total)
+                    }
+                    path = ""
+                    link := nextLinkRe.FindStringSubmatch(
+                                                resp.Header().Get("Link"))
+                    if link != nil {
+                        path = link[1]
+                    }
+                }
+                if strings.Join(pages, "|") != strings.Join(test.pages, "|") {
+                    client.t.Fatalf("Pages of %s are %q, expected %q",
+                                    test.path, pages, test.pages)
+                }
+            })
+        })
+    }
+}
+
+func TestInvalidPages(t *testing.T) {
+    runOnBackends(t, func(client *testClient) {
+        for _, query := range []string{"limit=0", "limit=x", "cursor=-1",
+                                       "cursor=x", "depth=x"} {
+            client.expect(http.StatusBadRequest, "GET", "/data?" + query, "")
+        }
+        client.expect(http.StatusBadRequest, "GET", "/data/name/nosuch", "")
+    })
+}
+
+func TestAncestors(t *testing.T) {
+    runOnBackends(t, func(client *testClient) {
+        client.createTestTree()
+        ancestors := map[string]string{
+            "root": "root",
+            "a": "root .a",
+            "a2x": "root .a ..a2 ...a2x",
+            "c": "root .c",
+        }
+        for name, names := range ancestors {
+            client.expectList("/data/id/" + client.uids[name] + "/ancestors",
+                              names)
+        }
+        client.expect(http.StatusOK, "PUT",
+                      "/data/id/" + client.uids["b"] + "/parent",
+                      `{"puid":"` + client.uids["a2"] + `"}`)
+        client.expectList("/data/id/" + client.uids["b1"] + "/ancestors",
+                          "root .a ..a2 ...b ....b1")
+        client.expect(http.StatusBadRequest, "GET",
+                      "/data/id/nosuch/ancestors", "")
+    })
+}
+
+func TestDescendants(t *testing.T) {
+    tests := []struct {
+        path string
+        names string
+    }{
+        {"a/descendants", "..a1 ..a2 ...a2x"},
+        {"a/descendants?depth=1", "..a1 ..a2"},
+        {"a/descendants?depth=0", "..a1 ..a2 ...a2x"},
+        {"root/descendants?depth=2", ".a ..a1 ..a2 .b ..b1 .c"},
+        {"a2x/descendants", ""},
+        {"root/children", ".a .b .c"},
+        {"a/children", "..a1 ..a2"},
+        {"a2x/children", ""},
+    }
+    runOnBackends(t, func(client *testClient) {
+        client.createTestTree()
+        for _, test := range tests {
+            parts := strings.SplitN(test.path, "/", 2)
+            client.expectList("/data/id/" + client.uids[parts[0]] + "/" +
+                              parts[1], test.names)
+        }
+        for _, query := range []string{"depth=-1", "depth=x"} {
+            client.expect(http.StatusBadRequest, "GET",
+                          "/data/id/" + client.uids["a"] + "/descendants?" +
+                          query, "")
+        }
+        client.expect(http.StatusBadRequest, "GET",
+                      "/data/id/nosuch/children", "")
+    })
+}
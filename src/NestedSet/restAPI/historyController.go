@@ -0,0 +1,91 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "net/http"
+    "encoding/json"
+    "time"
+    "github.com/gorilla/mux"
+    "NestedSet/logger"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl"
+)
+
+//Get the changes of a record in the order of time. Records that are deleted
+// still have their history.
+func (ctrl *controller) getRecordHistory(w http.ResponseWriter,
+                                         r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    Uid := vars["record-id"]
+    if len(Uid) == 0 {
+        log.Error("Empty record id , cannot find its history")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    entries, err := dbObj.GetRecordHistory(treeId, Uid)
+    if err != nil {
+        log.Error("Failed to retrieive the history of %s err : %s", Uid, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    if len(entries) == 0 {
+        //Records created before the history have no changes.
+        _, err = dbObj.GetRecord(treeId, Uid)
+        if err != nil {
+            log.Error("Record %s has no history err : %s", Uid, err)
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+    }
+    data, _ := json.Marshal(entries)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+//Get the changes of the records in all the trees, from the optional 'since'
+// time in RFC 3339 format.
+func (ctrl *controller) getAudit(w http.ResponseWriter, r *http.Request) {
+    var err error
+    var since time.Time
+    log := logger.GetLoggerInstance()
+    sinceVal := r.URL.Query().Get("since")
+    if len(sinceVal) != 0 {
+        since, err = time.Parse(time.RFC3339Nano, sinceVal)
+        if err != nil {
+            log.Error("Invalid time %s to get the audit err : %s", sinceVal,
+                      err)
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+    }
+    var entries []dataStore.HistoryEntry
+    dbObj := dataSetImpl.GetDataSetObj()
+    entries, err = dbObj.GetAudit(since)
+    if err != nil {
+        log.Error("Failed to retrieive the audit err : %s", err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    data, _ := json.Marshal(entries)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+    "NestedSet/dataStore"
+)
+
+//Get the history entries of the records.
+func (client *testClient)history(path string) []dataStore.HistoryEntry {
+    client.t.Helper()
+    entries := []dataStore.HistoryEntry{}
+    client.decode(client.expect(http.StatusOK, "GET", path, ""), &entries)
+    return entries
+}
+
+//Check the history entries as 'action:actor', the names of the records are
+// prefixed when 'names' is set.
+func (client *testClient)expectHistory(path string, names bool,
+                                       actions string) {
+    client.t.Helper()
+    recNames := map[string]string{}
+    for name, uid := range client.uids {
+        recNames[uid] = name
+    }
+    got := []string{}
+    for _, entry := range client.history(path) {
+        action := entry.Action + ":" + entry.Actor
+        if names == true {
+            action = recNames[entry.Uid] + ":" + action
+        }
+        got = append(got, action)
+    }
+    if strings.Join(got, " ") != actions {
+        client.t.Fatalf("%s returned '%s', expected '%s'", path,
+                        strings.Join(got, " "), actions)
+    }
+}
+
+func TestHistory(t *testing.T) {
+    runOnBackends(t, func(client *testClient) {
+        //Actor is the address of the client without the header.
+        client.createAll("a:root", "b:root", "a1:a")
+        path := "/data/id/" + client.uids["a"]
+        client.expect(http.StatusOK, "PATCH", path, `{"desc":"x"}`,
+                      ACTOR_HEADER, "alice")
+        client.expect(http.StatusOK, "PUT", path + "/parent",
+                      `{"puid":"` + client.uids["b"] + `"}`,
+                      ACTOR_HEADER, "bob")
+        client.expect(http.StatusOK, "DELETE", path + "?mode=trash", "",
+                      ACTOR_HEADER, "carol")
+        client.expect(http.StatusOK, "POST", "/trash/" + client.uids["a"] +
+                      "/restore", "", ACTOR_HEADER, "carol")
+        client.expect(http.StatusOK, "DELETE", path, "", ACTOR_HEADER, "dave")
+        client.expectHistory(path + "/history", false,
+                             "create:192.0.2.1 update:alice move:bob " +
+                             "trash:carol restore:carol delete:dave")
+        entries := client.history(path + "/history")
+        if entries[0].Before != nil || entries[1].Before.Desc != "" ||
+           entries[1].After.Desc != "x" ||
+           entries[2].After.Puid != client.uids["b"] ||
+           entries[3].After != nil || entries[5].After != nil {
+            client.t.Fatalf("Unexpected images in the history %+v", entries)
+        }
+        //Childrens are moved and removed along with their parent.
+        client.expectHistory("/data/id/" + client.uids["a1"] + "/history",
+                             false, "create:192.0.2.1 trash:carol " +
+                             "restore:carol delete:dave")
+        client.expectHistory("/data/id/" + client.uids["b"] + "/history",
+                             false, "create:192.0.2.1")
+        client.expect(http.StatusBadRequest, "GET",
+                      "/data/id/nosuch/history", "")
+    })
+}
+
+func TestAudit(t *testing.T) {
+    runOnBackends(t, func(client *testClient) {
+        client.createAll("a:root", "a1:a")
+        tree := client.createTree("products")
+        client.expect(http.StatusCreated, "POST",
+                      "/trees/" + tree.TreeId + "/data",
+                      `{"name":"p","puid":"` + tree.RootUid + `"}`,
+                      ACTOR_HEADER, "alice")
+        client.expect(http.StatusOK, "DELETE",
+                      "/data/id/" + client.uids["a"] + "?mode=trash", "",
+                      ACTOR_HEADER, "bob")
+        entries := client.history("/audit")
+        if len(entries) != 5 || entries[2].TreeId != tree.TreeId ||
+           entries[2].Actor != "alice" {
+            client.t.Fatalf("Unexpected audit %+v", entries)
+        }
+        //Changes of the subtree in a request are at the same time in order.
+        trashed := entries[3:]
+        if trashed[0].ChangedAt != trashed[1].ChangedAt ||
+           trashed[0].Seq >= trashed[1].Seq {
+            client.t.Fatalf("Unexpected order of the subtree %+v", trashed)
+        }
+        client.expectHistory("/audit?since=" + trashed[0].ChangedAt, true,
+                             "a:trash:bob a1:trash:bob")
+        client.expectHistory("/audit?since=2100-01-01T00:00:00Z", false, "")
+        client.expect(http.StatusBadRequest, "GET", "/audit?since=x", "")
+    })
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "fmt"
+    "testing"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl"
+    "NestedSet/dataStore/dataSetImpl/rdbms"
+)
+
+//Data table of the databases created before the trees, with the root and a
+// record under it.
+func createLegacyDataTable(t *testing.T, db *sqlx.DB) {
+    queries := []string{
+        fmt.Sprintf(`CREATE TABLE "%s" ("%s" VARCHAR(255) PRIMARY KEY,
+                     "%s" VARCHAR(255), "%s" VARCHAR(255) NOT NULL,
+                     "%s" VARCHAR(255), "%s" INTEGER, "%s" INTEGER)`,
+                    rdbms.SQL_DATA_TABLE_NAME, rdbms.DATA_UID,
+                    rdbms.PARENT_UID, rdbms.DATA_NAME, rdbms.DATA_DESC,
+                    rdbms.DATA_LFTID, rdbms.DATA_RGTID),
+        fmt.Sprintf(`INSERT INTO "%s" VALUES ('%s', '', 'root', '', 1, 4)`,
+                    rdbms.SQL_DATA_TABLE_NAME, dataStore.ROOT_UID),
+        fmt.Sprintf(`INSERT INTO "%s" VALUES ('legacy-a', '%s', 'a', '',
+                     2, 3)`, rdbms.SQL_DATA_TABLE_NAME, dataStore.ROOT_UID),
+    }
+    for _, query := range queries {
+        _, err := db.Exec(query)
+        if err != nil {
+            t.Fatalf("Failed to create the legacy data table err : %s", err)
+        }
+    }
+}
+
+//Check all the migrations are applied in order.
+func (client *testClient)expectMigrated() {
+    client.t.Helper()
+    dbObj := dataSetImpl.GetDataSetObj().(dataStore.MigrationInterface)
+    migrations, err := dbObj.GetMigrations()
+    if err != nil {
+        client.t.Fatalf("Failed to get the migrations err : %s", err)
+    }
+    if len(migrations) == 0 {
+        client.t.Fatalf("No migrations are found")
+    }
+    for i, migration := range migrations {
+        if migration.Version != i + 1 || len(migration.AppliedAt) == 0 {
+            client.t.Fatalf("Migrations are %+v, expected all applied",
+                            migrations)
+        }
+    }
+}
+
+func TestMigrations(t *testing.T) {
+    for _, backend := range testBackends {
+        t.Run(backend, func(t *testing.T) {
+            client := newPreparedTestClient(t, backend, func(db *sqlx.DB) {
+                createLegacyDataTable(t, db)
+            })
+            client.expectMigrated()
+            //Records of the legacy database are in the default tree.
+            client.expectTrees("default")
+            client.expectShape("root .a")
+            //Migrations whose version entry is lost are applied again over
+            // their changes.
+            sqlds := dataSetImpl.GetDataSetObj().(*rdbms.RdbmsDataStore)
+            _, err := sqlds.DBConn.Exec(sqlds.DBConn.Rebind(fmt.Sprintf(
+                                        `DELETE FROM "%s" WHERE "%s">?`,
+                                        rdbms.SQL_SCHEMA_TABLE_NAME,
+                                        rdbms.SCHEMA_VERSION)), 5)
+            if err != nil {
+                t.Fatalf("Failed to remove the schema versions err : %s", err)
+            }
+            err = sqlds.CreateDataStoreTables()
+            if err != nil {
+                t.Fatalf("Failed to apply the migrations again err : %s", err)
+            }
+            client.expectMigrated()
+            client.uids["a"] = "legacy-a"
+            client.create("a1", "a")
+            client.expectShape("root .a ..a1")
+        })
+    }
+}
@@ -35,7 +35,7 @@ type Routes struct {
 
 func (routeObj *Routes) CreateAllRoutes() {
     log := logger.GetLoggerInstance()
-    routeObj.entries = make([]routeEntry, 5)
+    routeObj.entries = make([]routeEntry, 11)
     routeObj.entries[0] = routeEntry{
                             "getAllRecords",
                             "GET",
@@ -61,6 +61,155 @@ func (routeObj *Routes) CreateAllRoutes() {
                             "DELETE",
                             "/data/id/{record-id}",
                             routeObj.controller.deleteRecord}
+    routeObj.entries[5] = routeEntry{
+                            "moveRecord",
+                            "PUT",
+                            "/data/id/{record-id}/parent",
+                            routeObj.controller.moveRecord}
+    routeObj.entries[6] = routeEntry{
+                            "getAncestors",
+                            "GET",
+                            "/data/id/{record-id}/ancestors",
+                            routeObj.controller.getAncestors}
+    routeObj.entries[7] = routeEntry{
+                            "getDescendants",
+                            "GET",
+                            "/data/id/{record-id}/descendants",
+                            routeObj.controller.getDescendants}
+    routeObj.entries[8] = routeEntry{
+                            "getChildren",
+                            "GET",
+                            "/data/id/{record-id}/children",
+                            routeObj.controller.getChildren}
+    routeObj.entries[9] = routeEntry{
+                            "updateRecord",
+                            "PUT",
+                            "/data/id/{record-id}",
+                            routeObj.controller.updateRecord}
+    routeObj.entries[10] = routeEntry{
+                            "patchRecord",
+                            "PATCH",
+                            "/data/id/{record-id}",
+                            routeObj.controller.updateRecord}
+    routeObj.entries = append(routeObj.entries,
+                        routeEntry{
+                            "copyRecord",
+                            "POST",
+                            "/data/id/{record-id}/copy",
+                            routeObj.controller.copyRecord},
+                        routeEntry{
+                            "getRecordHistory",
+                            "GET",
+                            "/data/id/{record-id}/history",
+                            routeObj.controller.getRecordHistory},
+                        routeEntry{
+                            "getTrash",
+                            "GET",
+                            "/trash",
+                            routeObj.controller.getTrash},
+                        routeEntry{
+                            "restoreRecord",
+                            "POST",
+                            "/trash/{record-id}/restore",
+                            routeObj.controller.restoreRecord},
+                        routeEntry{
+                            "getAllSnapshots",
+                            "GET",
+                            "/snapshots",
+                            routeObj.controller.getAllSnapshots},
+                        routeEntry{
+                            "addSnapshot",
+                            "POST",
+                            "/snapshots",
+                            routeObj.controller.addSnapshot},
+                        routeEntry{
+                            "getSnapshot",
+                            "GET",
+                            "/snapshots/{snapshot-id}",
+                            routeObj.controller.getSnapshot},
+                        routeEntry{
+                            "deleteSnapshot",
+                            "DELETE",
+                            "/snapshots/{snapshot-id}",
+                            routeObj.controller.deleteSnapshot},
+                        routeEntry{
+                            "getSnapshotRecords",
+                            "GET",
+                            "/snapshots/{snapshot-id}/data",
+                            routeObj.controller.getSnapshotRecords},
+                        routeEntry{
+                            "getSnapshotRecord",
+                            "GET",
+                            "/snapshots/{snapshot-id}/data/id/{record-id}",
+                            routeObj.controller.getSnapshotRecord},
+                        routeEntry{
+                            "getSnapshotSubtree",
+                            "GET",
+                            "/snapshots/{snapshot-id}/data/id/{record-id}" +
+                            "/subtree",
+                            routeObj.controller.getSnapshotSubtree},
+                        routeEntry{
+                            "restoreSnapshot",
+                            "POST",
+                            "/snapshots/{snapshot-id}/restore",
+                            routeObj.controller.restoreSnapshot})
+    //Data, history, trash and snapshot routes are served for every tree with
+    // the tree prefix, routes without the prefix are operating on the default
+    // tree.
+    dataRoutes := routeObj.entries
+    for _, route := range dataRoutes {
+        routeObj.entries = append(routeObj.entries, routeEntry{
+                            route.Name + "InTree",
+                            route.Method,
+                            "/trees/{tree-id}" + route.Pattern,
+                            route.HandlerFunc})
+    }
+    routeObj.entries = append(routeObj.entries,
+                        routeEntry{
+                            "getAllTrees",
+                            "GET",
+                            "/trees",
+                            routeObj.controller.getAllTrees},
+                        routeEntry{
+                            "getTree",
+                            "GET",
+                            "/trees/{tree-id}",
+                            routeObj.controller.getTree},
+                        routeEntry{
+                            "addTree",
+                            "POST",
+                            "/trees",
+                            routeObj.controller.addTree},
+                        routeEntry{
+                            "deleteTree",
+                            "DELETE",
+                            "/trees/{tree-id}",
+                            routeObj.controller.deleteTree},
+                        routeEntry{
+                            "verifyTree",
+                            "GET",
+                            "/admin/trees/{tree-id}/verify",
+                            routeObj.controller.verifyTree},
+                        routeEntry{
+                            "rebuildTree",
+                            "POST",
+                            "/admin/trees/{tree-id}/rebuild",
+                            routeObj.controller.rebuildTree},
+                        routeEntry{
+                            "getAllTypes",
+                            "GET",
+                            "/types",
+                            routeObj.controller.getAllTypes},
+                        routeEntry{
+                            "getType",
+                            "GET",
+                            "/types/{type-name}",
+                            routeObj.controller.getType},
+                        routeEntry{
+                            "getAudit",
+                            "GET",
+                            "/audit",
+                            routeObj.controller.getAudit})
     log.Trace("rest api routes are defined successfully")
 }
 
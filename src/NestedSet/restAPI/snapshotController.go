@@ -0,0 +1,204 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "net/http"
+    "encoding/json"
+    "io"
+    "io/ioutil"
+    "github.com/gorilla/mux"
+    "NestedSet/logger"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl"
+)
+
+func (ctrl *controller) getAllSnapshots(w http.ResponseWriter,
+                                        r *http.Request) {
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    dbObj := dataSetImpl.GetDataSetObj()
+    rows, err := dbObj.GetAllSnapshots(treeId)
+    if err != nil {
+        log.Error("Failed to get the snapshots of tree %s err : %s", treeId,
+                  err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    data, _ := json.Marshal(rows)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+func (ctrl *controller) getSnapshot(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    snapshotId := vars["snapshot-id"]
+    dbObj := dataSetImpl.GetDataSetObj()
+    snapshot, err := dbObj.GetSnapshot(treeId, snapshotId)
+    if err != nil {
+        log.Error("Failed to retrieive the snapshot %s err : %s", snapshotId,
+                  err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    data, _ := json.Marshal(snapshot)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+func (ctrl *controller) addSnapshot(w http.ResponseWriter, r *http.Request) {
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+    if err != nil {
+        log.Error("Failed to read request,")
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+    if err := r.Body.Close(); err != nil {
+        log.Error("Failed to close the request.")
+    }
+    snapshot := new(dataStore.Snapshot)
+    if err := json.Unmarshal(body, &snapshot); err != nil {
+        log.Error("Failed to Unmarshal the snapshot input err:%s", err)
+        w.WriteHeader(422)
+        return
+    }
+    if snapshot == nil {
+        log.Error("Empty snapshot in the request")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    err = dbObj.CreateSnapshot(treeId, snapshot)
+    if err != nil {
+        log.Error("Failed to create the snapshot %s err :%s", snapshot.Name,
+                  err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    //Return the snapshot, user need the generated id to access it.
+    data, _ := json.Marshal(snapshot)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.WriteHeader(http.StatusCreated)
+    w.Write(data)
+    log.Trace("Added a snapshot %s successfully", snapshot.SnapshotId)
+}
+
+func (ctrl *controller) deleteSnapshot(w http.ResponseWriter,
+                                       r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    snapshotId := vars["snapshot-id"]
+    dbObj := dataSetImpl.GetDataSetObj()
+    err := dbObj.DeleteSnapshot(treeId, snapshotId)
+    if err != nil {
+        log.Error("Failed to delete the snapshot %s err : %s", snapshotId, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+}
+
+//Write the records as of the snapshot, the subtree of the 'record-id' or all
+// the records when its not in the request. Only the record is written when
+// 'recordOnly' is set.
+func writeSnapshotRecords(w http.ResponseWriter, r *http.Request,
+                          recordOnly bool) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    snapshotId := vars["snapshot-id"]
+    Uid := vars["record-id"]
+    dbObj := dataSetImpl.GetDataSetObj()
+    rows, err := dbObj.GetSnapshotRecords(treeId, snapshotId, Uid)
+    if err != nil {
+        log.Error("Failed to retrieive the records of snapshot %s err : %s",
+                  snapshotId, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    var data []byte
+    if recordOnly == true {
+        data, _ = json.Marshal(rows[0])
+    } else {
+        data, _ = json.Marshal(rows)
+    }
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+func (ctrl *controller) getSnapshotRecords(w http.ResponseWriter,
+                                           r *http.Request) {
+    writeSnapshotRecords(w, r, false)
+}
+
+func (ctrl *controller) getSnapshotRecord(w http.ResponseWriter,
+                                          r *http.Request) {
+    writeSnapshotRecords(w, r, true)
+}
+
+func (ctrl *controller) getSnapshotSubtree(w http.ResponseWriter,
+                                           r *http.Request) {
+    writeSnapshotRecords(w, r, false)
+}
+
+//Restore the tree from the snapshot, or only the subtree of the 'uid' in the
+// request.
+func (ctrl *controller) restoreSnapshot(w http.ResponseWriter,
+                                        r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    snapshotId := vars["snapshot-id"]
+    body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+    if err != nil {
+        log.Error("Failed to read request,")
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+    if err := r.Body.Close(); err != nil {
+        log.Error("Failed to close the request.")
+    }
+    restoreReq := struct {
+        Uid string `json:"uid"`
+    }{}
+    if len(body) != 0 {
+        if err := json.Unmarshal(body, &restoreReq); err != nil {
+            log.Error("Failed to Unmarshal the restore request err:%s", err)
+            w.WriteHeader(422)
+            return
+        }
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    err = dbObj.RestoreSnapshot(treeId, snapshotId, restoreReq.Uid,
+                                getActor(r))
+    if err != nil {
+        log.Error("Failed to restore the snapshot %s err : %s", snapshotId,
+                  err)
+        writeErrorStatus(w, err)
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+    log.Trace("Restored the snapshot %s", snapshotId)
+}
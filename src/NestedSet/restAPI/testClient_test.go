@@ -0,0 +1,301 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "testing"
+    "github.com/jmoiron/sqlx"
+    "NestedSet/logger"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl"
+    "NestedSet/dataStore/dataSetImpl/rdbms"
+)
+
+//Every test runs on all these backends, so they are proven to behave the
+// same.
+var testBackends = []string{
+    dataSetImpl.BACKEND_MEMORY,
+    dataSetImpl.BACKEND_SQLITE,
+    dataSetImpl.BACKEND_BOLT,
+}
+
+//Database servers the tests run on as well, when the connection string of the
+// server is set in its environment variable. Tables of the database are
+// dropped before every test, so it must be a database only for the tests.
+var testServers = []struct {
+    backend string
+    dsnEnv string
+    //Format of a quoted table name.
+    quote string
+}{
+    {dataSetImpl.BACKEND_POSTGRES, "NESTEDSET_TEST_POSTGRES_DSN", `"%s"`},
+    {dataSetImpl.BACKEND_MYSQL, "NESTEDSET_TEST_MYSQL_DSN", "`%s`"},
+}
+
+//Tables of the SQL datastore.
+var testServerTables = []string{
+    rdbms.SQL_DATA_TABLE_NAME,
+    rdbms.SQL_TREE_TABLE_NAME,
+    rdbms.SQL_TRASH_TABLE_NAME,
+    rdbms.SQL_HISTORY_TABLE_NAME,
+    rdbms.SQL_SNAPSHOT_TABLE_NAME,
+    rdbms.SQL_SNAPSHOT_DATA_TABLE_NAME,
+    rdbms.SQL_SCHEMA_TABLE_NAME,
+}
+
+func TestMain(m *testing.M) {
+    logObj := new(logger.Logging)
+    logObj.LogInitSingleton(logger.Error, os.DevNull)
+    for _, server := range testServers {
+        if len(os.Getenv(server.dsnEnv)) != 0 {
+            testBackends = append(testBackends, server.backend)
+        }
+    }
+    os.Exit(m.Run())
+}
+
+//Get the connection string of the database server of the backend after
+// dropping the tables in it, empty when the backend has no server.
+func getServerDSN(t *testing.T, backend string) string {
+    for _, server := range testServers {
+        if server.backend != backend {
+            continue
+        }
+        dsn := os.Getenv(server.dsnEnv)
+        db, err := sql.Open(backend, dsn)
+        if err != nil {
+            t.Fatalf("Failed to open the %s database err : %s", backend, err)
+        }
+        defer db.Close()
+        for _, table := range testServerTables {
+            _, err = db.Exec("DROP TABLE IF EXISTS " +
+                             fmt.Sprintf(server.quote, table))
+            if err != nil {
+                t.Fatalf("Failed to drop the %s table %s err : %s", backend,
+                         table, err)
+            }
+        }
+        return dsn
+    }
+    return ""
+}
+
+//Client of the REST APIs on a new datastore of a backend. Changes of the
+// records are sent with 'If-Match: *' unless the test sets the header.
+type testClient struct {
+    t *testing.T
+    router http.Handler
+    uids map[string]string
+}
+
+//Create the datastore of the backend in a temporary directory, or on the
+// empty database of its server, and the router on it.
+func newTestClient(t *testing.T, backend string) *testClient {
+    return newPreparedTestClient(t, backend, nil)
+}
+
+//Create the client after 'prepareFn' sets up the tables of the empty
+// database, before the migrations are applied on them. Test is skipped on
+// the backends that are not SQL when 'prepareFn' is set.
+func newPreparedTestClient(t *testing.T, backend string,
+                           prepareFn func(db *sqlx.DB)) *testClient {
+    err := dataSetImpl.SetBackend(backend, nil)
+    if err != nil {
+        t.Fatalf("Failed to create the %s backend err : %s", backend, err)
+    }
+    dsn := getServerDSN(t, backend)
+    if len(dsn) == 0 {
+        dsn = filepath.Join(t.TempDir(), "test.db")
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    err = dbObj.CreateDBConnection(dsn)
+    if err != nil {
+        t.Fatalf("Failed to connect the %s backend err : %s", backend, err)
+    }
+    //Connection pools of the SQL datastores are closed after the test.
+    sqlds, ok := dbObj.(*rdbms.RdbmsDataStore)
+    if ok {
+        t.Cleanup(func() {
+            sqlds.DBConn.Close()
+        })
+    }
+    if prepareFn != nil {
+        if ok == false {
+            t.Skipf("Tables of the %s backend cannot be prepared", backend)
+        }
+        prepareFn(sqlds.DBConn)
+    }
+    err = dbObj.CreateDataStoreTables()
+    if err != nil {
+        t.Fatalf("Failed to create the %s tables err : %s", backend, err)
+    }
+    client := &testClient{t, new(Routes).NewRouter(), map[string]string{}}
+    client.uids["root"] = dataStore.ROOT_UID
+    return client
+}
+
+//Run the test on every backend.
+func runOnBackends(t *testing.T, testFn func(client *testClient)) {
+    for _, backend := range testBackends {
+        t.Run(backend, func(t *testing.T) {
+            testFn(newTestClient(t, backend))
+        })
+    }
+}
+
+//Send the request, 'headers' are the pairs of header name and value.
+func (client *testClient)do(method string, path string, body string,
+                            headers ...string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(method, path, strings.NewReader(body))
+    if method != "GET" {
+        req.Header.Set(IF_MATCH_HEADER, "*")
+    }
+    for i := 0; i + 1 < len(headers); i += 2 {
+        req.Header.Set(headers[i], headers[i + 1])
+    }
+    resp := httptest.NewRecorder()
+    client.router.ServeHTTP(resp, req)
+    return resp
+}
+
+//Send the request and check the status of the response.
+func (client *testClient)expect(status int, method string, path string,
+                                body string,
+                                headers ...string) *httptest.ResponseRecorder {
+    client.t.Helper()
+    resp := client.do(method, path, body, headers...)
+    if resp.Code != status {
+        client.t.Fatalf("%s %s returned %d, expected %d : %s", method, path,
+                        resp.Code, status, resp.Body.String())
+    }
+    return resp
+}
+
+//Decode the JSON body of the response.
+func (client *testClient)decode(resp *httptest.ResponseRecorder,
+                                value interface{}) {
+    client.t.Helper()
+    err := json.Unmarshal(resp.Body.Bytes(), value)
+    if err != nil {
+        client.t.Fatalf("Failed to decode %s err : %s", resp.Body.String(),
+                        err)
+    }
+}
+
+//Create the record under the parent, names of the records are unique in the
+// tests and used to find their ids.
+func (client *testClient)create(name string, parent string) string {
+    client.t.Helper()
+    client.expect(http.StatusCreated, "POST", "/data",
+                  `{"name":"` + name + `","puid":"` + client.uids[parent] +
+                  `"}`)
+    rows := []dataStore.Data{}
+    client.decode(client.expect(http.StatusOK, "GET", "/data/name/" + name,
+                                ""), &rows)
+    client.uids[name] = rows[len(rows) - 1].Uid
+    return client.uids[name]
+}
+
+//Create the records from the list of 'name:parent' pairs in order.
+func (client *testClient)createAll(records ...string) {
+    client.t.Helper()
+    for _, record := range records {
+        pair := strings.SplitN(record, ":", 2)
+        client.create(pair[0], pair[1])
+    }
+}
+
+//Tree of the test, 'a' and 'b' have childrens.
+func (client *testClient)createTestTree() {
+    client.t.Helper()
+    client.createAll("a:root", "a1:a", "a2:a", "a2x:a2", "b:root", "b1:b",
+                     "c:root")
+}
+
+//Get the records of the list as their names indented with their depth.
+func (client *testClient)list(path string) string {
+    client.t.Helper()
+    rows := []dataStore.Data{}
+    client.decode(client.expect(http.StatusOK, "GET", path, ""), &rows)
+    names := []string{}
+    for _, row := range rows {
+        names = append(names, strings.Repeat(".", int(row.Depth)) + row.Name)
+    }
+    return strings.Join(names, " ")
+}
+
+//Check the records of the list.
+func (client *testClient)expectList(path string, names string) {
+    client.t.Helper()
+    if got := client.list(path); got != names {
+        client.t.Fatalf("%s returned '%s', expected '%s'", path, got, names)
+    }
+}
+
+//Get the records of the tree in pre-order as their names indented with
+// their depth.
+func (client *testClient)shape() string {
+    client.t.Helper()
+    return client.list("/data?limit=1000")
+}
+
+//Check the shape of the tree.
+func (client *testClient)expectShape(shape string) {
+    client.t.Helper()
+    if got := client.shape(); got != shape {
+        client.t.Fatalf("Tree is '%s', expected '%s'", got, shape)
+    }
+}
+
+//Check the names of the records in the trash, sorted on the name.
+func (client *testClient)expectTrash(names string) {
+    client.t.Helper()
+    entries := []dataStore.TrashEntry{}
+    client.decode(client.expect(http.StatusOK, "GET", "/trash", ""), &entries)
+    got := []string{}
+    for _, entry := range entries {
+        got = append(got, entry.Name)
+    }
+    sort.Strings(got)
+    if strings.Join(got, " ") != names {
+        client.t.Fatalf("Trash is '%s', expected '%s'", strings.Join(got, " "),
+                        names)
+    }
+}
+
+//Get the ETag of the record.
+func (client *testClient)etag(name string) string {
+    client.t.Helper()
+    resp := client.expect(http.StatusOK, "GET", "/data/id/" + client.uids[name],
+                          "")
+    return resp.Header().Get("ETag")
+}
+
+//Create the snapshot of the tree, returns its id.
+func (client *testClient)createSnapshot(name string) string {
+    client.t.Helper()
+    snapshot := new(dataStore.Snapshot)
+    client.decode(client.expect(http.StatusCreated, "POST", "/snapshots",
+                                `{"name":"` + name + `"}`), snapshot)
+    return snapshot.SnapshotId
+}
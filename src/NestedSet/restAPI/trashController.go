@@ -0,0 +1,87 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restAPI
+
+import (
+    "net/http"
+    "encoding/json"
+    "io"
+    "io/ioutil"
+    "github.com/gorilla/mux"
+    "NestedSet/logger"
+    "NestedSet/dataStore/dataSetImpl"
+)
+
+//Get the subtrees in the trash of the tree, ordered on delete time.
+func (ctrl *controller) getTrash(w http.ResponseWriter, r *http.Request) {
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    dbObj := dataSetImpl.GetDataSetObj()
+    entries, err := dbObj.GetTrash(treeId)
+    if err != nil {
+        log.Error("Failed to get the trash of tree %s err : %s", treeId, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    data, _ := json.Marshal(entries)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+//Restore a subtree from the trash. Its restored under the 'puid' of the
+// request, or under its original parent when the request has no 'puid'.
+func (ctrl *controller) restoreRecord(w http.ResponseWriter,
+                                      r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := getTreeId(r)
+    Uid := vars["record-id"]
+    if len(Uid) == 0 {
+        log.Error("Empty record id , cannot restore it")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+    if err != nil {
+        log.Error("Failed to read request,")
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+    if err := r.Body.Close(); err != nil {
+        log.Error("Failed to close the request.")
+    }
+    restoreReq := struct {
+        Puid string `json:"puid"`
+    }{}
+    if len(body) != 0 {
+        if err := json.Unmarshal(body, &restoreReq); err != nil {
+            log.Error("Failed to Unmarshal the restore request err:%s", err)
+            w.WriteHeader(422)
+            return
+        }
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    err = dbObj.RestoreRecord(treeId, Uid, restoreReq.Puid,
+                              getActor(r))
+    if err != nil {
+        log.Error("Failed to restore the record %s err : %s", Uid, err)
+        writeErrorStatus(w, err)
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+    log.Trace("Restored the record %s from the trash", Uid)
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restAPI
+
+import (
+    "net/http"
+    "encoding/json"
+    "io"
+    "io/ioutil"
+    "github.com/gorilla/mux"
+    "NestedSet/logger"
+    "NestedSet/dataStore"
+    "NestedSet/dataStore/dataSetImpl"
+)
+
+func (ctrl *controller) getAllTrees(w http.ResponseWriter, r *http.Request) {
+    log := logger.GetLoggerInstance()
+    dbObj := dataSetImpl.GetDataSetObj()
+    rows, err := dbObj.GetAllTrees()
+    if err != nil {
+        log.Error("Failed to get the trees from DB err : %s", err)
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+    data, _ := json.Marshal(rows)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+func (ctrl *controller) getTree(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := vars["tree-id"]
+    if len(treeId) == 0 {
+        log.Error("Empty tree id , cannot find it")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    treeObj, err := dbObj.GetTree(treeId)
+    if err != nil {
+        log.Error("Failed to retrieive the tree %s err : %s", treeId, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    data, _ := json.Marshal(treeObj)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+func (ctrl *controller) addTree(w http.ResponseWriter, r *http.Request) {
+    log := logger.GetLoggerInstance()
+    body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+    if err != nil {
+        log.Error("Failed to read request,")
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+    if err := r.Body.Close(); err != nil {
+        log.Error("Failed to close the request.")
+    }
+    treeObj := new(dataStore.Tree)
+    if err := json.Unmarshal(body, &treeObj); err != nil {
+        log.Error("Failed to Unmarshal the tree input err:%s", err)
+        w.WriteHeader(422)
+        return
+    }
+    if treeObj == nil {
+        log.Error("Empty tree in the request")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    err = dbObj.CreateTree(treeObj)
+    if err != nil {
+        log.Error("Failed to create the tree %s err :%s", treeObj.Name, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    //Return the tree, user need the generated ids to access it.
+    data, _ := json.Marshal(treeObj)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.WriteHeader(http.StatusCreated)
+    w.Write(data)
+    log.Trace("Added a tree %s successfully", treeObj.TreeId)
+}
+
+func (ctrl *controller) deleteTree(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    treeId := vars["tree-id"]
+    if len(treeId) == 0 {
+        log.Error("Empty tree id , cannot delete it")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    dbObj := dataSetImpl.GetDataSetObj()
+    err := dbObj.DeleteTree(treeId)
+    if err != nil {
+        log.Error("Failed to delete the tree %s err : %s", treeId, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    w.WriteHeader(http.StatusOK)
+}
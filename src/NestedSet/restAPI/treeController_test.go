@@ -0,0 +1,92 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "net/http"
+    "sort"
+    "strings"
+    "testing"
+    "NestedSet/dataStore"
+)
+
+//Create the tree, returns it with the generated ids.
+func (client *testClient)createTree(name string) *dataStore.Tree {
+    client.t.Helper()
+    tree := new(dataStore.Tree)
+    client.decode(client.expect(http.StatusCreated, "POST", "/trees",
+                                `{"name":"` + name + `"}`), tree)
+    return tree
+}
+
+//Check the names of the trees in the system, sorted on the name.
+func (client *testClient)expectTrees(names string) {
+    client.t.Helper()
+    trees := []dataStore.Tree{}
+    client.decode(client.expect(http.StatusOK, "GET", "/trees", ""), &trees)
+    got := []string{}
+    for _, tree := range trees {
+        got = append(got, tree.Name)
+    }
+    sort.Strings(got)
+    if strings.Join(got, " ") != names {
+        client.t.Fatalf("Trees are '%s', expected '%s'", strings.Join(got, " "),
+                        names)
+    }
+}
+
+func TestTrees(t *testing.T) {
+    runOnBackends(t, func(client *testClient) {
+        client.expectTrees("default")
+        client.expect(http.StatusOK, "GET", "/trees/default", "")
+        tree := client.createTree("products")
+        if len(tree.TreeId) == 0 || len(tree.RootUid) == 0 {
+            client.t.Fatalf("Tree ids are not generated : %+v", tree)
+        }
+        client.expectTrees("default products")
+        got := new(dataStore.Tree)
+        client.decode(client.expect(http.StatusOK, "GET",
+                                    "/trees/" + tree.TreeId, ""), got)
+        if got.Name != "products" || got.RootUid != tree.RootUid {
+            client.t.Fatalf("Tree is %+v, expected %+v", got, tree)
+        }
+        client.expect(http.StatusBadRequest, "POST", "/trees",
+                      `{"name":"products"}`)
+        client.expect(422, "POST", "/trees", `{"name":`)
+        client.expect(http.StatusBadRequest, "GET", "/trees/nosuch", "")
+        client.expect(http.StatusBadRequest, "DELETE", "/trees/default", "")
+        client.expect(http.StatusBadRequest, "DELETE", "/trees/nosuch", "")
+    })
+}
+
+func TestTreeData(t *testing.T) {
+    runOnBackends(t, func(client *testClient) {
+        client.createTestTree()
+        tree := client.createTree("products")
+        prefix := "/trees/" + tree.TreeId
+        client.expect(http.StatusCreated, "POST", prefix + "/data",
+                      `{"name":"p","puid":"` + tree.RootUid + `"}`)
+        client.expectList(prefix + "/data?limit=1000", "root .p")
+        client.expectShape("root .a ..a1 ..a2 ...a2x .b ..b1 .c")
+        //Records of the other tree cannot be the parent.
+        client.expect(http.StatusBadRequest, "POST", prefix + "/data",
+                      `{"name":"q","puid":"` + client.uids["a"] + `"}`)
+        client.expect(http.StatusBadRequest, "GET",
+                      prefix + "/data/id/" + client.uids["a"], "")
+        client.expect(http.StatusOK, "DELETE", prefix, "")
+        client.expectTrees("default")
+        client.expect(http.StatusBadRequest, "GET", prefix + "/data", "")
+        client.expectShape("root .a ..a1 ..a2 ...a2x .b ..b1 .c")
+    })
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "net/http"
+    "encoding/json"
+    "github.com/gorilla/mux"
+    "NestedSet/logger"
+    "NestedSet/dataStore"
+)
+
+func (ctrl *controller) getAllTypes(w http.ResponseWriter, r *http.Request) {
+    data, _ := json.Marshal(dataStore.GetNodeTypes())
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
+
+func (ctrl *controller) getType(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    log := logger.GetLoggerInstance()
+    name := vars["type-name"]
+    if len(name) == 0 {
+        log.Error("Empty type name , cannot find it")
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    typeObj, err := dataStore.GetNodeType(name)
+    if err != nil {
+        log.Error("Failed to retrieive the type %s err : %s", name, err)
+        w.WriteHeader(getErrorStatus(err))
+        return
+    }
+    data, _ := json.Marshal(typeObj)
+    w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.WriteHeader(http.StatusOK)
+    w.Write(data)
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 Sugesh Chandran
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package restAPI
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "NestedSet/dataStore"
+)
+
+//Types of the tests, same as the types in the README.
+var testTypes = `[
+    {
+        "name": "folder",
+        "children": ["folder", "document"]
+    },
+    {
+        "name": "document",
+        "schema": {
+            "type": "object",
+            "required": ["size"],
+            "properties": {"size": {"type": "integer", "minimum": 0}}
+        },
+        "children": []
+    }
+]`
+
+//Set the types of the tests, types are removed after the test.
+func setTestTypes(t *testing.T) {
+    types := []dataStore.NodeType{}
+    err := json.Unmarshal([]byte(testTypes), &types)
+    if err == nil {
+        err = dataStore.SetNodeTypes(types)
+    }
+    if err != nil {
+        t.Fatalf("Failed to set the types err : %s", err)
+    }
+    t.Cleanup(func() {
+        dataStore.SetNodeTypes(nil)
+    })
+}
+
+//Create the record of the type under the parent, 'size' is the attribute of
+// the documents.
+func (client *testClient)createTyped(name string, parent string,
+                                     nodeType string, size string) {
+    client.t.Helper()
+    body := `{"name":"` + name + `","puid":"` + client.uids[parent] +
+            `","type":"` + nodeType + `"`
+    if len(size) != 0 {
+        body += `,"attributes":{"size":` + size + `}}`
+    } else {
+        body += "}"
+    }
+    client.expect(http.StatusCreated, "POST", "/data", body)
+    rows := []dataStore.Data{}
+    client.decode(client.expect(http.StatusOK, "GET", "/data/name/" + name,
+                                ""), &rows)
+    client.uids[name] = rows[len(rows) - 1].Uid
+}
+
+//Send the request and check it is refused with the violations of the
+// 'fields'.
+func (client *testClient)expectViolations(method string, path string,
+                                          body string, fields string) {
+    client.t.Helper()
+    typeErr := new(dataStore.TypeError)
+    client.decode(client.expect(422, method, path, body), typeErr)
+    got := []string{}
+    for _, violation := range typeErr.Violations {
+        got = append(got, violation.Field)
+    }
+    if strings.Join(got, " ") != fields {
+        client.t.Fatalf("%s %s violates '%s', expected '%s'", method, path,
+                        strings.Join(got, " "), fields)
+    }
+}
+
+func TestTypes(t *testing.T) {
+    setTestTypes(t)
+    runOnBackends(t, func(client *testClient) {
+        types := []dataStore.NodeType{}
+        client.decode(client.expect(http.StatusOK, "GET", "/types", ""),
+                      &types)
+        if len(types) != 2 || types[0].Name != "document" ||
+           types[1].Name != "folder" {
+            client.t.Fatalf("Types are %+v, expected document and folder",
+                            types)
+        }
+        client.expect(http.StatusOK, "GET", "/types/folder", "")
+        client.expect(http.StatusBadRequest, "GET", "/types/nosuch", "")
+    })
+}
+
+func TestTypeViolations(t *testing.T) {
+    setTestTypes(t)
+    runOnBackends(t, func(client *testClient) {
+        client.createTyped("f", "root", "folder", "")
+        client.createTyped("g", "root", "folder", "")
+        client.createTyped("d", "f", "document", "1")
+        client.createTyped("e", "g", "document", "2")
+        client.create("u", "root")
+        shape := "root .f ..d .g ..e .u"
+        client.expectShape(shape)
+        client.expectViolations("POST", "/data",
+                                `{"name":"x","puid":"` + client.uids["f"] +
+                                `","type":"document",` +
+                                `"attributes":{"size":-1}}`,
+                                "attributes.size")
+        client.expectViolations("POST", "/data",
+                                `{"name":"x","puid":"` + client.uids["f"] +
+                                `","type":"document"}`, "attributes")
+        client.expectViolations("POST", "/data",
+                                `{"name":"x","puid":"` + client.uids["d"] +
+                                `","type":"folder"}`, "type")
+        client.expectViolations("POST", "/data",
+                                `{"name":"x","puid":"` + client.uids["f"] +
+                                `"}`, "type")
+        client.expectViolations("POST", "/data",
+                                `{"name":"x","puid":"` + client.uids["u"] +
+                                `","type":"nosuch"}`, "type")
+        client.expectViolations("PATCH", "/data/id/" + client.uids["d"],
+                                `{"attributes":{"size":-1}}`,
+                                "attributes.size")
+        client.expectViolations("PATCH", "/data/id/" + client.uids["f"],
+                                `{"type":"document",` +
+                                `"attributes":{"size":1}}`, "children")
+        client.expectViolations("PUT",
+                                "/data/id/" + client.uids["g"] + "/parent",
+                                `{"puid":"` + client.uids["d"] + `"}`, "type")
+        client.expectViolations("POST",
+                                "/data/id/" + client.uids["g"] + "/copy",
+                                `{"puid":"` + client.uids["e"] + `"}`, "type")
+        client.expectShape(shape)
+        //Parent of the trashed record is a document when its restored.
+        client.expect(http.StatusOK, "DELETE",
+                      "/data/id/" + client.uids["d"] + "?mode=trash", "")
+        client.expect(http.StatusOK, "PATCH", "/data/id/" + client.uids["f"],
+                      `{"type":"document","attributes":{"size":1}}`)
+        client.expectViolations("POST",
+                                "/trash/" + client.uids["d"] + "/restore", "",
+                                "type")
+        client.expectShape("root .f .g ..e .u")
+        client.expectTrash("d")
+    })
+}